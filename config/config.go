@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
@@ -20,6 +21,10 @@ type Config struct {
 	SecurecookieBlockKeyCurrent  string
 	SessionExpirationSeconds     int64
 	ValidatorServerURL           string
+	ValidatorMode                string
+	ValidatorTagsFileFullPath    string
+	ValidationRulesFileFullPath  string
+	ScheduledTasksFileFullPath   string
 	DBRootUser                   string
 	DBRootPassword               string
 	DBUser                       string
@@ -38,6 +43,15 @@ type Config struct {
 	FixesDirFullPath             string
 	SubmissionsDirFullPath       string
 	SubmissionImagesDirFullPath  string
+	SubmissionJobConsumerCount   int64
+	ApprovalQuorumAudition       int64
+	ApprovalQuorumTrial          int64
+	ApprovalQuorumStaff          int64
+	AssignmentExpiryDays         int64
+	AssignmentExpiryDaysByRole   map[string]int64
+	WorkloadCap                  int64
+	WorkloadCapByRole            map[string]int64
+	FileRetentionDays            int64
 }
 
 func EnvString(name string) string {
@@ -48,6 +62,11 @@ func EnvString(name string) string {
 	return s
 }
 
+// EnvOptionalString returns the value of an env variable which does not have to be set
+func EnvOptionalString(name string) string {
+	return os.Getenv(name)
+}
+
 func EnvInt(name string) int64 {
 	s := os.Getenv(name)
 	if s == "" {
@@ -72,6 +91,27 @@ func EnvBool(name string) bool {
 	panic(fmt.Sprintf("invalid value of env variable '%s'", name))
 }
 
+// EnvIntMap parses a comma-separated list of key:value pairs, like 'Tester:14,Trial Curator:7', an empty value is an empty map
+func EnvIntMap(name string) map[string]int64 {
+	s := strings.TrimSpace(os.Getenv(name))
+	result := make(map[string]int64)
+	if s == "" {
+		return result
+	}
+	for _, pair := range strings.Split(s, ",") {
+		i := strings.LastIndex(pair, ":")
+		if i < 0 {
+			panic(fmt.Sprintf("invalid value of env variable '%s'", name))
+		}
+		v, err := strconv.ParseInt(strings.TrimSpace(pair[i+1:]), 10, 64)
+		if err != nil {
+			panic(err)
+		}
+		result[strings.TrimSpace(pair[:i])] = v
+	}
+	return result
+}
+
 func GetConfig(l *logrus.Entry) *Config {
 	const ScopeIdentify = "identify"
 
@@ -96,6 +136,10 @@ func GetConfig(l *logrus.Entry) *Config {
 		SecurecookieBlockKeyCurrent:  EnvString("SECURECOOKIE_BLOCK_KEY_CURRENT"),
 		SessionExpirationSeconds:     EnvInt("SESSION_EXPIRATION_SECONDS"),
 		ValidatorServerURL:           EnvString("VALIDATOR_SERVER_URL"),
+		ValidatorMode:                EnvString("VALIDATOR_MODE"),
+		ValidatorTagsFileFullPath:    EnvString("VALIDATOR_TAGS_FILE_FULL_PATH"),
+		ValidationRulesFileFullPath:  EnvOptionalString("VALIDATION_RULES_FILE_FULL_PATH"),
+		ScheduledTasksFileFullPath:   EnvString("SCHEDULED_TASKS_FILE_FULL_PATH"),
 		DBUser:                       EnvString("DB_USER"),
 		DBPassword:                   EnvString("DB_PASSWORD"),
 		DBIP:                         EnvString("DB_IP"),
@@ -112,5 +156,14 @@ func GetConfig(l *logrus.Entry) *Config {
 		FixesDirFullPath:             EnvString("FIXES_DIR_FULL_PATH"),
 		SubmissionsDirFullPath:       EnvString("SUBMISSIONS_DIR_FULL_PATH"),
 		SubmissionImagesDirFullPath:  EnvString("SUBMISSION_IMAGES_DIR_FULL_PATH"),
+		SubmissionJobConsumerCount:   EnvInt("SUBMISSION_JOB_CONSUMER_COUNT"),
+		ApprovalQuorumAudition:       EnvInt("APPROVAL_QUORUM_AUDITION"),
+		ApprovalQuorumTrial:          EnvInt("APPROVAL_QUORUM_TRIAL"),
+		ApprovalQuorumStaff:          EnvInt("APPROVAL_QUORUM_STAFF"),
+		AssignmentExpiryDays:         EnvInt("ASSIGNMENT_EXPIRY_DAYS"),
+		AssignmentExpiryDaysByRole:   EnvIntMap("ASSIGNMENT_EXPIRY_DAYS_BY_ROLE"),
+		WorkloadCap:                  EnvInt("WORKLOAD_CAP"),
+		WorkloadCapByRole:            EnvIntMap("WORKLOAD_CAP_BY_ROLE"),
+		FileRetentionDays:            EnvInt("FILE_RETENTION_DAYS"),
 	}
 }
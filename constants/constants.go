@@ -1,5 +1,7 @@
 package constants
 
+import "time"
+
 const ValidatorID = 810112564787675166
 const SystemID = 844246603102945333
 const UserInAuditSubmissionMaxFilesize = 500000000
@@ -19,6 +21,7 @@ const (
 	ActionReject               = "reject"
 	ActionAuditionUpload       = "audition-upload"
 	ActionAuditionSubscribe    = "audition-subscribe"
+	ActionReopen               = "reopen"
 )
 
 const (
@@ -40,6 +43,7 @@ func GetAllowedActions() []string {
 		ActionAssignVerification,
 		ActionUnassignVerification,
 		ActionReject,
+		ActionReopen,
 	}
 }
 
@@ -59,6 +63,7 @@ func GetActionsWithNotification() []string {
 		ActionMarkAdded,
 		ActionUpload,
 		ActionReject,
+		ActionReopen,
 	}
 }
 
@@ -69,11 +74,15 @@ const (
 	ResourceKeyFileIDs               = "file-ids"
 	ResourceKeyCommentID             = "comment-id"
 	ResourceKeyCurationImageID       = "curation-image-id"
+	ResourceKeyCommentImageID        = "comment-image-id"
 	ResourceKeyFlashfreezeRootFileID = "flashfreeze-root-file-id"
 	ResourceKeyFixID                 = "fix-id"
 	ResourceKeyFixFileID             = "fix-file-id"
 	ResourceKeyUserID                = "user-id"
 	ResourceKeyTempName              = "temp-name"
+	ResourceKeySimilarityRuleID      = "similarity-rule-id"
+	ResourceKeyLabelID               = "label-id"
+	ResourceKeyOperationID           = "operation-id"
 )
 
 const (
@@ -100,3 +109,189 @@ const (
 	SubmissionStatusFinalizing = "finalizing"
 	SubmissionStatusSuccess    = "success"
 )
+
+const (
+	SubmissionJobStateQueued  = "queued"
+	SubmissionJobStateRunning = "running"
+	SubmissionJobStateDone    = "done"
+	SubmissionJobStateFailed  = "failed"
+)
+
+// SubmissionJobMaxAttempts is the number of times a submission job is attempted before it's marked as failed
+const SubmissionJobMaxAttempts = 3
+
+// SubmissionJobTTL is how long a finished submission job and its status timeline are kept around
+const SubmissionJobTTL = time.Hour * 24 * 7
+
+// AssignmentSweepInterval is how often expired assignments are looked for
+const AssignmentSweepInterval = time.Hour
+
+// AssignmentExpiryNoticeBefore is how long before the expiry the assignee gets notified
+const AssignmentExpiryNoticeBefore = time.Hour * 24
+
+const (
+	// ValidatorModeRemote uses only the validator server
+	ValidatorModeRemote = "remote"
+	// ValidatorModeLocal uses only the in-process validator, which checks curation metadata only
+	ValidatorModeLocal = "local"
+	// ValidatorModeFallback uses the validator server and falls back to the in-process validator when the server is not available
+	ValidatorModeFallback = "fallback"
+)
+
+const (
+	ValidationSeverityError   = "error"
+	ValidationSeverityWarning = "warning"
+	ValidationSeverityInfo    = "info"
+)
+
+// sources of stored validation messages of a submission file
+const (
+	ValidationMessageSourceValidator = "validator"
+	ValidationMessageSourceRule      = "rule"
+)
+
+// ValidationMessageCodeUnknown is the code of validator messages which are not in the code table
+const ValidationMessageCodeUnknown = "unknown"
+
+const (
+	ContentHashMatchSourceFlashfreeze = "flashfreeze"
+	ContentHashMatchSourceSubmission  = "submission"
+)
+
+// ContentHashExtensions are extensions of archive files which are compared by hash to find duplicate submissions, other files are too generic
+var ContentHashExtensions = []string{".swf", ".unity3d", ".unityweb", ".dcr", ".dir", ".cct", ".html", ".htm", ".jar", ".xap"}
+
+// ContentHashMinimumSize excludes tiny files such as redirect pages, which are identical across unrelated curations
+const ContentHashMinimumSize = 256
+
+const (
+	// CommentImageMaxCount is the number of images that can be attached to one comment
+	CommentImageMaxCount = 5
+	// CommentImageMaxFilesize is the size limit of one uploaded comment image
+	CommentImageMaxFilesize = 10000000
+	// CommentImageMaxDimension limits the width and height of comment images, so that small files cannot decode into huge bitmaps
+	CommentImageMaxDimension = 8000
+)
+
+// audit log actions
+const (
+	AuditActionSoftDeleteSubmission               = "soft-delete-submission"
+	AuditActionSoftDeleteSubmissionFile           = "soft-delete-submission-file"
+	AuditActionSoftDeleteComment                  = "soft-delete-comment"
+	AuditActionOverrideBot                        = "override-bot"
+	AuditActionDeleteUserSessions                 = "delete-user-sessions"
+	AuditActionUpdateMasterDB                     = "update-master-db"
+	AuditActionIngestFlashfreeze                  = "ingest-flashfreeze"
+	AuditActionRecomputeSubmissionCacheAll        = "recompute-submission-cache-all"
+	AuditActionIngestUnknownFlashfreeze           = "ingest-unknown-flashfreeze"
+	AuditActionIndexUnindexedFlashfreeze          = "index-unindexed-flashfreeze"
+	AuditActionIndexUnindexedSubmissionFiles      = "index-unindexed-submission-files"
+	AuditActionRevalidateSubmissionFiles          = "revalidate-submission-files"
+	AuditActionSendRemindersAboutRequestedChanges = "send-reminders-about-requested-changes"
+	AuditActionCreateSimilarityRule               = "create-similarity-rule"
+	AuditActionUpdateSimilarityRule               = "update-similarity-rule"
+	AuditActionDeleteSimilarityRule               = "delete-similarity-rule"
+	AuditActionRestoreSubmission                  = "restore-submission"
+	AuditActionRestoreSubmissionFile              = "restore-submission-file"
+	AuditActionRestoreComment                     = "restore-comment"
+	AuditActionCancelOperation                    = "cancel-operation"
+)
+
+// GetAuditActions returns all audit log actions
+func GetAuditActions() []string {
+	return []string{
+		AuditActionSoftDeleteSubmission,
+		AuditActionSoftDeleteSubmissionFile,
+		AuditActionSoftDeleteComment,
+		AuditActionOverrideBot,
+		AuditActionDeleteUserSessions,
+		AuditActionUpdateMasterDB,
+		AuditActionIngestFlashfreeze,
+		AuditActionRecomputeSubmissionCacheAll,
+		AuditActionIngestUnknownFlashfreeze,
+		AuditActionIndexUnindexedFlashfreeze,
+		AuditActionIndexUnindexedSubmissionFiles,
+		AuditActionRevalidateSubmissionFiles,
+		AuditActionSendRemindersAboutRequestedChanges,
+		AuditActionCreateSimilarityRule,
+		AuditActionUpdateSimilarityRule,
+		AuditActionDeleteSimilarityRule,
+		AuditActionRestoreSubmission,
+		AuditActionRestoreSubmissionFile,
+		AuditActionRestoreComment,
+		AuditActionCancelOperation,
+	}
+}
+
+// types of items in the trash
+const (
+	DeletedItemSubmission     = "submission"
+	DeletedItemSubmissionFile = "submission-file"
+	DeletedItemComment        = "comment"
+)
+
+// types of files purged after the retention period
+const (
+	PurgedFileSubmission  = "submission-file"
+	PurgedFileFixes       = "fixes-file"
+	PurgedFileFlashfreeze = "flashfreeze-file"
+)
+
+// FilePurgeInterval is how often files past their retention period are looked for
+const FilePurgeInterval = time.Hour
+
+// FilePurgeBatchSize is the most files purged in a single run, the rest waits for the next one
+const FilePurgeBatchSize = 1000
+
+// maintenance tasks which can be scheduled
+const (
+	ScheduledTaskSendRemindersAboutRequestedChanges = "send-reminders-about-requested-changes"
+	ScheduledTaskIndexUnindexedFlashfreezeFiles     = "index-unindexed-flashfreeze-files"
+	ScheduledTaskRecomputeSubmissionCacheAll        = "recompute-submission-cache-all"
+	ScheduledTaskUpdateMasterDB                     = "update-master-db"
+	ScheduledTaskRevalidateDegradedSubmissionFiles  = "revalidate-degraded-submission-files"
+)
+
+// states of scheduled task runs
+const (
+	ScheduledTaskRunRunning   = "running"
+	ScheduledTaskRunSucceeded = "succeeded"
+	ScheduledTaskRunFailed    = "failed"
+)
+
+// ScheduledTaskLockLease is how long a scheduled task lock is held without being renewed,
+// an instance which dies while running a task blocks it at most for this long
+const ScheduledTaskLockLease = time.Minute * 10
+
+// ScheduledTaskRunHistorySize is the number of the most recent runs shown on the internal page
+const ScheduledTaskRunHistorySize = 50
+
+// long-running internal operations which report progress and can be cancelled
+const (
+	OperationUpdateMasterDB              = "update-master-db"
+	OperationRecomputeSubmissionCacheAll = "recompute-submission-cache-all"
+	OperationIngestUnknownFlashfreeze    = "ingest-unknown-flashfreeze-files"
+)
+
+// states of internal operations
+const (
+	OperationStatusRunning   = "running"
+	OperationStatusSucceeded = "succeeded"
+	OperationStatusFailed    = "failed"
+	OperationStatusCancelled = "cancelled"
+)
+
+// OperationLogTailSize is the number of the most recent log lines kept for an operation
+const OperationLogTailSize = 100
+
+// OperationHistorySize is the number of finished operations kept in memory
+const OperationHistorySize = 20
+
+// audit log target resource types
+const (
+	AuditTargetSubmission     = "submission"
+	AuditTargetSubmissionFile = "submission-file"
+	AuditTargetComment        = "comment"
+	AuditTargetUser           = "user"
+	AuditTargetSimilarityRule = "similarity-rule"
+)
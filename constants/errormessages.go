@@ -1,6 +1,8 @@
 package constants
 
 const (
-	ErrorCannotDeleteLastSubmissionFile = "cannot delete last submission file for a given submission"
-	ErrorFailedToBeginTransaction       = "failed to begin transaction"
+	ErrorCannotDeleteLastSubmissionFile   = "cannot delete last submission file for a given submission"
+	ErrorFailedToBeginTransaction         = "failed to begin transaction"
+	ErrorCannotRestoreInDeletedSubmission = "the submission is deleted, restore the submission instead"
+	ErrorCannotRestorePurgedFile          = "the file has already been purged from the disk and cannot be restored"
 )
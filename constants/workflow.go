@@ -0,0 +1,131 @@
+package constants
+
+const (
+	SubmissionStateNew              = "new"
+	SubmissionStateInTesting        = "in-testing"
+	SubmissionStateChangesRequested = "changes-requested"
+	SubmissionStateApproved         = "approved"
+	SubmissionStateVerified         = "verified"
+	SubmissionStateAdded            = "added"
+	SubmissionStateRejected         = "rejected"
+)
+
+func GetSubmissionStates() []string {
+	return []string{
+		SubmissionStateNew,
+		SubmissionStateInTesting,
+		SubmissionStateChangesRequested,
+		SubmissionStateApproved,
+		SubmissionStateVerified,
+		SubmissionStateAdded,
+		SubmissionStateRejected,
+	}
+}
+
+// submissionTransitions maps a comment action to the states it can be posted in, and to the state it leads to
+var submissionTransitions = map[string]map[string]string{
+	ActionComment: {
+		SubmissionStateNew:              SubmissionStateNew,
+		SubmissionStateInTesting:        SubmissionStateInTesting,
+		SubmissionStateChangesRequested: SubmissionStateChangesRequested,
+		SubmissionStateApproved:         SubmissionStateApproved,
+		SubmissionStateVerified:         SubmissionStateVerified,
+		SubmissionStateAdded:            SubmissionStateAdded,
+		SubmissionStateRejected:         SubmissionStateRejected,
+	},
+	ActionUpload: {
+		SubmissionStateNew:              SubmissionStateNew,
+		SubmissionStateInTesting:        SubmissionStateInTesting,
+		SubmissionStateChangesRequested: SubmissionStateInTesting,
+		SubmissionStateApproved:         SubmissionStateInTesting,
+		SubmissionStateVerified:         SubmissionStateInTesting,
+		SubmissionStateAdded:            SubmissionStateAdded,
+	},
+	ActionAssignTesting: {
+		SubmissionStateNew:              SubmissionStateInTesting,
+		SubmissionStateInTesting:        SubmissionStateInTesting,
+		SubmissionStateChangesRequested: SubmissionStateChangesRequested,
+		SubmissionStateApproved:         SubmissionStateApproved,
+		SubmissionStateVerified:         SubmissionStateVerified,
+	},
+	ActionUnassignTesting: {
+		SubmissionStateNew:              SubmissionStateNew,
+		SubmissionStateInTesting:        SubmissionStateInTesting,
+		SubmissionStateChangesRequested: SubmissionStateChangesRequested,
+		SubmissionStateApproved:         SubmissionStateApproved,
+		SubmissionStateVerified:         SubmissionStateVerified,
+	},
+	ActionAssignVerification: {
+		SubmissionStateApproved: SubmissionStateApproved,
+		SubmissionStateVerified: SubmissionStateVerified,
+	},
+	ActionUnassignVerification: {
+		SubmissionStateInTesting:        SubmissionStateInTesting,
+		SubmissionStateChangesRequested: SubmissionStateChangesRequested,
+		SubmissionStateApproved:         SubmissionStateApproved,
+		SubmissionStateVerified:         SubmissionStateVerified,
+	},
+	ActionApprove: {
+		SubmissionStateNew:              SubmissionStateApproved,
+		SubmissionStateInTesting:        SubmissionStateApproved,
+		SubmissionStateChangesRequested: SubmissionStateApproved,
+		SubmissionStateApproved:         SubmissionStateApproved,
+		SubmissionStateVerified:         SubmissionStateVerified,
+	},
+	ActionRequestChanges: {
+		SubmissionStateNew:              SubmissionStateChangesRequested,
+		SubmissionStateInTesting:        SubmissionStateChangesRequested,
+		SubmissionStateChangesRequested: SubmissionStateChangesRequested,
+		SubmissionStateApproved:         SubmissionStateChangesRequested,
+		SubmissionStateVerified:         SubmissionStateChangesRequested,
+	},
+	ActionVerify: {
+		SubmissionStateApproved: SubmissionStateVerified,
+		SubmissionStateVerified: SubmissionStateVerified,
+	},
+	ActionMarkAdded: {
+		SubmissionStateVerified: SubmissionStateAdded,
+	},
+	ActionReject: {
+		SubmissionStateNew:              SubmissionStateRejected,
+		SubmissionStateInTesting:        SubmissionStateRejected,
+		SubmissionStateChangesRequested: SubmissionStateRejected,
+		SubmissionStateApproved:         SubmissionStateRejected,
+		SubmissionStateVerified:         SubmissionStateRejected,
+	},
+	ActionReopen: {
+		SubmissionStateRejected: SubmissionStateNew,
+	},
+}
+
+// IsWorkflowAction tells if the action takes part in the submission workflow, other actions (system, audition etc.) don't change the state
+func IsWorkflowAction(action string) bool {
+	_, ok := submissionTransitions[action]
+	return ok
+}
+
+// NextSubmissionState returns the state the submission ends up in after the action, or false if the action is not allowed in the given state
+func NextSubmissionState(state, action string) (string, bool) {
+	next, ok := submissionTransitions[action][state]
+	return next, ok
+}
+
+// AllowedSubmissionStates returns the states in which the action can be posted
+func AllowedSubmissionStates(action string) []string {
+	result := make([]string, 0, len(submissionTransitions[action]))
+	for _, state := range GetSubmissionStates() {
+		if _, ok := submissionTransitions[action][state]; ok {
+			result = append(result, state)
+		}
+	}
+	return result
+}
+
+// NextSubmissionStateWithApprovals is NextSubmissionState for an approval quorum, an approval leads to the approved state only once the quorum is met
+func NextSubmissionStateWithApprovals(state, action string, approvals, requiredApprovals int64) (string, bool) {
+	next, ok := NextSubmissionState(state, action)
+	if ok && next == SubmissionStateApproved && state != SubmissionStateApproved && approvals < requiredApprovals {
+		return SubmissionStateInTesting, true
+	}
+	return next, ok
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
 	"github.com/Dri0m/flashpoint-submission-system/utils"
 	"strings"
 	"time"
@@ -62,21 +63,30 @@ func (d *mysqlDAL) UpdateSubmissionCacheTable(dbs DBSession, sid int64) error {
 		return err
 	}
 
-	if distinctActionsSeq != nil {
-		for _, action := range strings.Split(*distinctActionsSeq, ",") {
-			if action == constants.ActionReject {
-				assignedTestingIDseq = nil
-				assignedVerificationIDseq = nil
-				requestedChangesIDseq = nil
-				approvedIDseq = nil
-				verifiedIDseq = nil
+	state, err := getSubmissionState(dbs, sid, d.approvalQuorums)
+	if err != nil {
+		return err
+	}
 
-				reject := constants.ActionReject
-				distinctActionsSeq = &reject
+	if state == constants.SubmissionStateRejected {
+		assignedTestingIDseq = nil
+		assignedVerificationIDseq = nil
+		requestedChangesIDseq = nil
+		approvedIDseq = nil
+		verifiedIDseq = nil
 
-				break
+		reject := constants.ActionReject
+		distinctActionsSeq = &reject
+	} else if distinctActionsSeq != nil {
+		// a reopened submission is back in the active queue, the rejection stays in the comments
+		actions := make([]string, 0)
+		for _, action := range strings.Split(*distinctActionsSeq, ",") {
+			if action != constants.ActionReject {
+				actions = append(actions, action)
 			}
 		}
+		seq := strings.Join(actions, ",")
+		distinctActionsSeq = &seq
 	}
 
 	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
@@ -93,12 +103,13 @@ func (d *mysqlDAL) UpdateSubmissionCacheTable(dbs DBSession, sid int64) error {
 		    sha256sum_sequence = ?,
 		    
 		    bot_action = ?,
-		    distinct_actions = ?
+		    distinct_actions = ?,
+		    state = ?
 		
 		WHERE fk_submission_id = ?`,
 		assignedTestingIDseq, assignedVerificationIDseq, requestedChangesIDseq, approvedIDseq, verifiedIDseq,
 		ofs, cfs, md5s, sha256s,
-		botAction, distinctActionsSeq,
+		botAction, distinctActionsSeq, state,
 		sid)
 	if err != nil {
 		return err
@@ -109,6 +120,7 @@ func (d *mysqlDAL) UpdateSubmissionCacheTable(dbs DBSession, sid int64) error {
 	return err
 }
 
+// getUserCountWithEnabledAction returns IDs of users whose latest enabling action has not been disabled, actions the system posted on behalf of a user count as theirs
 func getUserCountWithEnabledAction(dbs DBSession, enablerChunk, disablerChunk string, sid int64, onlyFromLastFileVersion bool) (*string, error) {
 
 	lastFileJoinQuery := ` `
@@ -119,15 +131,26 @@ func getUserCountWithEnabledAction(dbs DBSession, enablerChunk, disablerChunk st
 		lastFileVersionQuery = `AND ranked_comment.created_at > last_file.created_at`
 	}
 
+	// actions posted before the submission was reopened were wiped by the rejection
+	// comment IDs are compared because timestamps have only seconds resolution
+	sinceReopenQuery := fmt.Sprintf(`AND c.id > COALESCE((
+							SELECT MAX(reopen_comment.id)
+							FROM comment AS reopen_comment
+							WHERE reopen_comment.fk_submission_id = %d
+								AND reopen_comment.deleted_at IS NULL
+								AND reopen_comment.fk_action_id = (SELECT id FROM action WHERE name = "%s")
+						), 0)`, sid, constants.ActionReopen)
+
 	q := fmt.Sprintf(`
 		SELECT GROUP_CONCAT(latest_enabler.author_id) AS user_ids_with_enabled_action
 		FROM submission
 			LEFT JOIN (
 				WITH ranked_comment AS (
 					SELECT c.*,
+						COALESCE(c.fk_target_user_id, c.fk_user_id) AS actor_id,
 						ROW_NUMBER() OVER (
 							PARTITION BY c.fk_submission_id,
-							c.fk_user_id
+							COALESCE(c.fk_target_user_id, c.fk_user_id)
 							ORDER BY created_at DESC
 						) AS rn
 					FROM comment AS c
@@ -139,10 +162,11 @@ func getUserCountWithEnabledAction(dbs DBSession, enablerChunk, disablerChunk st
 						)
 						AND c.deleted_at IS NULL
 						AND c.fk_submission_id = %d
+						`+sinceReopenQuery+`
 					ORDER BY created_at ASC
 				)
 				SELECT ranked_comment.fk_submission_id AS submission_id,
-					ranked_comment.fk_user_id AS author_id,
+					ranked_comment.actor_id AS author_id,
 					ranked_comment.created_at
 				FROM ranked_comment
 					LEFT JOIN (SELECT * FROM submission_cache WHERE fk_submission_id = %d) AS submission_cache ON submission_cache.fk_submission_id = ranked_comment.fk_submission_id
@@ -153,9 +177,10 @@ func getUserCountWithEnabledAction(dbs DBSession, enablerChunk, disablerChunk st
 			LEFT JOIN (
 				WITH ranked_comment AS (
 					SELECT c.*,
+						COALESCE(c.fk_target_user_id, c.fk_user_id) AS actor_id,
 						ROW_NUMBER() OVER (
 							PARTITION BY c.fk_submission_id,
-							c.fk_user_id
+							COALESCE(c.fk_target_user_id, c.fk_user_id)
 							ORDER BY created_at DESC
 						) AS rn
 					FROM comment AS c
@@ -167,9 +192,10 @@ func getUserCountWithEnabledAction(dbs DBSession, enablerChunk, disablerChunk st
 						)
 						AND c.deleted_at IS NULL
 				    	AND c.fk_submission_id = %d
+						`+sinceReopenQuery+`
 				)
 				SELECT ranked_comment.fk_submission_id AS submission_id,
-					ranked_comment.fk_user_id AS author_id,
+					ranked_comment.actor_id AS author_id,
 					ranked_comment.created_at
 				FROM ranked_comment
 					LEFT JOIN (SELECT * FROM submission_cache WHERE fk_submission_id = %d) AS submission_cache ON submission_cache.fk_submission_id = ranked_comment.fk_submission_id
@@ -277,3 +303,59 @@ func getBotAction(dbs DBSession, sid int64) (result *string, err error) {
 	err = row.Scan(&result)
 	return
 }
+
+// getSubmissionState replays the workflow actions of the submission, actions which were not allowed at the time they were posted are skipped
+func getSubmissionState(dbs DBSession, sid int64, approvalQuorums types.ApprovalQuorums) (string, error) {
+	var submissionLevel string
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT submission_level.name
+		FROM submission
+			LEFT JOIN submission_level ON submission_level.id = submission.fk_submission_level_id
+		WHERE submission.id = ?`,
+		sid)
+	if err := row.Scan(&submissionLevel); err != nil {
+		return "", err
+	}
+	requiredApprovals := approvalQuorums.Required(submissionLevel)
+
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT comment.fk_user_id, action.name
+		FROM comment
+			LEFT JOIN action ON action.id = comment.fk_action_id
+		WHERE comment.fk_submission_id = ?
+			AND comment.fk_user_id != ?
+			AND comment.deleted_at IS NULL
+		ORDER BY comment.created_at, comment.id`,
+		sid, constants.ValidatorID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	state := constants.SubmissionStateNew
+	// approvals of the newest file version, the same way active_approved_ids counts them
+	approvers := make(map[int64]bool)
+
+	for rows.Next() {
+		var uid int64
+		var action string
+		if err := rows.Scan(&uid, &action); err != nil {
+			return "", err
+		}
+
+		switch action {
+		case constants.ActionUpload, constants.ActionReopen:
+			approvers = make(map[int64]bool)
+		case constants.ActionApprove:
+			approvers[uid] = true
+		case constants.ActionRequestChanges:
+			delete(approvers, uid)
+		}
+
+		if next, ok := constants.NextSubmissionStateWithApprovals(state, action, int64(len(approvers)), requiredApprovals); ok {
+			state = next
+		}
+	}
+
+	return state, rows.Err()
+}
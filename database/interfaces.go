@@ -17,6 +17,7 @@ type DAL interface {
 	GetDiscordUser(dbs DBSession, uid int64) (*types.DiscordUser, error)
 	StoreDiscordServerRoles(dbs DBSession, roles []types.DiscordRole) error
 	StoreDiscordUserRoles(dbs DBSession, uid int64, roles []int64) error
+	LockUser(dbs DBSession, uid int64) error
 	GetDiscordUserRoles(dbs DBSession, uid int64) ([]string, error)
 
 	StoreSubmission(dbs DBSession, submissionLevel string) (int64, error)
@@ -33,9 +34,13 @@ type DAL interface {
 	GetExtendedCommentsBySubmissionID(dbs DBSession, sid int64) ([]*types.ExtendedComment, error)
 	GetCommentByID(dbs DBSession, cid int64) (*types.Comment, error)
 
-	SoftDeleteSubmissionFile(dbs DBSession, sfid int64, deleteReason string) error
-	SoftDeleteSubmission(dbs DBSession, sid int64, deleteReason string) error
-	SoftDeleteComment(dbs DBSession, cid int64, deleteReason string) error
+	SoftDeleteSubmissionFile(dbs DBSession, sfid int64, deleteReason string, deletedBy int64) error
+	SoftDeleteSubmission(dbs DBSession, sid int64, deleteReason string, deletedBy int64, deletedAt time.Time) error
+	SoftDeleteComment(dbs DBSession, cid int64, deleteReason string, deletedBy int64) error
+	RestoreSubmissionFile(dbs DBSession, sfid int64) error
+	RestoreSubmission(dbs DBSession, sid int64) error
+	RestoreComment(dbs DBSession, cid int64) error
+	GetDeletedItems(dbs DBSession, limit int64) ([]*types.DeletedItem, error)
 
 	StoreNotificationSettings(dbs DBSession, uid int64, actions []string) error
 	GetNotificationSettingsByUserID(dbs DBSession, uid int64) ([]string, error)
@@ -90,6 +95,77 @@ type DAL interface {
 
 	GetUsers(dbs DBSession) ([]*types.User, error)
 	GetCommentsByUserIDAndAction(dbs DBSession, uid int64, action string) ([]*types.Comment, error)
+
+	StoreSubmissionJob(dbs DBSession, j *types.SubmissionJob) (int64, error)
+	GetSubmissionJobByTempName(dbs DBSession, tempName string) (*types.SubmissionJob, error)
+	GetUnfinishedSubmissionJob(dbs DBSession, uid int64, resumableIdentifier string) (*types.SubmissionJob, error)
+	ClaimNextSubmissionJob(dbs DBSession, staleBefore time.Time) (*types.SubmissionJob, error)
+	RefreshSubmissionJobLock(dbs DBSession, jid int64) error
+	MarkSubmissionJobAsDone(dbs DBSession, jid, sid int64) error
+	RequeueSubmissionJob(dbs DBSession, jid int64, lastError string, nextAttemptAt time.Time) error
+	MarkSubmissionJobAsFailed(dbs DBSession, jid int64, lastError string) error
+	UpdateSubmissionJobStatus(dbs DBSession, tempName, status string, message *string) error
+	StoreSubmissionJobStatusHistory(dbs DBSession, tempName, status string, message *string) error
+	GetSubmissionJobStatusHistory(dbs DBSession, tempName string) ([]*types.SubmissionStatusHistoryEntry, error)
+	DeleteFinishedSubmissionJobs(dbs DBSession, olderThan time.Time) (int64, error)
+
+	StoreSubmissionFileContents(dbs DBSession, sfid int64, entries []*types.IndexedFileEntry) error
+	DeleteSubmissionFileContents(dbs DBSession, sfid int64) error
+	UpdateSubmissionFileIndexedState(dbs DBSession, sfid int64, indexedAt time.Time, indexingErrors uint64) error
+	GetSubmissionFileContents(dbs DBSession, sfid int64) ([]*types.IndexedFileEntry, error)
+	GetUnindexedSubmissionFiles(dbs DBSession) ([]*types.ExtendedSubmissionFile, error)
+
+	StoreValidationRuleResults(dbs DBSession, sfid int64, results []*types.ValidationRuleResult) error
+	GetValidationRuleResultsBySubmissionFileID(dbs DBSession, sfid int64) ([]*types.ValidationRuleResult, error)
+
+	StoreSubmissionFileValidation(dbs DBSession, sfid int64, vr *types.ValidatorResponse, messages []*types.ValidationMessage, createdAt time.Time) error
+	GetSubmissionFileValidation(dbs DBSession, sfid int64) (*types.SubmissionFileValidation, error)
+	DeleteValidationRuleResults(dbs DBSession, sfid int64) error
+	GetSubmissionFileValidationTargets(dbs DBSession, sfids []int64) ([]*types.SubmissionFileValidationTarget, error)
+	GetDegradedSubmissionFileValidationTargets(dbs DBSession) ([]*types.SubmissionFileValidationTarget, error)
+
+	GetContentHashMatches(dbs DBSession, sha256sums []string) ([]*types.ContentHashMatch, error)
+
+	GetSimilarityRules(dbs DBSession) ([]*types.SimilarityRule, error)
+	StoreSimilarityRule(dbs DBSession, r *types.SimilarityRule) (int64, error)
+	UpdateSimilarityRule(dbs DBSession, r *types.SimilarityRule) error
+	DeleteSimilarityRule(dbs DBSession, id int64) error
+
+	GetActiveAssignments(dbs DBSession) ([]*types.ActiveAssignment, error)
+	StoreAssignmentExpiryNotice(dbs DBSession, cid int64, createdAt time.Time) error
+	CountActiveTestingAssignments(dbs DBSession, uid int64) (int64, error)
+
+	GetLabels(dbs DBSession) ([]*types.Label, error)
+	GetLabelByName(dbs DBSession, name string) (*types.Label, error)
+	StoreLabel(dbs DBSession, l *types.Label) (int64, error)
+	UpdateLabel(dbs DBSession, id int64, name string) error
+	DeleteLabel(dbs DBSession, id int64) error
+	StoreSubmissionLabel(dbs DBSession, sid, lid, uid int64, createdAt time.Time) error
+	DeleteSubmissionLabel(dbs DBSession, sid, lid int64) error
+
+	UpdateCommentMessage(dbs DBSession, cid int64, message *string, updatedAt time.Time) error
+	GetCommentRevisionsBySubmissionID(dbs DBSession, sid int64) ([]*types.CommentRevision, error)
+
+	GetUserIDsByUsernames(dbs DBSession, usernames []string) (map[string]int64, error)
+
+	StoreCommentImage(dbs DBSession, ci *types.CommentImage) (int64, error)
+	GetCommentImage(dbs DBSession, ciid int64) (*types.CommentImage, error)
+	GetCommentImagesBySubmissionID(dbs DBSession, sid int64) ([]*types.CommentImage, error)
+
+	StoreAuditLogEntry(dbs DBSession, e *types.AuditLogEntry) error
+	SearchAuditLog(dbs DBSession, filter *types.AuditLogFilter) ([]*types.AuditLogEntry, int64, error)
+
+	GetPurgeableFiles(dbs DBSession, deletedBefore time.Time, limit int64) ([]*types.PurgeableFile, error)
+	LockPurgeableFile(dbs DBSession, fileType string, fileID int64, deletedBefore time.Time) error
+	StoreFilePurge(dbs DBSession, fp *types.FilePurge) error
+	GetFilePurgeTotals(dbs DBSession) (int64, int64, error)
+
+	AcquireScheduledTaskLock(dbs DBSession, taskName, owner string, now, lockedUntil time.Time) (bool, error)
+	ReleaseScheduledTaskLock(dbs DBSession, taskName, owner string) error
+	StoreScheduledTaskRun(dbs DBSession, r *types.ScheduledTaskRun) (int64, error)
+	FinishScheduledTaskRun(dbs DBSession, id int64, status string, message *string, finishedAt time.Time) error
+	GetScheduledTaskRuns(dbs DBSession, limit int64) ([]*types.ScheduledTaskRun, error)
+	GetLastScheduledTaskRuns(dbs DBSession) ([]*types.ScheduledTaskRun, error)
 }
 
 type DBSession interface {
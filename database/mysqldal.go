@@ -3,6 +3,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -17,12 +18,14 @@ import (
 )
 
 type mysqlDAL struct {
-	db *sql.DB
+	db              *sql.DB
+	approvalQuorums types.ApprovalQuorums
 }
 
-func NewMysqlDAL(conn *sql.DB) *mysqlDAL {
+func NewMysqlDAL(conn *sql.DB, approvalQuorums types.ApprovalQuorums) *mysqlDAL {
 	return &mysqlDAL{
-		db: conn,
+		db:              conn,
+		approvalQuorums: approvalQuorums,
 	}
 }
 
@@ -180,6 +183,13 @@ func (d *mysqlDAL) StoreDiscordUserRoles(dbs DBSession, uid int64, roles []int64
 	return err
 }
 
+// LockUser locks the user until the end of the session, so that sessions doing the same work for the user wait for each other
+func (d *mysqlDAL) LockUser(dbs DBSession, uid int64) error {
+	var id int64
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `SELECT id FROM discord_user WHERE id = ? FOR UPDATE`, uid)
+	return row.Scan(&id)
+}
+
 // GetDiscordUserRoles returns all user roles
 func (d *mysqlDAL) GetDiscordUserRoles(dbs DBSession, uid int64) ([]string, error) {
 	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
@@ -290,7 +300,7 @@ func (d *mysqlDAL) GetSubmissionFiles(dbs DBSession, sfids []int64) ([]*types.Su
 func (d *mysqlDAL) GetExtendedSubmissionFilesBySubmissionID(dbs DBSession, sid int64) ([]*types.ExtendedSubmissionFile, error) {
 	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
 		SELECT submission_file.id, fk_user_id, username, avatar, 
-		       original_filename, current_filename, size, created_at, md5sum, sha256sum 
+		       original_filename, current_filename, size, created_at, md5sum, sha256sum, indexed_at 
 		FROM submission_file 
 		LEFT JOIN discord_user ON fk_user_id=discord_user.id
 		WHERE fk_submission_id=?
@@ -302,15 +312,20 @@ func (d *mysqlDAL) GetExtendedSubmissionFilesBySubmissionID(dbs DBSession, sid i
 	var result = make([]*types.ExtendedSubmissionFile, 0)
 	var avatar string
 	var uploadedAt int64
+	var indexedAt *int64
 	for rows.Next() {
 		sf := &types.ExtendedSubmissionFile{SubmissionID: sid}
 		err := rows.Scan(&sf.FileID, &sf.SubmitterID, &sf.SubmitterUsername, &avatar,
-			&sf.OriginalFilename, &sf.CurrentFilename, &sf.Size, &uploadedAt, &sf.MD5Sum, &sf.SHA256Sum)
+			&sf.OriginalFilename, &sf.CurrentFilename, &sf.Size, &uploadedAt, &sf.MD5Sum, &sf.SHA256Sum, &indexedAt)
 		if err != nil {
 			return nil, err
 		}
 		sf.SubmitterAvatarURL = utils.FormatAvatarURL(sf.SubmitterID, avatar)
 		sf.UploadedAt = time.Unix(uploadedAt, 0)
+		if indexedAt != nil {
+			t := time.Unix(*indexedAt, 0)
+			sf.IndexedAt = &t
+		}
 		result = append(result, sf)
 	}
 	return result, nil
@@ -354,10 +369,14 @@ func (d *mysqlDAL) StoreComment(dbs DBSession, c *types.Comment) error {
 		s := strings.TrimSpace(*c.Message)
 		msg = &s
 	}
-	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
-		INSERT INTO comment (fk_user_id, fk_submission_id, message, fk_action_id, created_at) 
-        VALUES (?, ?, ?, (SELECT id FROM action WHERE name=?), ?)`,
-		c.AuthorID, c.SubmissionID, msg, c.Action, c.CreatedAt.Unix())
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO comment (fk_user_id, fk_submission_id, message, fk_action_id, created_at, fk_target_user_id, fk_parent_comment_id) 
+        VALUES (?, ?, ?, (SELECT id FROM action WHERE name=?), ?, ?, ?)`,
+		c.AuthorID, c.SubmissionID, msg, c.Action, c.CreatedAt.Unix(), c.TargetUserID, c.ParentCommentID)
+	if err != nil {
+		return err
+	}
+	c.ID, err = res.LastInsertId()
 	if err != nil {
 		return err
 	}
@@ -368,7 +387,7 @@ func (d *mysqlDAL) StoreComment(dbs DBSession, c *types.Comment) error {
 // GetExtendedCommentsBySubmissionID returns all comments with author data for a given submission
 func (d *mysqlDAL) GetExtendedCommentsBySubmissionID(dbs DBSession, sid int64) ([]*types.ExtendedComment, error) {
 	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
-		SELECT comment.id, discord_user.id, username, avatar, message, (SELECT name FROM action WHERE id=comment.fk_action_id) as action, created_at 
+		SELECT comment.id, discord_user.id, username, avatar, message, (SELECT name FROM action WHERE id=comment.fk_action_id) as action, created_at, updated_at, fk_parent_comment_id
 		FROM comment 
 		JOIN discord_user ON discord_user.id = fk_user_id
 		WHERE fk_submission_id=? 
@@ -382,15 +401,20 @@ func (d *mysqlDAL) GetExtendedCommentsBySubmissionID(dbs DBSession, sid int64) (
 	result := make([]*types.ExtendedComment, 0)
 
 	var createdAt int64
+	var updatedAt *int64
 	var avatar string
 
 	for rows.Next() {
 
 		ec := &types.ExtendedComment{SubmissionID: sid}
-		if err := rows.Scan(&ec.CommentID, &ec.AuthorID, &ec.Username, &avatar, &ec.Message, &ec.Action, &createdAt); err != nil {
+		if err := rows.Scan(&ec.CommentID, &ec.AuthorID, &ec.Username, &avatar, &ec.Message, &ec.Action, &createdAt, &updatedAt, &ec.ParentCommentID); err != nil {
 			return nil, err
 		}
 		ec.CreatedAt = time.Unix(createdAt, 0)
+		if updatedAt != nil {
+			t := time.Unix(*updatedAt, 0)
+			ec.UpdatedAt = &t
+		}
 		ec.AvatarURL = utils.FormatAvatarURL(ec.AuthorID, avatar)
 		result = append(result, ec)
 	}
@@ -417,7 +441,7 @@ func (d *mysqlDAL) GetCommentByID(dbs DBSession, cid int64) (*types.Comment, err
 }
 
 // SoftDeleteSubmissionFile marks submission file as deleted
-func (d *mysqlDAL) SoftDeleteSubmissionFile(dbs DBSession, sfid int64, deleteReason string) error {
+func (d *mysqlDAL) SoftDeleteSubmissionFile(dbs DBSession, sfid int64, deleteReason string, deletedBy int64) error {
 	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
 		SELECT COUNT(*), fk_submission_id FROM submission_file
 		WHERE fk_submission_id = (SELECT fk_submission_id FROM submission_file WHERE id = ?)
@@ -435,9 +459,9 @@ func (d *mysqlDAL) SoftDeleteSubmissionFile(dbs DBSession, sfid int64, deleteRea
 	}
 
 	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
-		UPDATE submission_file SET deleted_at = UNIX_TIMESTAMP(), deleted_reason = ?
+		UPDATE submission_file SET deleted_at = UNIX_TIMESTAMP(), deleted_reason = ?, fk_deleted_by_user_id = ?
 		WHERE id  = ?`,
-		deleteReason, sfid)
+		deleteReason, deletedBy, sfid)
 	if err != nil {
 		return err
 	}
@@ -450,28 +474,29 @@ func (d *mysqlDAL) SoftDeleteSubmissionFile(dbs DBSession, sfid int64, deleteRea
 	return nil
 }
 
-// SoftDeleteSubmission marks submission and its files as deleted
-func (d *mysqlDAL) SoftDeleteSubmission(dbs DBSession, sid int64, deleteReason string) error {
+// SoftDeleteSubmission marks submission and its files and comments as deleted, the files and comments which were not deleted yet
+// are marked as deleted by the cascade, so that they can be restored together with it
+func (d *mysqlDAL) SoftDeleteSubmission(dbs DBSession, sid int64, deleteReason string, deletedBy int64, deletedAt time.Time) error {
 	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
-		UPDATE submission_file SET deleted_at = UNIX_TIMESTAMP(), deleted_reason = ?
-		WHERE fk_submission_id = ?`,
-		deleteReason, sid)
+		UPDATE submission_file SET deleted_at = ?, deleted_reason = ?, fk_deleted_by_user_id = ?, is_deleted_by_cascade = TRUE
+		WHERE fk_submission_id = ? AND deleted_at IS NULL`,
+		deletedAt.Unix(), deleteReason, deletedBy, sid)
 	if err != nil {
 		return err
 	}
 
 	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
-		UPDATE comment SET deleted_at = UNIX_TIMESTAMP(), deleted_reason = ?
-		WHERE fk_submission_id = ?`,
-		deleteReason, sid)
+		UPDATE comment SET deleted_at = ?, deleted_reason = ?, fk_deleted_by_user_id = ?, is_deleted_by_cascade = TRUE
+		WHERE fk_submission_id = ? AND deleted_at IS NULL`,
+		deletedAt.Unix(), deleteReason, deletedBy, sid)
 	if err != nil {
 		return err
 	}
 
 	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
-		UPDATE submission SET deleted_at = UNIX_TIMESTAMP(), deleted_reason = ?
+		UPDATE submission SET deleted_at = ?, deleted_reason = ?, fk_deleted_by_user_id = ?
 		WHERE id = ?`,
-		deleteReason, sid)
+		deletedAt.Unix(), deleteReason, deletedBy, sid)
 	if err != nil {
 		return err
 	}
@@ -485,11 +510,11 @@ func (d *mysqlDAL) SoftDeleteSubmission(dbs DBSession, sid int64, deleteReason s
 }
 
 // SoftDeleteComment marks comment as deleted
-func (d *mysqlDAL) SoftDeleteComment(dbs DBSession, cid int64, deleteReason string) error {
+func (d *mysqlDAL) SoftDeleteComment(dbs DBSession, cid int64, deleteReason string, deletedBy int64) error {
 	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
-		UPDATE comment SET deleted_at = UNIX_TIMESTAMP(), deleted_reason = ?
+		UPDATE comment SET deleted_at = UNIX_TIMESTAMP(), deleted_reason = ?, fk_deleted_by_user_id = ?
 		WHERE id = ?`,
-		deleteReason, cid)
+		deleteReason, deletedBy, cid)
 	if err != nil {
 		return err
 	}
@@ -513,6 +538,171 @@ func (d *mysqlDAL) SoftDeleteComment(dbs DBSession, cid int64, deleteReason stri
 	return nil
 }
 
+// RestoreSubmissionFile reverses the soft delete of a submission file, files of deleted submissions are restored with the submission
+func (d *mysqlDAL) RestoreSubmissionFile(dbs DBSession, sfid int64) error {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT submission.id, submission.deleted_at IS NOT NULL,
+			EXISTS (SELECT 1 FROM file_purge WHERE file_type = ? AND file_id = submission_file.id)
+		FROM submission_file
+		JOIN submission ON submission.id = submission_file.fk_submission_id
+		WHERE submission_file.id = ? AND submission_file.deleted_at IS NOT NULL`,
+		constants.PurgedFileSubmission, sfid)
+
+	var sid int64
+	var isSubmissionDeleted bool
+	var isPurged bool
+	if err := row.Scan(&sid, &isSubmissionDeleted, &isPurged); err != nil {
+		return err
+	}
+	if isSubmissionDeleted {
+		return fmt.Errorf(constants.ErrorCannotRestoreInDeletedSubmission)
+	}
+	if isPurged {
+		return fmt.Errorf(constants.ErrorCannotRestorePurgedFile)
+	}
+
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_file SET deleted_at = NULL, deleted_reason = NULL, fk_deleted_by_user_id = NULL
+		WHERE id = ?`,
+		sfid)
+	if err != nil {
+		return err
+	}
+
+	return d.UpdateSubmissionCacheTable(dbs, sid)
+}
+
+// RestoreSubmission reverses the soft delete of a submission together with the files and comments deleted along with it
+func (d *mysqlDAL) RestoreSubmission(dbs DBSession, sid int64) error {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT EXISTS (
+				SELECT 1 FROM file_purge
+				JOIN submission_file ON submission_file.id = file_purge.file_id
+				WHERE file_purge.file_type = ? AND submission_file.fk_submission_id = submission.id
+					AND submission_file.is_deleted_by_cascade)
+		FROM submission
+		WHERE id = ? AND deleted_at IS NOT NULL`,
+		constants.PurgedFileSubmission, sid)
+
+	var isPurged bool
+	if err := row.Scan(&isPurged); err != nil {
+		return err
+	}
+	if isPurged {
+		return fmt.Errorf(constants.ErrorCannotRestorePurgedFile)
+	}
+
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_file SET deleted_at = NULL, deleted_reason = NULL, fk_deleted_by_user_id = NULL, is_deleted_by_cascade = FALSE
+		WHERE fk_submission_id = ? AND is_deleted_by_cascade`,
+		sid)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE comment SET deleted_at = NULL, deleted_reason = NULL, fk_deleted_by_user_id = NULL, is_deleted_by_cascade = FALSE
+		WHERE fk_submission_id = ? AND is_deleted_by_cascade`,
+		sid)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission SET deleted_at = NULL, deleted_reason = NULL, fk_deleted_by_user_id = NULL
+		WHERE id = ?`,
+		sid)
+	if err != nil {
+		return err
+	}
+
+	return d.UpdateSubmissionCacheTable(dbs, sid)
+}
+
+// RestoreComment reverses the soft delete of a comment, comments of deleted submissions are restored with the submission
+func (d *mysqlDAL) RestoreComment(dbs DBSession, cid int64) error {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT submission.id, submission.deleted_at IS NOT NULL
+		FROM comment
+		JOIN submission ON submission.id = comment.fk_submission_id
+		WHERE comment.id = ? AND comment.deleted_at IS NOT NULL`,
+		cid)
+
+	var sid int64
+	var isSubmissionDeleted bool
+	if err := row.Scan(&sid, &isSubmissionDeleted); err != nil {
+		return err
+	}
+	if isSubmissionDeleted {
+		return fmt.Errorf(constants.ErrorCannotRestoreInDeletedSubmission)
+	}
+
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE comment SET deleted_at = NULL, deleted_reason = NULL, fk_deleted_by_user_id = NULL
+		WHERE id = ?`,
+		cid)
+	if err != nil {
+		return err
+	}
+
+	return d.UpdateSubmissionCacheTable(dbs, sid)
+}
+
+// GetDeletedItems returns the most recently deleted submissions, and files and comments deleted on their own, newest first.
+// Items with purged files are left out, they cannot be restored anymore.
+func (d *mysqlDAL) GetDeletedItems(dbs DBSession, limit int64) ([]*types.DeletedItem, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT type, id, submission_id, description, deleted_at, deleted_reason, deleted_by_user_id, discord_user.username
+		FROM (
+			SELECT ? AS type, submission.id AS id, submission.id AS submission_id,
+				(SELECT original_filename FROM submission_file WHERE fk_submission_id = submission.id ORDER BY created_at DESC LIMIT 1) AS description,
+				submission.deleted_at AS deleted_at, submission.deleted_reason AS deleted_reason, submission.fk_deleted_by_user_id AS deleted_by_user_id
+			FROM submission
+			WHERE submission.deleted_at IS NOT NULL
+				AND NOT EXISTS (
+					SELECT 1 FROM file_purge
+					JOIN submission_file ON submission_file.id = file_purge.file_id
+					WHERE file_purge.file_type = ? AND submission_file.fk_submission_id = submission.id
+						AND submission_file.is_deleted_by_cascade)
+			UNION ALL
+			SELECT ?, submission_file.id, submission_file.fk_submission_id, submission_file.original_filename,
+				submission_file.deleted_at, submission_file.deleted_reason, submission_file.fk_deleted_by_user_id
+			FROM submission_file
+			JOIN submission ON submission.id = submission_file.fk_submission_id
+			WHERE submission_file.deleted_at IS NOT NULL AND submission.deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM file_purge WHERE file_purge.file_type = ? AND file_purge.file_id = submission_file.id)
+			UNION ALL
+			SELECT ?, comment.id, comment.fk_submission_id, comment.message,
+				comment.deleted_at, comment.deleted_reason, comment.fk_deleted_by_user_id
+			FROM comment
+			JOIN submission ON submission.id = comment.fk_submission_id
+			WHERE comment.deleted_at IS NOT NULL AND submission.deleted_at IS NULL
+		) AS trash
+		LEFT JOIN discord_user ON discord_user.id = trash.deleted_by_user_id
+		ORDER BY deleted_at DESC, id DESC
+		LIMIT ?`,
+		constants.DeletedItemSubmission, constants.PurgedFileSubmission, constants.DeletedItemSubmissionFile, constants.PurgedFileSubmission,
+		constants.DeletedItemComment, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.DeletedItem, 0)
+	var deletedAt int64
+	for rows.Next() {
+		item := &types.DeletedItem{}
+		if err := rows.Scan(&item.Type, &item.ID, &item.SubmissionID, &item.Description, &deletedAt, &item.DeletedReason,
+			&item.DeletedByUserID, &item.DeletedByUsername); err != nil {
+			return nil, err
+		}
+		item.DeletedAt = time.Unix(deletedAt, 0)
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
 // StoreNotificationSettings clears and stores new notification settings for user
 func (d *mysqlDAL) StoreNotificationSettings(dbs DBSession, uid int64, actions []string) error {
 	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
@@ -1254,3 +1444,1191 @@ func (d *mysqlDAL) GetCommentsByUserIDAndAction(dbs DBSession, uid int64, action
 
 	return result, nil
 }
+
+// StoreSubmissionJob stores a submission job in the database which acts as a queue for the submission job consumer
+func (d *mysqlDAL) StoreSubmissionJob(dbs DBSession, j *types.SubmissionJob) (int64, error) {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO submission_job (temp_name, fk_user_id, fk_submission_id, resumable_identifier, filename, size, chunk_count, state, next_attempt_at, created_at, updated_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, UNIX_TIMESTAMP(), UNIX_TIMESTAMP(), UNIX_TIMESTAMP())`,
+		j.TempName, j.UserID, j.SubmissionID, j.ResumableIdentifier, j.Filename, j.Size, j.ChunkCount, constants.SubmissionJobStateQueued)
+	if err != nil {
+		return 0, err
+	}
+	jid, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return jid, nil
+}
+
+const submissionJobColumns = `id, temp_name, fk_user_id, fk_submission_id, resumable_identifier, filename, size, chunk_count, state, attempts, last_error, result_submission_id, status, status_message, next_attempt_at, locked_at, created_at, updated_at`
+
+func scanSubmissionJob(row interface{ Scan(...interface{}) error }) (*types.SubmissionJob, error) {
+	j := &types.SubmissionJob{}
+	var nextAttemptAt, createdAt, updatedAt int64
+	var lockedAt *int64
+
+	err := row.Scan(&j.ID, &j.TempName, &j.UserID, &j.SubmissionID, &j.ResumableIdentifier, &j.Filename, &j.Size, &j.ChunkCount,
+		&j.State, &j.Attempts, &j.LastError, &j.ResultSubmissionID, &j.Status, &j.StatusMessage, &nextAttemptAt, &lockedAt, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	j.NextAttemptAt = time.Unix(nextAttemptAt, 0)
+	if lockedAt != nil {
+		t := time.Unix(*lockedAt, 0)
+		j.LockedAt = &t
+	}
+	j.CreatedAt = time.Unix(createdAt, 0)
+	j.UpdatedAt = time.Unix(updatedAt, 0)
+
+	return j, nil
+}
+
+// GetSubmissionJobByTempName returns submission job identified by the temp name given to the client
+func (d *mysqlDAL) GetSubmissionJobByTempName(dbs DBSession, tempName string) (*types.SubmissionJob, error) {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT `+submissionJobColumns+`
+		FROM submission_job
+		WHERE temp_name = ?`,
+		tempName)
+
+	return scanSubmissionJob(row)
+}
+
+// GetUnfinishedSubmissionJob returns queued or running submission job of a given resumable upload, if there is any
+func (d *mysqlDAL) GetUnfinishedSubmissionJob(dbs DBSession, uid int64, resumableIdentifier string) (*types.SubmissionJob, error) {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT `+submissionJobColumns+`
+		FROM submission_job
+		WHERE fk_user_id = ? AND resumable_identifier = ? AND state IN (?, ?)
+		ORDER BY id DESC LIMIT 1`,
+		uid, resumableIdentifier, constants.SubmissionJobStateQueued, constants.SubmissionJobStateRunning)
+
+	return scanSubmissionJob(row)
+}
+
+// ClaimNextSubmissionJob locks the oldest queued job which is due, or a running job whose lock is older than staleBefore, and marks it as running
+func (d *mysqlDAL) ClaimNextSubmissionJob(dbs DBSession, staleBefore time.Time) (*types.SubmissionJob, error) {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT `+submissionJobColumns+`
+		FROM submission_job
+		WHERE (state = ? AND next_attempt_at <= UNIX_TIMESTAMP())
+		OR (state = ? AND locked_at < ?)
+		ORDER BY id LIMIT 1
+		FOR UPDATE SKIP LOCKED`,
+		constants.SubmissionJobStateQueued, constants.SubmissionJobStateRunning, staleBefore.Unix())
+
+	j, err := scanSubmissionJob(row)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_job SET state = ?, attempts = attempts + 1, locked_at = UNIX_TIMESTAMP(), updated_at = UNIX_TIMESTAMP()
+		WHERE id = ?`,
+		constants.SubmissionJobStateRunning, j.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	j.State = constants.SubmissionJobStateRunning
+	j.Attempts++
+
+	return j, nil
+}
+
+// RefreshSubmissionJobLock refreshes the lock of a running submission job so that it's not considered stale
+func (d *mysqlDAL) RefreshSubmissionJobLock(dbs DBSession, jid int64) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_job SET locked_at = UNIX_TIMESTAMP()
+		WHERE id = ? AND state = ?`,
+		jid, constants.SubmissionJobStateRunning)
+
+	return err
+}
+
+// MarkSubmissionJobAsDone marks submission job as successfully processed
+func (d *mysqlDAL) MarkSubmissionJobAsDone(dbs DBSession, jid, sid int64) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_job SET state = ?, result_submission_id = ?, last_error = NULL, locked_at = NULL, updated_at = UNIX_TIMESTAMP()
+		WHERE id = ?`,
+		constants.SubmissionJobStateDone, sid, jid)
+
+	return err
+}
+
+// RequeueSubmissionJob puts submission job back to the queue to be retried after nextAttemptAt
+func (d *mysqlDAL) RequeueSubmissionJob(dbs DBSession, jid int64, lastError string, nextAttemptAt time.Time) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_job SET state = ?, last_error = ?, next_attempt_at = ?, locked_at = NULL, updated_at = UNIX_TIMESTAMP()
+		WHERE id = ?`,
+		constants.SubmissionJobStateQueued, lastError, nextAttemptAt.Unix(), jid)
+
+	return err
+}
+
+// MarkSubmissionJobAsFailed marks submission job as permanently failed
+func (d *mysqlDAL) MarkSubmissionJobAsFailed(dbs DBSession, jid int64, lastError string) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_job SET state = ?, last_error = ?, locked_at = NULL, updated_at = UNIX_TIMESTAMP()
+		WHERE id = ?`,
+		constants.SubmissionJobStateFailed, lastError, jid)
+
+	return err
+}
+
+// UpdateSubmissionJobStatus updates the current status of a submission job
+func (d *mysqlDAL) UpdateSubmissionJobStatus(dbs DBSession, tempName, status string, message *string) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_job SET status = ?, status_message = ?, updated_at = UNIX_TIMESTAMP()
+		WHERE temp_name = ?`,
+		status, message, tempName)
+
+	return err
+}
+
+// StoreSubmissionJobStatusHistory stores an entry of a submission job status timeline
+func (d *mysqlDAL) StoreSubmissionJobStatusHistory(dbs DBSession, tempName, status string, message *string) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO submission_job_status (fk_submission_job_id, status, message, created_at)
+		VALUES ((SELECT id FROM submission_job WHERE temp_name = ?), ?, ?, UNIX_TIMESTAMP())`,
+		tempName, status, message)
+
+	return err
+}
+
+// GetSubmissionJobStatusHistory returns the status timeline of a submission job, oldest first
+func (d *mysqlDAL) GetSubmissionJobStatusHistory(dbs DBSession, tempName string) ([]*types.SubmissionStatusHistoryEntry, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT submission_job_status.status, submission_job_status.message, submission_job_status.created_at
+		FROM submission_job_status
+		LEFT JOIN submission_job ON submission_job.id = submission_job_status.fk_submission_job_id
+		WHERE submission_job.temp_name = ?
+		ORDER BY submission_job_status.id`,
+		tempName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.SubmissionStatusHistoryEntry, 0)
+
+	for rows.Next() {
+		var createdAt int64
+		e := &types.SubmissionStatusHistoryEntry{}
+		if err := rows.Scan(&e.Status, &e.Message, &createdAt); err != nil {
+			return nil, err
+		}
+		e.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, e)
+	}
+
+	return result, rows.Err()
+}
+
+// DeleteFinishedSubmissionJobs deletes finished submission jobs and their status timeline if they were last updated before olderThan
+func (d *mysqlDAL) DeleteFinishedSubmissionJobs(dbs DBSession, olderThan time.Time) (int64, error) {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		DELETE FROM submission_job
+		WHERE state IN (?, ?) AND updated_at < ?`,
+		constants.SubmissionJobStateDone, constants.SubmissionJobStateFailed, olderThan.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// StoreSubmissionFileContents stores data about indexed submission files
+func (d *mysqlDAL) StoreSubmissionFileContents(dbs DBSession, sfid int64, entries []*types.IndexedFileEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	data := make([]interface{}, 0, len(entries)*7)
+	for _, ife := range entries {
+		data = append(data, sfid, ife.Name, ife.SizeCompressed, ife.SizeUncompressed, ife.MD5, ife.SHA256, ife.FileUtilOutput)
+	}
+
+	const valuePlaceholder = `(?, ?, ?, ?, ?, ?, ?)`
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(),
+		`INSERT INTO submission_file_contents (fk_submission_file_id, filename, size_compressed, size_uncompressed, md5sum, sha256sum, description) VALUES 
+		`+valuePlaceholder+strings.Repeat(`,`+valuePlaceholder, len(entries)-1),
+		data...)
+	return err
+}
+
+// DeleteSubmissionFileContents deletes indexed contents of a submission file. The submission file stays locked
+// until the end of the transaction, so that the same file is not indexed twice at the same time.
+func (d *mysqlDAL) DeleteSubmissionFileContents(dbs DBSession, sfid int64) error {
+	var id int64
+	err := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT id FROM submission_file WHERE id = ? FOR UPDATE`,
+		sfid).Scan(&id)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
+		DELETE FROM submission_file_contents WHERE fk_submission_file_id = ?`,
+		sfid)
+	return err
+}
+
+// UpdateSubmissionFileIndexedState marks submission file as indexed
+func (d *mysqlDAL) UpdateSubmissionFileIndexedState(dbs DBSession, sfid int64, indexedAt time.Time, indexingErrors uint64) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE submission_file SET indexed_at = ?, indexing_errors = ? WHERE id = ?`,
+		indexedAt.Unix(), indexingErrors, sfid)
+	return err
+}
+
+// GetSubmissionFileContents returns indexed contents of a submission file
+func (d *mysqlDAL) GetSubmissionFileContents(dbs DBSession, sfid int64) ([]*types.IndexedFileEntry, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT filename, size_compressed, size_uncompressed, md5sum, sha256sum, description
+		FROM submission_file_contents
+		WHERE fk_submission_file_id = ?`,
+		sfid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.IndexedFileEntry, 0)
+	for rows.Next() {
+		ife := &types.IndexedFileEntry{}
+		if err := rows.Scan(&ife.Name, &ife.SizeCompressed, &ife.SizeUncompressed, &ife.MD5, &ife.SHA256, &ife.FileUtilOutput); err != nil {
+			return nil, err
+		}
+		result = append(result, ife)
+	}
+
+	return result, nil
+}
+
+// GetUnindexedSubmissionFiles returns IDs and filenames of all submission files which are not indexed
+func (d *mysqlDAL) GetUnindexedSubmissionFiles(dbs DBSession) ([]*types.ExtendedSubmissionFile, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT id, fk_submission_id, current_filename
+		FROM submission_file
+		WHERE indexed_at IS NULL AND deleted_at IS NULL`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.ExtendedSubmissionFile, 0)
+	for rows.Next() {
+		sf := &types.ExtendedSubmissionFile{}
+		if err := rows.Scan(&sf.FileID, &sf.SubmissionID, &sf.CurrentFilename); err != nil {
+			return nil, err
+		}
+		result = append(result, sf)
+	}
+
+	return result, nil
+}
+
+// StoreValidationRuleResults stores results of validation rules for a submission file
+func (d *mysqlDAL) StoreValidationRuleResults(dbs DBSession, sfid int64, results []*types.ValidationRuleResult) error {
+	if len(results) == 0 {
+		return nil
+	}
+	data := make([]interface{}, 0, len(results)*7)
+	for _, r := range results {
+		data = append(data, sfid, constants.ValidationMessageSourceRule, r.Severity, r.RuleID, r.Message, r.ExplanationURL, r.CreatedAt.Unix())
+	}
+
+	const valuePlaceholder = `(?, ?, ?, ?, ?, ?, ?)`
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(),
+		`INSERT INTO submission_file_validation_message (fk_submission_file_id, source, kind, code, message, explanation_url, created_at) VALUES 
+		`+valuePlaceholder+strings.Repeat(`,`+valuePlaceholder, len(results)-1),
+		data...)
+	return err
+}
+
+// GetValidationRuleResultsBySubmissionFileID returns results of validation rules for a submission file
+func (d *mysqlDAL) GetValidationRuleResultsBySubmissionFileID(dbs DBSession, sfid int64) ([]*types.ValidationRuleResult, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT id, fk_submission_file_id, code, kind, message, explanation_url, created_at
+		FROM submission_file_validation_message
+		WHERE fk_submission_file_id = ? AND source = ?
+		ORDER BY id`,
+		sfid, constants.ValidationMessageSourceRule)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.ValidationRuleResult, 0)
+
+	for rows.Next() {
+		r := &types.ValidationRuleResult{}
+		var createdAt int64
+		if err := rows.Scan(&r.ID, &r.SubmissionFileID, &r.RuleID, &r.Severity, &r.Message, &r.ExplanationURL, &createdAt); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, r)
+	}
+
+	return result, rows.Err()
+}
+
+// StoreSubmissionFileValidation stores validator response of a submission file together with its messages, replacing the previous ones
+func (d *mysqlDAL) StoreSubmissionFileValidation(dbs DBSession, sfid int64, vr *types.ValidatorResponse, messages []*types.ValidationMessage, createdAt time.Time) error {
+	response, err := json.Marshal(vr)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO submission_file_validation (fk_submission_file_id, is_extreme, curation_type, is_degraded, response, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE is_extreme = VALUES(is_extreme), curation_type = VALUES(curation_type),
+		                        is_degraded = VALUES(is_degraded), response = VALUES(response), created_at = VALUES(created_at)`,
+		sfid, vr.IsExtreme, vr.CurationType, vr.IsDegraded, string(response), createdAt.Unix())
+	if err != nil {
+		return err
+	}
+
+	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
+		DELETE FROM submission_file_validation_message WHERE fk_submission_file_id = ? AND source = ?`,
+		sfid, constants.ValidationMessageSourceValidator)
+	if err != nil {
+		return err
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	data := make([]interface{}, 0, len(messages)*6)
+	for _, m := range messages {
+		data = append(data, sfid, constants.ValidationMessageSourceValidator, m.Kind, m.Code, m.Message, createdAt.Unix())
+	}
+
+	const valuePlaceholder = `(?, ?, ?, ?, ?, ?)`
+	_, err = dbs.Tx().ExecContext(dbs.Ctx(),
+		`INSERT INTO submission_file_validation_message (fk_submission_file_id, source, kind, code, message, created_at) VALUES 
+		`+valuePlaceholder+strings.Repeat(`,`+valuePlaceholder, len(messages)-1),
+		data...)
+	return err
+}
+
+// GetSubmissionFileValidation returns stored validator response of a submission file, or sql.ErrNoRows if the file has none
+func (d *mysqlDAL) GetSubmissionFileValidation(dbs DBSession, sfid int64) (*types.SubmissionFileValidation, error) {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT response, created_at
+		FROM submission_file_validation
+		WHERE fk_submission_file_id = ?`,
+		sfid)
+
+	var response string
+	var createdAt int64
+	if err := row.Scan(&response, &createdAt); err != nil {
+		return nil, err
+	}
+
+	sfv := &types.SubmissionFileValidation{
+		SubmissionFileID: sfid,
+		CreatedAt:        time.Unix(createdAt, 0),
+		Messages:         make([]*types.ValidationMessage, 0),
+	}
+
+	if err := json.Unmarshal([]byte(response), &sfv.Response); err != nil {
+		return nil, err
+	}
+
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT kind, code, message
+		FROM submission_file_validation_message
+		WHERE fk_submission_file_id = ? AND source = ?
+		ORDER BY id`,
+		sfid, constants.ValidationMessageSourceValidator)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		m := &types.ValidationMessage{}
+		if err := rows.Scan(&m.Kind, &m.Code, &m.Message); err != nil {
+			return nil, err
+		}
+		sfv.Messages = append(sfv.Messages, m)
+	}
+
+	return sfv, rows.Err()
+}
+
+// DeleteValidationRuleResults deletes results of validation rules for a submission file
+func (d *mysqlDAL) DeleteValidationRuleResults(dbs DBSession, sfid int64) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		DELETE FROM submission_file_validation_message WHERE fk_submission_file_id = ? AND source = ?`,
+		sfid, constants.ValidationMessageSourceRule)
+	return err
+}
+
+// GetSubmissionFileValidationTargets returns given submission files for validation,
+// or newest files of all submissions which were not added yet if no submission file IDs are given
+func (d *mysqlDAL) GetSubmissionFileValidationTargets(dbs DBSession, sfids []int64) ([]*types.SubmissionFileValidationTarget, error) {
+	filter := `submission_file.id = submission_cache.fk_newest_file_id
+		AND (submission_cache.distinct_actions IS NULL OR NOT FIND_IN_SET(?, submission_cache.distinct_actions))`
+	data := []interface{}{constants.ActionMarkAdded}
+
+	if len(sfids) > 0 {
+		filter = `submission_file.id IN(?` + strings.Repeat(",?", len(sfids)-1) + `)`
+		data = make([]interface{}, 0, len(sfids))
+		for _, sfid := range sfids {
+			data = append(data, sfid)
+		}
+	}
+
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT submission_file.id, submission_file.fk_submission_id, submission_file.current_filename,
+		       submission_file.id = submission_cache.fk_newest_file_id
+		FROM submission_file
+		JOIN submission ON submission.id = submission_file.fk_submission_id
+		JOIN submission_cache ON submission_cache.fk_submission_id = submission_file.fk_submission_id
+		WHERE submission_file.deleted_at IS NULL
+		AND submission.deleted_at IS NULL
+		AND `+filter+`
+		ORDER BY submission_file.id`,
+		data...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.SubmissionFileValidationTarget, 0)
+
+	for rows.Next() {
+		t := &types.SubmissionFileValidationTarget{}
+		if err := rows.Scan(&t.FileID, &t.SubmissionID, &t.CurrentFilename, &t.IsNewest); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+// GetDegradedSubmissionFileValidationTargets returns newest files of submissions which were not added yet,
+// whose stored validation comes only from the local validator
+func (d *mysqlDAL) GetDegradedSubmissionFileValidationTargets(dbs DBSession) ([]*types.SubmissionFileValidationTarget, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT submission_file.id, submission_file.fk_submission_id, submission_file.current_filename, TRUE
+		FROM submission_file
+		JOIN submission ON submission.id = submission_file.fk_submission_id
+		JOIN submission_cache ON submission_cache.fk_submission_id = submission_file.fk_submission_id
+		JOIN submission_file_validation ON submission_file_validation.fk_submission_file_id = submission_file.id
+		WHERE submission_file.deleted_at IS NULL
+		AND submission.deleted_at IS NULL
+		AND submission_file.id = submission_cache.fk_newest_file_id
+		AND (submission_cache.distinct_actions IS NULL OR NOT FIND_IN_SET(?, submission_cache.distinct_actions))
+		AND submission_file_validation.is_degraded = TRUE
+		ORDER BY submission_file.id`,
+		constants.ActionMarkAdded)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.SubmissionFileValidationTarget, 0)
+
+	for rows.Next() {
+		t := &types.SubmissionFileValidationTarget{}
+		if err := rows.Scan(&t.FileID, &t.SubmissionID, &t.CurrentFilename, &t.IsNewest); err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, rows.Err()
+}
+
+// GetContentHashMatches returns flashfreeze files, their contents and contents of submission files with any of the given hashes
+func (d *mysqlDAL) GetContentHashMatches(dbs DBSession, sha256sums []string) ([]*types.ContentHashMatch, error) {
+	if len(sha256sums) == 0 {
+		return make([]*types.ContentHashMatch, 0), nil
+	}
+
+	data := make([]interface{}, 0, len(sha256sums))
+	for _, sum := range sha256sums {
+		data = append(data, sum)
+	}
+	inPlaceholder := `(?` + strings.Repeat(`, ?`, len(sha256sums)-1) + `)`
+
+	args := make([]interface{}, 0, len(data)*3+3)
+	args = append(append(args, constants.ContentHashMatchSourceFlashfreeze), data...)
+	args = append(append(args, constants.ContentHashMatchSourceFlashfreeze), data...)
+	args = append(append(args, constants.ContentHashMatchSourceSubmission), data...)
+
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT flashfreeze_file_contents.sha256sum, ?, flashfreeze_file.id, flashfreeze_file_contents.filename
+		FROM flashfreeze_file_contents
+		JOIN flashfreeze_file ON flashfreeze_file.id = flashfreeze_file_contents.fk_flashfreeze_file_id
+		WHERE flashfreeze_file_contents.sha256sum IN `+inPlaceholder+` AND flashfreeze_file.deleted_at IS NULL
+	UNION ALL
+		SELECT sha256sum, ?, id, original_filename
+		FROM flashfreeze_file
+		WHERE sha256sum IN `+inPlaceholder+` AND deleted_at IS NULL
+	UNION ALL
+		SELECT submission_file_contents.sha256sum, ?, submission.id, submission_file_contents.filename
+		FROM submission_file_contents
+		JOIN submission_file ON submission_file.id = submission_file_contents.fk_submission_file_id
+		JOIN submission ON submission.id = submission_file.fk_submission_id
+		WHERE submission_file_contents.sha256sum IN `+inPlaceholder+` AND submission_file.deleted_at IS NULL AND submission.deleted_at IS NULL
+		LIMIT 1000`,
+		args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.ContentHashMatch, 0)
+	for rows.Next() {
+		m := &types.ContentHashMatch{}
+		if err := rows.Scan(&m.SHA256, &m.Source, &m.ID, &m.MatchFilename); err != nil {
+			return nil, err
+		}
+		result = append(result, m)
+	}
+
+	return result, nil
+}
+
+// GetSimilarityRules returns all similarity rules in the order they are applied
+func (d *mysqlDAL) GetSimilarityRules(dbs DBSession) ([]*types.SimilarityRule, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT similarity_rule.id, pattern, threshold, strip_query_string, ignore_host, case_fold, fk_user_id, discord_user.username, updated_at
+		FROM similarity_rule
+		JOIN discord_user ON discord_user.id = similarity_rule.fk_user_id
+		ORDER BY similarity_rule.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.SimilarityRule, 0)
+	var updatedAt int64
+	for rows.Next() {
+		r := &types.SimilarityRule{}
+		if err := rows.Scan(&r.ID, &r.Pattern, &r.Threshold, &r.StripQueryString, &r.IgnoreHost, &r.CaseFold, &r.UserID, &r.Username, &updatedAt); err != nil {
+			return nil, err
+		}
+		r.UpdatedAt = time.Unix(updatedAt, 0)
+		result = append(result, r)
+	}
+
+	return result, nil
+}
+
+// StoreSimilarityRule stores a new similarity rule
+func (d *mysqlDAL) StoreSimilarityRule(dbs DBSession, r *types.SimilarityRule) (int64, error) {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO similarity_rule (pattern, threshold, strip_query_string, ignore_host, case_fold, fk_user_id, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.Pattern, r.Threshold, r.StripQueryString, r.IgnoreHost, r.CaseFold, r.UserID, r.UpdatedAt.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// UpdateSimilarityRule updates a similarity rule
+func (d *mysqlDAL) UpdateSimilarityRule(dbs DBSession, r *types.SimilarityRule) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE similarity_rule
+		SET pattern = ?, threshold = ?, strip_query_string = ?, ignore_host = ?, case_fold = ?, fk_user_id = ?, updated_at = ?
+		WHERE id = ?`,
+		r.Pattern, r.Threshold, r.StripQueryString, r.IgnoreHost, r.CaseFold, r.UserID, r.UpdatedAt.Unix(), r.ID)
+	return err
+}
+
+// DeleteSimilarityRule deletes a similarity rule, returns sql.ErrNoRows if it does not exist
+func (d *mysqlDAL) DeleteSimilarityRule(dbs DBSession, id int64) error {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		DELETE FROM similarity_rule WHERE id = ?`,
+		id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// GetActiveAssignments returns assignments of submissions which are not deleted, rejected or added, along with the comment which made them
+func (d *mysqlDAL) GetActiveAssignments(dbs DBSession) ([]*types.ActiveAssignment, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		WITH ranked_comment AS (
+			SELECT comment.id, comment.fk_submission_id, comment.fk_user_id, comment.created_at, action.name AS action,
+				ROW_NUMBER() OVER (
+					PARTITION BY comment.fk_submission_id, comment.fk_user_id, action.name
+					ORDER BY comment.created_at DESC, comment.id DESC
+				) AS rn
+			FROM comment
+				JOIN action ON action.id = comment.fk_action_id
+			WHERE action.name IN (?, ?)
+				AND comment.deleted_at IS NULL
+		)
+		SELECT ranked_comment.id, ranked_comment.fk_submission_id, ranked_comment.fk_user_id, ranked_comment.action, ranked_comment.created_at,
+			assignment_expiry_notice.id IS NOT NULL AS is_notice_sent
+		FROM ranked_comment
+			JOIN submission ON submission.id = ranked_comment.fk_submission_id
+			JOIN submission_cache ON submission_cache.fk_submission_id = ranked_comment.fk_submission_id
+			LEFT JOIN assignment_expiry_notice ON assignment_expiry_notice.fk_comment_id = ranked_comment.id
+		WHERE ranked_comment.rn = 1
+			AND submission.deleted_at IS NULL
+			AND submission_cache.state NOT IN (?, ?)
+			AND (
+				(ranked_comment.action = ? AND FIND_IN_SET(ranked_comment.fk_user_id, submission_cache.active_assigned_testing_ids))
+				OR (ranked_comment.action = ? AND FIND_IN_SET(ranked_comment.fk_user_id, submission_cache.active_assigned_verification_ids))
+			)
+		ORDER BY ranked_comment.created_at`,
+		constants.ActionAssignTesting, constants.ActionAssignVerification,
+		constants.SubmissionStateRejected, constants.SubmissionStateAdded,
+		constants.ActionAssignTesting, constants.ActionAssignVerification)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.ActiveAssignment, 0)
+
+	var assignedAt int64
+
+	for rows.Next() {
+		a := &types.ActiveAssignment{}
+		if err := rows.Scan(&a.CommentID, &a.SubmissionID, &a.UserID, &a.Action, &assignedAt, &a.IsNoticeSent); err != nil {
+			return nil, err
+		}
+		a.AssignedAt = time.Unix(assignedAt, 0)
+		result = append(result, a)
+	}
+
+	return result, rows.Err()
+}
+
+// CountActiveTestingAssignments returns the number of unfinished submissions the user is assigned to test
+func (d *mysqlDAL) CountActiveTestingAssignments(dbs DBSession, uid int64) (int64, error) {
+	var count int64
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT COUNT(*)
+		FROM submission_cache
+			JOIN submission ON submission.id = submission_cache.fk_submission_id
+		WHERE submission.deleted_at IS NULL
+			AND submission_cache.state NOT IN (?, ?)
+			AND FIND_IN_SET(?, submission_cache.active_assigned_testing_ids)`,
+		constants.SubmissionStateRejected, constants.SubmissionStateAdded, uid)
+	if err := row.Scan(&count); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// StoreAssignmentExpiryNotice records that the assignee has been notified about the upcoming expiry of the assignment
+func (d *mysqlDAL) StoreAssignmentExpiryNotice(dbs DBSession, cid int64, createdAt time.Time) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO assignment_expiry_notice (fk_comment_id, created_at)
+		VALUES (?, ?)`,
+		cid, createdAt.Unix())
+	return err
+}
+
+// GetLabels returns all labels along with the number of submissions they are attached to
+func (d *mysqlDAL) GetLabels(dbs DBSession) ([]*types.Label, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT label.id, label.name, label.fk_user_id, discord_user.username, label.created_at,
+			(SELECT COUNT(*)
+				FROM submission_label
+				JOIN submission ON submission.id = submission_label.fk_submission_id
+				WHERE submission_label.fk_label_id = label.id AND submission.deleted_at IS NULL) AS submission_count
+		FROM label
+		JOIN discord_user ON discord_user.id = label.fk_user_id
+		ORDER BY label.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.Label, 0)
+	var createdAt int64
+	for rows.Next() {
+		l := &types.Label{}
+		if err := rows.Scan(&l.ID, &l.Name, &l.UserID, &l.Username, &createdAt, &l.SubmissionCount); err != nil {
+			return nil, err
+		}
+		l.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, l)
+	}
+
+	return result, nil
+}
+
+// GetLabelByName returns a label, returns sql.ErrNoRows if it does not exist
+func (d *mysqlDAL) GetLabelByName(dbs DBSession, name string) (*types.Label, error) {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT label.id, label.name, label.fk_user_id, discord_user.username, label.created_at
+		FROM label
+		JOIN discord_user ON discord_user.id = label.fk_user_id
+		WHERE label.name = ?`,
+		name)
+
+	l := &types.Label{}
+	var createdAt int64
+	if err := row.Scan(&l.ID, &l.Name, &l.UserID, &l.Username, &createdAt); err != nil {
+		return nil, err
+	}
+	l.CreatedAt = time.Unix(createdAt, 0)
+
+	return l, nil
+}
+
+// StoreLabel stores a new label
+func (d *mysqlDAL) StoreLabel(dbs DBSession, l *types.Label) (int64, error) {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO label (name, fk_user_id, created_at)
+		VALUES (?, ?, ?)`,
+		l.Name, l.UserID, l.CreatedAt.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// UpdateLabel renames a label, returns sql.ErrNoRows if it does not exist
+func (d *mysqlDAL) UpdateLabel(dbs DBSession, id int64, name string) error {
+	var exists bool
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `SELECT EXISTS(SELECT 1 FROM label WHERE id = ?)`, id)
+	if err := row.Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE label SET name = ? WHERE id = ?`,
+		name, id)
+	return err
+}
+
+// DeleteLabel deletes a label and detaches it from all submissions, returns sql.ErrNoRows if it does not exist
+func (d *mysqlDAL) DeleteLabel(dbs DBSession, id int64) error {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		DELETE FROM label WHERE id = ?`,
+		id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// StoreSubmissionLabel attaches a label to a submission, attaching it again does nothing
+func (d *mysqlDAL) StoreSubmissionLabel(dbs DBSession, sid, lid, uid int64, createdAt time.Time) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT IGNORE INTO submission_label (fk_submission_id, fk_label_id, fk_user_id, created_at)
+		VALUES (?, ?, ?, ?)`,
+		sid, lid, uid, createdAt.Unix())
+	return err
+}
+
+// DeleteSubmissionLabel detaches a label from a submission, returns sql.ErrNoRows if it is not attached
+func (d *mysqlDAL) DeleteSubmissionLabel(dbs DBSession, sid, lid int64) error {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		DELETE FROM submission_label WHERE fk_submission_id = ? AND fk_label_id = ?`,
+		sid, lid)
+	if err != nil {
+		return err
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UpdateCommentMessage changes the message of a comment and keeps the previous message as a revision
+func (d *mysqlDAL) UpdateCommentMessage(dbs DBSession, cid int64, message *string, updatedAt time.Time) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO comment_revision (fk_comment_id, message, created_at)
+		SELECT id, message, COALESCE(updated_at, created_at)
+		FROM comment
+		WHERE id = ?`,
+		cid)
+	if err != nil {
+		return err
+	}
+
+	_, err = dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE comment SET message = ?, updated_at = ?
+		WHERE id = ?`,
+		message, updatedAt.Unix(), cid)
+	return err
+}
+
+// GetCommentRevisionsBySubmissionID returns previous versions of the comments of a submission, newest first
+func (d *mysqlDAL) GetCommentRevisionsBySubmissionID(dbs DBSession, sid int64) ([]*types.CommentRevision, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT comment_revision.id, comment_revision.fk_comment_id, comment_revision.message, comment_revision.created_at
+		FROM comment_revision
+		JOIN comment ON comment.id = comment_revision.fk_comment_id
+		WHERE comment.fk_submission_id = ?
+		ORDER BY comment_revision.created_at DESC, comment_revision.id DESC`,
+		sid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.CommentRevision, 0)
+	var createdAt int64
+	for rows.Next() {
+		cr := &types.CommentRevision{}
+		if err := rows.Scan(&cr.ID, &cr.CommentID, &cr.Message, &createdAt); err != nil {
+			return nil, err
+		}
+		cr.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, cr)
+	}
+
+	return result, nil
+}
+
+// GetUserIDsByUsernames returns IDs of users with given usernames, keyed by the lowercase username
+func (d *mysqlDAL) GetUserIDsByUsernames(dbs DBSession, usernames []string) (map[string]int64, error) {
+	result := make(map[string]int64)
+	if len(usernames) == 0 {
+		return result, nil
+	}
+
+	data := make([]interface{}, 0, len(usernames))
+	for _, username := range usernames {
+		data = append(data, username)
+	}
+
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT id, username
+		FROM discord_user
+		WHERE username IN(?`+strings.Repeat(`,?`, len(usernames)-1)+`)`,
+		data...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uid int64
+	var username string
+	for rows.Next() {
+		if err := rows.Scan(&uid, &username); err != nil {
+			return nil, err
+		}
+		result[strings.ToLower(username)] = uid
+	}
+
+	return result, nil
+}
+
+// StoreCommentImage stores comment image
+func (d *mysqlDAL) StoreCommentImage(dbs DBSession, ci *types.CommentImage) (int64, error) {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO comment_image (fk_comment_id, filename, size, created_at) 
+		VALUES (?, ?, ?, ?)`,
+		ci.CommentID, ci.Filename, ci.Size, ci.CreatedAt.Unix())
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetCommentImage returns comment image, images of deleted comments are not returned
+func (d *mysqlDAL) GetCommentImage(dbs DBSession, ciid int64) (*types.CommentImage, error) {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT comment_image.fk_comment_id, comment.fk_submission_id, comment_image.filename, comment_image.size, comment_image.created_at
+		FROM comment_image
+		JOIN comment ON comment.id = comment_image.fk_comment_id
+		WHERE comment_image.id = ? AND comment.deleted_at IS NULL`,
+		ciid)
+
+	ci := &types.CommentImage{ID: ciid}
+	var createdAt int64
+
+	err := row.Scan(&ci.CommentID, &ci.SubmissionID, &ci.Filename, &ci.Size, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+	ci.CreatedAt = time.Unix(createdAt, 0)
+
+	return ci, nil
+}
+
+// GetCommentImagesBySubmissionID returns images attached to non-deleted comments of a given submission
+func (d *mysqlDAL) GetCommentImagesBySubmissionID(dbs DBSession, sid int64) ([]*types.CommentImage, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT comment_image.id, comment_image.fk_comment_id, comment_image.filename, comment_image.size, comment_image.created_at
+		FROM comment_image
+		JOIN comment ON comment.id = comment_image.fk_comment_id
+		WHERE comment.fk_submission_id = ? AND comment.deleted_at IS NULL
+		ORDER BY comment_image.id`,
+		sid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.CommentImage, 0)
+	var createdAt int64
+	for rows.Next() {
+		ci := &types.CommentImage{SubmissionID: sid}
+		if err := rows.Scan(&ci.ID, &ci.CommentID, &ci.Filename, &ci.Size, &createdAt); err != nil {
+			return nil, err
+		}
+		ci.CreatedAt = time.Unix(createdAt, 0)
+		result = append(result, ci)
+	}
+
+	return result, nil
+}
+
+// StoreAuditLogEntry appends an entry to the audit log, the entries are never updated or deleted
+func (d *mysqlDAL) StoreAuditLogEntry(dbs DBSession, e *types.AuditLogEntry) error {
+	var before, after *string
+	if e.Before != nil {
+		s := string(e.Before)
+		before = &s
+	}
+	if e.After != nil {
+		s := string(e.After)
+		after = &s
+	}
+
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO audit_log (fk_user_id, action, target_type, target_id, before_state, after_state, request_id, created_at) 
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.UserID, e.Action, e.TargetType, e.TargetID, before, after, e.RequestID, e.CreatedAt.Unix())
+	return err
+}
+
+// GetPurgeableFiles returns submission, fixes and flashfreeze files deleted before the given time which have not been purged yet, oldest first
+func (d *mysqlDAL) GetPurgeableFiles(dbs DBSession, deletedBefore time.Time, limit int64) ([]*types.PurgeableFile, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT type, id, current_filename
+		FROM (
+			SELECT ? AS type, id, current_filename, deleted_at FROM submission_file
+			WHERE deleted_at < ?
+			UNION ALL
+			SELECT ?, id, current_filename, deleted_at FROM fixes_file
+			WHERE deleted_at < ?
+			UNION ALL
+			SELECT ?, id, current_filename, deleted_at FROM flashfreeze_file
+			WHERE deleted_at < ?
+		) AS deleted_file
+		WHERE NOT EXISTS (SELECT 1 FROM file_purge WHERE file_purge.file_type = deleted_file.type AND file_purge.file_id = deleted_file.id)
+		ORDER BY deleted_at
+		LIMIT ?`,
+		constants.PurgedFileSubmission, deletedBefore.Unix(),
+		constants.PurgedFileFixes, deletedBefore.Unix(),
+		constants.PurgedFileFlashfreeze, deletedBefore.Unix(),
+		limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.PurgeableFile, 0)
+	for rows.Next() {
+		f := &types.PurgeableFile{}
+		if err := rows.Scan(&f.Type, &f.ID, &f.CurrentFilename); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+
+	return result, rows.Err()
+}
+
+// LockPurgeableFile locks a file which is still deleted before the given time and not purged yet, or returns sql.ErrNoRows if it's not purgeable anymore
+func (d *mysqlDAL) LockPurgeableFile(dbs DBSession, fileType string, fileID int64, deletedBefore time.Time) error {
+	var table string
+	switch fileType {
+	case constants.PurgedFileSubmission:
+		table = "submission_file"
+	case constants.PurgedFileFixes:
+		table = "fixes_file"
+	case constants.PurgedFileFlashfreeze:
+		table = "flashfreeze_file"
+	default:
+		return fmt.Errorf("unknown purgeable file type '%s'", fileType)
+	}
+
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT id FROM `+table+`
+		WHERE id = ? AND deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM file_purge WHERE file_type = ? AND file_id = ?)
+		FOR UPDATE`,
+		fileID, deletedBefore.Unix(), fileType, fileID)
+
+	var id int64
+	return row.Scan(&id)
+}
+
+// StoreFilePurge records that a deleted file has been removed from the disk
+func (d *mysqlDAL) StoreFilePurge(dbs DBSession, fp *types.FilePurge) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO file_purge (file_type, file_id, filename, image_count, reclaimed_bytes, purged_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		fp.FileType, fp.FileID, fp.Filename, fp.ImageCount, fp.ReclaimedBytes, fp.PurgedAt.Unix())
+	return err
+}
+
+// GetFilePurgeTotals returns the number of purged files and the total number of bytes reclaimed by purging
+func (d *mysqlDAL) GetFilePurgeTotals(dbs DBSession) (int64, int64, error) {
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT COUNT(*), COALESCE(SUM(reclaimed_bytes), 0) FROM file_purge`)
+
+	var count int64
+	var size int64
+	if err := row.Scan(&count, &size); err != nil {
+		return 0, 0, err
+	}
+
+	return count, size, nil
+}
+
+// AcquireScheduledTaskLock takes or renews the lock of a scheduled task, an expired lock of another owner is taken over.
+// Returns false if the lock is held by someone else.
+func (d *mysqlDAL) AcquireScheduledTaskLock(dbs DBSession, taskName, owner string, now, lockedUntil time.Time) (bool, error) {
+	// the assignments are evaluated left to right, so locked_until is extended only when locked_by is the owner after the first one
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO scheduled_task_lock (task_name, locked_by, locked_until) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE
+			locked_by = IF(locked_until < ?, VALUES(locked_by), locked_by),
+			locked_until = IF(locked_by = VALUES(locked_by), VALUES(locked_until), locked_until)`,
+		taskName, owner, lockedUntil.Unix(), now.Unix())
+	if err != nil {
+		return false, err
+	}
+
+	row := dbs.Tx().QueryRowContext(dbs.Ctx(), `
+		SELECT locked_by FROM scheduled_task_lock WHERE task_name = ?`,
+		taskName)
+
+	var lockedBy string
+	if err := row.Scan(&lockedBy); err != nil {
+		return false, err
+	}
+
+	return lockedBy == owner, nil
+}
+
+// ReleaseScheduledTaskLock releases the lock of a scheduled task if it's held by the owner
+func (d *mysqlDAL) ReleaseScheduledTaskLock(dbs DBSession, taskName, owner string) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		DELETE FROM scheduled_task_lock WHERE task_name = ? AND locked_by = ?`,
+		taskName, owner)
+	return err
+}
+
+// StoreScheduledTaskRun stores a started run of a scheduled task
+func (d *mysqlDAL) StoreScheduledTaskRun(dbs DBSession, r *types.ScheduledTaskRun) (int64, error) {
+	res, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		INSERT INTO scheduled_task_run (task_name, instance, status, message, started_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		r.TaskName, r.Instance, r.Status, r.Message, r.StartedAt.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// FinishScheduledTaskRun stores the result of a scheduled task run
+func (d *mysqlDAL) FinishScheduledTaskRun(dbs DBSession, id int64, status string, message *string, finishedAt time.Time) error {
+	_, err := dbs.Tx().ExecContext(dbs.Ctx(), `
+		UPDATE scheduled_task_run SET status = ?, message = ?, finished_at = ?
+		WHERE id = ?`,
+		status, message, finishedAt.Unix(), id)
+	return err
+}
+
+// GetScheduledTaskRuns returns the most recent runs of all scheduled tasks, newest first
+func (d *mysqlDAL) GetScheduledTaskRuns(dbs DBSession, limit int64) ([]*types.ScheduledTaskRun, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT id, task_name, instance, status, message, started_at, finished_at
+		FROM scheduled_task_run
+		ORDER BY id DESC
+		LIMIT ?`,
+		limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanScheduledTaskRuns(rows)
+}
+
+// GetLastScheduledTaskRuns returns the last run of every scheduled task which has ever run
+func (d *mysqlDAL) GetLastScheduledTaskRuns(dbs DBSession) ([]*types.ScheduledTaskRun, error) {
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), `
+		SELECT id, task_name, instance, status, message, started_at, finished_at
+		FROM scheduled_task_run
+		WHERE id IN (SELECT MAX(id) FROM scheduled_task_run GROUP BY task_name)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanScheduledTaskRuns(rows)
+}
+
+func scanScheduledTaskRuns(rows *sql.Rows) ([]*types.ScheduledTaskRun, error) {
+	result := make([]*types.ScheduledTaskRun, 0)
+	var startedAt int64
+	var finishedAt *int64
+	for rows.Next() {
+		r := &types.ScheduledTaskRun{}
+		if err := rows.Scan(&r.ID, &r.TaskName, &r.Instance, &r.Status, &r.Message, &startedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		r.StartedAt = time.Unix(startedAt, 0)
+		if finishedAt != nil {
+			t := time.Unix(*finishedAt, 0)
+			r.FinishedAt = &t
+		}
+		result = append(result, r)
+	}
+
+	return result, nil
+}
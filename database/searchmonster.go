@@ -1,6 +1,7 @@
 package database
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/Dri0m/flashpoint-submission-system/types"
 	"github.com/Dri0m/flashpoint-submission-system/utils"
@@ -263,6 +264,14 @@ func (d *mysqlDAL) SearchSubmissions(dbs DBSession, filter *types.SubmissionsFil
 			}
 			masterFilters = append(masterFilters, "(1 = 0)") // exclude legacy results
 		}
+		if len(filter.States) != 0 {
+			filters = append(filters, `(submission_cache.state IN(?`+strings.Repeat(",?", len(filter.States)-1)+`))`)
+			masterFilters = append(masterFilters, `("added" IN(?`+strings.Repeat(",?", len(filter.States)-1)+`))`)
+			for _, state := range filter.States {
+				data = append(data, state)
+				masterData = append(masterData, state)
+			}
+		}
 		if filter.LaunchCommandFuzzy != nil { // TODO not really fuzzy is it
 			filters = append(filters, "(meta.launch_command LIKE ?)")
 			data = append(data, utils.FormatLike(*filter.LaunchCommandFuzzy))
@@ -299,6 +308,29 @@ func (d *mysqlDAL) SearchSubmissions(dbs DBSession, filter *types.SubmissionsFil
 			data = append(data, uid)
 			masterFilters = append(masterFilters, "(1 = 0)") // exclude legacy results
 		}
+		if len(filter.ValidationCodes) != 0 {
+			placeholders := `?` + strings.Repeat(`,?`, len(filter.ValidationCodes)-1)
+			filters = append(filters, `(EXISTS (SELECT 1 FROM submission_file_validation_message AS sfvm WHERE sfvm.fk_submission_file_id = newest_file.id AND sfvm.code IN(`+placeholders+`)))`)
+			for _, vc := range filter.ValidationCodes {
+				data = append(data, vc)
+			}
+			masterFilters = append(masterFilters, "(1 = 0)") // exclude legacy results
+		}
+		if len(filter.Labels) != 0 {
+			filters = append(filters, `(EXISTS (SELECT 1 FROM submission_label AS sl JOIN label ON label.id = sl.fk_label_id
+				WHERE sl.fk_submission_id = submission.id AND label.name IN(?`+strings.Repeat(",?", len(filter.Labels)-1)+`)))`)
+			for _, l := range filter.Labels {
+				data = append(data, l)
+			}
+			masterFilters = append(masterFilters, "(1 = 0)") // exclude legacy results
+		}
+		if len(filter.LabelsNot) != 0 {
+			filters = append(filters, `(NOT EXISTS (SELECT 1 FROM submission_label AS sl JOIN label ON label.id = sl.fk_label_id
+				WHERE sl.fk_submission_id = submission.id AND label.name IN(?`+strings.Repeat(",?", len(filter.LabelsNot)-1)+`)))`)
+			for _, l := range filter.LabelsNot {
+				data = append(data, l)
+			}
+		}
 		if filter.ExcludeLegacy {
 			masterFilters = append(masterFilters, "(1 = 0)") // exclude legacy results
 		}
@@ -352,7 +384,14 @@ func (d *mysqlDAL) SearchSubmissions(dbs DBSession, filter *types.SubmissionsFil
 		submission_cache.active_requested_changes_ids AS active_requested_changes_ids,
 		submission_cache.active_approved_ids AS active_approved_ids,
 		submission_cache.active_verified_ids AS active_verified_ids,
-		submission_cache.distinct_actions AS distinct_actions
+		submission_cache.distinct_actions AS distinct_actions,
+		submission_cache.state AS state,
+		(
+			SELECT GROUP_CONCAT(label.name ORDER BY label.name)
+			FROM submission_label AS sl
+			JOIN label ON label.id = sl.fk_label_id
+			WHERE sl.fk_submission_id = submission.id
+		) AS labels
 		FROM submission
 		LEFT JOIN submission_cache ON submission_cache.fk_submission_id = submission.id
 		LEFT JOIN submission_file AS oldest_file ON oldest_file.id = submission_cache.fk_oldest_file_id
@@ -400,10 +439,12 @@ func (d *mysqlDAL) SearchSubmissions(dbs DBSession, filter *types.SubmissionsFil
 			(SELECT "") AS active_requested_changes_ids,
 			(SELECT "") AS active_approved_ids,
 			(SELECT "") AS active_verified_ids,
-			(SELECT "mark-added") AS distinct_actions
+			(SELECT "mark-added") AS distinct_actions,
+			(SELECT "added") AS state,
+			(SELECT "") AS labels
 			FROM masterdb_game
 			WHERE (SELECT 1) ` + masterAnd + strings.Join(masterFilters, " AND ") + `
-		ORDER BY ` + currentOrderBy + ` ` + currentSortOrder + `
+		ORDER BY ` + currentOrderBy + ` ` + currentSortOrder + `, submission_id ` + currentSortOrder + `
 		`
 	unlimitedQuery := finalQuery + rest
 	finalQuery = unlimitedQuery + ` LIMIT ? OFFSET ?`
@@ -444,6 +485,7 @@ func (d *mysqlDAL) SearchSubmissions(dbs DBSession, filter *types.SubmissionsFil
 	var approvedUserIDs *string
 	var verifiedUserIDs *string
 	var distinctActions *string
+	var labels *string
 
 	for rows.Next() {
 		s := &types.ExtendedSubmission{}
@@ -458,7 +500,9 @@ func (d *mysqlDAL) SearchSubmissions(dbs DBSession, filter *types.SubmissionsFil
 			&s.BotAction,
 			&s.FileCount,
 			&assignedTestingUserIDs, &assignedVerificationUserIDs, &requestedChangesUserIDs, &approvedUserIDs, &verifiedUserIDs,
-			&distinctActions); err != nil {
+			&distinctActions,
+			&s.State,
+			&labels); err != nil {
 			return nil, 0, err
 		}
 		s.SubmitterAvatarURL = utils.FormatAvatarURL(s.SubmitterID, submitterAvatar)
@@ -531,6 +575,13 @@ func (d *mysqlDAL) SearchSubmissions(dbs DBSession, filter *types.SubmissionsFil
 			s.DistinctActions = append(s.DistinctActions, strings.Split(*distinctActions, ",")...)
 		}
 
+		s.Labels = []string{}
+		if labels != nil && len(*labels) > 0 {
+			s.Labels = append(s.Labels, strings.Split(*labels, ",")...)
+		}
+
+		s.RequiredApprovals = d.approvalQuorums.Required(s.SubmissionLevel)
+
 		result = append(result, s)
 	}
 
@@ -998,3 +1049,96 @@ func (d *mysqlDAL) SearchFixes(dbs DBSession, filter *types.FixesFilter) ([]*typ
 
 	return result, counter, nil
 }
+
+// SearchAuditLog returns audit log entries matching the filter, newest first
+func (d *mysqlDAL) SearchAuditLog(dbs DBSession, filter *types.AuditLogFilter) ([]*types.AuditLogEntry, int64, error) {
+	filters := make([]string, 0)
+	data := make([]interface{}, 0)
+
+	const defaultLimit int64 = 100
+	const defaultOffset int64 = 0
+
+	currentLimit := defaultLimit
+	currentOffset := defaultOffset
+
+	if filter != nil {
+		if len(filter.Actions) > 0 {
+			filters = append(filters, `(audit_log.action IN(?`+strings.Repeat(`,?`, len(filter.Actions)-1)+`))`)
+			for _, action := range filter.Actions {
+				data = append(data, action)
+			}
+		}
+		if filter.UserID != nil {
+			filters = append(filters, "(audit_log.fk_user_id = ?)")
+			data = append(data, *filter.UserID)
+		}
+		if filter.TargetType != nil {
+			filters = append(filters, "(audit_log.target_type = ?)")
+			data = append(data, *filter.TargetType)
+		}
+		if filter.TargetID != nil {
+			filters = append(filters, "(audit_log.target_id = ?)")
+			data = append(data, *filter.TargetID)
+		}
+		if filter.RequestID != nil {
+			filters = append(filters, "(audit_log.request_id = ?)")
+			data = append(data, *filter.RequestID)
+		}
+
+		if filter.ResultsPerPage != nil {
+			currentLimit = *filter.ResultsPerPage
+		}
+		if filter.Page != nil {
+			currentOffset = (*filter.Page - 1) * currentLimit
+		}
+	}
+
+	where := ` WHERE 1=1 ` + magicAnd(filters) + strings.Join(filters, " AND ")
+
+	countingQuery := `SELECT COUNT(*) FROM audit_log` + where
+	var counter int64
+	if err := dbs.Tx().QueryRowContext(dbs.Ctx(), countingQuery, data...).Scan(&counter); err != nil {
+		return nil, 0, err
+	}
+
+	finalQuery := `
+		SELECT audit_log.id, audit_log.fk_user_id, discord_user.username, audit_log.action, audit_log.target_type, audit_log.target_id,
+		       audit_log.before_state, audit_log.after_state, audit_log.request_id, audit_log.created_at
+		FROM audit_log
+		LEFT JOIN discord_user ON discord_user.id = audit_log.fk_user_id` + where + `
+		ORDER BY audit_log.id DESC
+		LIMIT ? OFFSET ?`
+	finalData := append(data, currentLimit, currentOffset)
+
+	rows, err := dbs.Tx().QueryContext(dbs.Ctx(), finalQuery, finalData...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	result := make([]*types.AuditLogEntry, 0)
+
+	var username, before, after *string
+	var createdAt int64
+
+	for rows.Next() {
+		e := &types.AuditLogEntry{}
+		if err := rows.Scan(&e.ID, &e.UserID, &username, &e.Action, &e.TargetType, &e.TargetID, &before, &after, &e.RequestID, &createdAt); err != nil {
+			return nil, 0, err
+		}
+		if username != nil {
+			e.Username = *username
+		}
+		if before != nil {
+			e.Before = json.RawMessage(*before)
+		}
+		if after != nil {
+			e.After = json.RawMessage(*after)
+		}
+		e.CreatedAt = time.Unix(createdAt, 0)
+
+		result = append(result, e)
+	}
+
+	return result, counter, nil
+}
@@ -0,0 +1 @@
+DROP TABLE submission_job;
\ No newline at end of file
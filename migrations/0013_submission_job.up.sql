@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS submission_job
+(
+    id                   BIGINT PRIMARY KEY AUTO_INCREMENT,
+    temp_name            VARCHAR(255) UNIQUE NOT NULL,
+    fk_user_id           BIGINT              NOT NULL,
+    fk_submission_id     BIGINT       DEFAULT NULL,
+    resumable_identifier VARCHAR(255)        NOT NULL,
+    filename             VARCHAR(255)        NOT NULL,
+    size                 BIGINT              NOT NULL,
+    chunk_count          BIGINT              NOT NULL,
+    state                VARCHAR(31)         NOT NULL,
+    attempts             BIGINT              NOT NULL DEFAULT 0,
+    last_error           TEXT         DEFAULT NULL,
+    result_submission_id BIGINT       DEFAULT NULL,
+    next_attempt_at      BIGINT              NOT NULL,
+    locked_at            BIGINT       DEFAULT NULL,
+    created_at           BIGINT              NOT NULL,
+    updated_at           BIGINT              NOT NULL,
+    FOREIGN KEY (fk_user_id) REFERENCES discord_user (id),
+    FOREIGN KEY (fk_submission_id) REFERENCES submission (id)
+);
+CREATE INDEX idx_submission_job_state ON submission_job (state);
+CREATE INDEX idx_submission_job_next_attempt_at ON submission_job (next_attempt_at);
+CREATE INDEX idx_submission_job_resumable_identifier ON submission_job (fk_user_id, resumable_identifier);
@@ -0,0 +1,5 @@
+DROP TABLE submission_job_status;
+DROP INDEX idx_submission_job_updated_at ON submission_job;
+ALTER TABLE submission_job
+    DROP COLUMN status,
+    DROP COLUMN status_message;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE submission_job
+    ADD status         VARCHAR(31) DEFAULT NULL,
+    ADD status_message TEXT        DEFAULT NULL;
+CREATE INDEX idx_submission_job_updated_at ON submission_job (updated_at);
+
+CREATE TABLE IF NOT EXISTS submission_job_status
+(
+    id                   BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_submission_job_id BIGINT      NOT NULL,
+    status               VARCHAR(31) NOT NULL,
+    message              TEXT DEFAULT NULL,
+    created_at           BIGINT      NOT NULL,
+    FOREIGN KEY (fk_submission_job_id) REFERENCES submission_job (id) ON DELETE CASCADE
+);
+CREATE INDEX idx_submission_job_status_created_at ON submission_job_status (created_at);
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP TABLE submission_file_contents;
+ALTER TABLE submission_file
+    DROP COLUMN indexed_at,
+    DROP COLUMN indexing_errors;
\ No newline at end of file
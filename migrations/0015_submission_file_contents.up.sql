@@ -0,0 +1,18 @@
+ALTER TABLE submission_file
+    ADD indexed_at      BIGINT DEFAULT NULL,
+    ADD indexing_errors BIGINT DEFAULT NULL;
+
+CREATE TABLE IF NOT EXISTS submission_file_contents
+(
+    id                    BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_submission_file_id BIGINT   NOT NULL,
+    filename              TEXT     NOT NULL,
+    size_compressed       BIGINT   NOT NULL,
+    size_uncompressed     BIGINT   NOT NULL,
+    md5sum                CHAR(32) NOT NULL,
+    sha256sum             CHAR(64) NOT NULL,
+    description           TEXT     NOT NULL,
+    FOREIGN KEY (fk_submission_file_id) REFERENCES submission_file (id)
+);
+CREATE INDEX idx_submission_file_contents_md5sum ON submission_file_contents (md5sum);
+CREATE INDEX idx_submission_file_contents_sha256sum ON submission_file_contents (sha256sum);
\ No newline at end of file
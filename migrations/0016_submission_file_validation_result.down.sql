@@ -0,0 +1 @@
+DROP TABLE submission_file_validation_result;
\ No newline at end of file
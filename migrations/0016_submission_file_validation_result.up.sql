@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS submission_file_validation_result
+(
+    id                    BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_submission_file_id BIGINT       NOT NULL,
+    rule_id               VARCHAR(64)  NOT NULL,
+    severity              VARCHAR(16)  NOT NULL,
+    message               TEXT         NOT NULL,
+    explanation_url       VARCHAR(512) DEFAULT NULL,
+    created_at            BIGINT       NOT NULL,
+    FOREIGN KEY (fk_submission_file_id) REFERENCES submission_file (id)
+);
+CREATE INDEX idx_submission_file_validation_result_rule_id ON submission_file_validation_result (rule_id);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE submission_file_validation_message;
+DROP TABLE submission_file_validation;
\ No newline at end of file
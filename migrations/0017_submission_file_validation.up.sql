@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS submission_file_validation
+(
+    id                    BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_submission_file_id BIGINT  NOT NULL UNIQUE,
+    is_extreme            BOOLEAN NOT NULL,
+    curation_type         INT     NOT NULL,
+    is_degraded           BOOLEAN NOT NULL,
+    response              JSON    NOT NULL,
+    created_at            BIGINT  NOT NULL,
+    FOREIGN KEY (fk_submission_file_id) REFERENCES submission_file (id)
+);
+CREATE TABLE IF NOT EXISTS submission_file_validation_message
+(
+    id                    BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_submission_file_id BIGINT      NOT NULL,
+    kind                  VARCHAR(16) NOT NULL,
+    code                  VARCHAR(64) NOT NULL,
+    message               TEXT        NOT NULL,
+    FOREIGN KEY (fk_submission_file_id) REFERENCES submission_file (id)
+);
+CREATE INDEX idx_submission_file_validation_message_code ON submission_file_validation_message (code);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE similarity_rule;
\ No newline at end of file
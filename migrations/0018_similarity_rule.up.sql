@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS similarity_rule
+(
+    id                 BIGINT PRIMARY KEY AUTO_INCREMENT,
+    pattern            VARCHAR(255) NOT NULL,
+    threshold          DOUBLE       NOT NULL,
+    strip_query_string BOOL         NOT NULL DEFAULT FALSE,
+    ignore_host        BOOL         NOT NULL DEFAULT FALSE,
+    case_fold          BOOL         NOT NULL DEFAULT FALSE,
+    fk_user_id         BIGINT       NOT NULL,
+    updated_at         BIGINT       NOT NULL,
+    FOREIGN KEY (fk_user_id) REFERENCES discord_user (id)
+);
+INSERT INTO similarity_rule (pattern, threshold, strip_query_string, ignore_host, case_fold, fk_user_id, updated_at)
+VALUES ('ssl\\.hwcdn\\.net/html', 0.95, FALSE, FALSE, FALSE, 844246603102945333, UNIX_TIMESTAMP());
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP INDEX idx_submission_cache_state ON submission_cache;
+ALTER TABLE submission_cache
+    DROP COLUMN state;
\ No newline at end of file
@@ -0,0 +1,15 @@
+ALTER TABLE submission_cache
+    ADD COLUMN state VARCHAR(32) NOT NULL DEFAULT 'new';
+CREATE INDEX idx_submission_cache_state ON submission_cache (state);
+
+-- approximation of the replayed state, recomputing the submission cache replays the actual comment history
+UPDATE submission_cache
+SET state = CASE
+                WHEN FIND_IN_SET('reject', distinct_actions) THEN 'rejected'
+                WHEN FIND_IN_SET('mark-added', distinct_actions) THEN 'added'
+                WHEN active_requested_changes_ids IS NOT NULL AND active_requested_changes_ids != '' THEN 'changes-requested'
+                WHEN active_verified_ids IS NOT NULL AND active_verified_ids != '' THEN 'verified'
+                WHEN active_approved_ids IS NOT NULL AND active_approved_ids != '' THEN 'approved'
+                WHEN FIND_IN_SET('assign-testing', distinct_actions) THEN 'in-testing'
+                ELSE 'new'
+    END;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DELETE
+FROM action
+WHERE id = 15;
\ No newline at end of file
@@ -0,0 +1,2 @@
+INSERT IGNORE INTO action (id, name)
+VALUES (15, 'reopen');
\ No newline at end of file
@@ -0,0 +1,4 @@
+DROP TABLE assignment_expiry_notice;
+ALTER TABLE comment
+    DROP FOREIGN KEY fk_comment_target_user_id,
+    DROP COLUMN fk_target_user_id;
\ No newline at end of file
@@ -0,0 +1,10 @@
+ALTER TABLE comment
+    ADD COLUMN fk_target_user_id BIGINT DEFAULT NULL,
+    ADD CONSTRAINT fk_comment_target_user_id FOREIGN KEY (fk_target_user_id) REFERENCES discord_user (id);
+CREATE TABLE IF NOT EXISTS assignment_expiry_notice
+(
+    id            BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_comment_id BIGINT NOT NULL UNIQUE,
+    created_at    BIGINT NOT NULL,
+    FOREIGN KEY (fk_comment_id) REFERENCES comment (id)
+);
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE submission_label;
+DROP TABLE label;
\ No newline at end of file
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS label
+(
+    id         BIGINT PRIMARY KEY AUTO_INCREMENT,
+    name       VARCHAR(64) NOT NULL UNIQUE,
+    fk_user_id BIGINT      NOT NULL,
+    created_at BIGINT      NOT NULL,
+    FOREIGN KEY (fk_user_id) REFERENCES discord_user (id)
+);
+CREATE TABLE IF NOT EXISTS submission_label
+(
+    fk_submission_id BIGINT NOT NULL,
+    fk_label_id      BIGINT NOT NULL,
+    fk_user_id       BIGINT NOT NULL,
+    created_at       BIGINT NOT NULL,
+    PRIMARY KEY (fk_submission_id, fk_label_id),
+    FOREIGN KEY (fk_submission_id) REFERENCES submission (id),
+    FOREIGN KEY (fk_label_id) REFERENCES label (id) ON DELETE CASCADE,
+    FOREIGN KEY (fk_user_id) REFERENCES discord_user (id)
+);
\ No newline at end of file
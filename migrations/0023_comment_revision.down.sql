@@ -0,0 +1,3 @@
+DROP TABLE comment_revision;
+ALTER TABLE comment
+    DROP COLUMN updated_at;
\ No newline at end of file
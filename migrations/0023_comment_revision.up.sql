@@ -0,0 +1,10 @@
+ALTER TABLE comment
+    ADD COLUMN updated_at BIGINT DEFAULT NULL;
+CREATE TABLE IF NOT EXISTS comment_revision
+(
+    id            BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_comment_id BIGINT NOT NULL,
+    message       TEXT,
+    created_at    BIGINT NOT NULL,
+    FOREIGN KEY (fk_comment_id) REFERENCES comment (id)
+);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE comment
+    DROP FOREIGN KEY fk_comment_parent_comment_id,
+    DROP COLUMN fk_parent_comment_id;
\ No newline at end of file
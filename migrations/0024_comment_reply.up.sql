@@ -0,0 +1,3 @@
+ALTER TABLE comment
+    ADD COLUMN fk_parent_comment_id BIGINT DEFAULT NULL,
+    ADD CONSTRAINT fk_comment_parent_comment_id FOREIGN KEY (fk_parent_comment_id) REFERENCES comment (id);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE comment_image;
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS comment_image
+(
+    id            BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_comment_id BIGINT              NOT NULL,
+    filename      VARCHAR(255) UNIQUE NOT NULL,
+    size          BIGINT              NOT NULL,
+    created_at    BIGINT              NOT NULL,
+    FOREIGN KEY (fk_comment_id) REFERENCES comment (id)
+);
\ No newline at end of file
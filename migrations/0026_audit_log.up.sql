@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS audit_log
+(
+    id           BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_user_id   BIGINT      NOT NULL,
+    action       VARCHAR(64) NOT NULL,
+    target_type  VARCHAR(64) DEFAULT NULL,
+    target_id    BIGINT      DEFAULT NULL,
+    before_state JSON        DEFAULT NULL,
+    after_state  JSON        DEFAULT NULL,
+    request_id   VARCHAR(32) NOT NULL,
+    created_at   BIGINT      NOT NULL,
+    FOREIGN KEY (fk_user_id) REFERENCES discord_user (id)
+);
+CREATE INDEX idx_audit_log_action ON audit_log (action);
+CREATE INDEX idx_audit_log_target ON audit_log (target_type, target_id);
+CREATE INDEX idx_audit_log_created_at ON audit_log (created_at);
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE comment
+    DROP FOREIGN KEY fk_comment_deleted_by_user_id,
+    DROP COLUMN fk_deleted_by_user_id;
+ALTER TABLE submission_file
+    DROP FOREIGN KEY fk_submission_file_deleted_by_user_id,
+    DROP COLUMN fk_deleted_by_user_id;
+ALTER TABLE submission
+    DROP FOREIGN KEY fk_submission_deleted_by_user_id,
+    DROP COLUMN fk_deleted_by_user_id;
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE submission
+    ADD COLUMN fk_deleted_by_user_id BIGINT DEFAULT NULL,
+    ADD CONSTRAINT fk_submission_deleted_by_user_id FOREIGN KEY (fk_deleted_by_user_id) REFERENCES discord_user (id);
+ALTER TABLE submission_file
+    ADD COLUMN fk_deleted_by_user_id BIGINT DEFAULT NULL,
+    ADD CONSTRAINT fk_submission_file_deleted_by_user_id FOREIGN KEY (fk_deleted_by_user_id) REFERENCES discord_user (id);
+ALTER TABLE comment
+    ADD COLUMN fk_deleted_by_user_id BIGINT DEFAULT NULL,
+    ADD CONSTRAINT fk_comment_deleted_by_user_id FOREIGN KEY (fk_deleted_by_user_id) REFERENCES discord_user (id);
\ No newline at end of file
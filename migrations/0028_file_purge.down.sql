@@ -0,0 +1 @@
+DROP TABLE file_purge;
\ No newline at end of file
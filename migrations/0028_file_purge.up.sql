@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS file_purge
+(
+    id              BIGINT PRIMARY KEY AUTO_INCREMENT,
+    file_type       VARCHAR(64)  NOT NULL,
+    file_id         BIGINT       NOT NULL,
+    filename        VARCHAR(255) NOT NULL,
+    image_count     BIGINT       NOT NULL,
+    reclaimed_bytes BIGINT       NOT NULL,
+    purged_at       BIGINT       NOT NULL,
+    CONSTRAINT uq_file_purge_file UNIQUE (file_type, file_id)
+);
+CREATE INDEX idx_file_purge_purged_at ON file_purge (purged_at);
\ No newline at end of file
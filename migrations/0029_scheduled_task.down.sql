@@ -0,0 +1,2 @@
+DROP TABLE scheduled_task_run;
+DROP TABLE scheduled_task_lock;
\ No newline at end of file
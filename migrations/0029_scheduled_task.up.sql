@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS scheduled_task_lock
+(
+    task_name    VARCHAR(64) PRIMARY KEY,
+    locked_by    VARCHAR(64) NOT NULL,
+    locked_until BIGINT      NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS scheduled_task_run
+(
+    id          BIGINT PRIMARY KEY AUTO_INCREMENT,
+    task_name   VARCHAR(64) NOT NULL,
+    instance    VARCHAR(64) NOT NULL,
+    status      VARCHAR(16) NOT NULL,
+    message     TEXT   DEFAULT NULL,
+    started_at  BIGINT      NOT NULL,
+    finished_at BIGINT DEFAULT NULL
+);
+CREATE INDEX idx_scheduled_task_run_task_name ON scheduled_task_run (task_name);
+CREATE INDEX idx_scheduled_task_run_started_at ON scheduled_task_run (started_at);
\ No newline at end of file
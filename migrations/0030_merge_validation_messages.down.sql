@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS submission_file_validation_result
+(
+    id                    BIGINT PRIMARY KEY AUTO_INCREMENT,
+    fk_submission_file_id BIGINT       NOT NULL,
+    rule_id               VARCHAR(64)  NOT NULL,
+    severity              VARCHAR(16)  NOT NULL,
+    message               TEXT         NOT NULL,
+    explanation_url       VARCHAR(512) DEFAULT NULL,
+    created_at            BIGINT       NOT NULL,
+    FOREIGN KEY (fk_submission_file_id) REFERENCES submission_file (id)
+);
+CREATE INDEX idx_submission_file_validation_result_rule_id ON submission_file_validation_result (rule_id);
+INSERT INTO submission_file_validation_result (fk_submission_file_id, rule_id, severity, message, explanation_url, created_at)
+SELECT fk_submission_file_id, code, kind, message, explanation_url, created_at
+FROM submission_file_validation_message
+WHERE source = 'rule'
+ORDER BY id;
+DELETE FROM submission_file_validation_message WHERE source = 'rule';
+DROP INDEX idx_submission_file_validation_message_source ON submission_file_validation_message;
+ALTER TABLE submission_file_validation_message
+    DROP COLUMN source,
+    DROP COLUMN explanation_url,
+    DROP COLUMN created_at;
\ No newline at end of file
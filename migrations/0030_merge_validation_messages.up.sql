@@ -0,0 +1,32 @@
+ALTER TABLE submission_file_validation_message
+    ADD COLUMN source          VARCHAR(16)  NOT NULL DEFAULT 'validator' AFTER fk_submission_file_id,
+    ADD COLUMN explanation_url VARCHAR(512) DEFAULT NULL,
+    ADD COLUMN created_at      BIGINT       NOT NULL DEFAULT 0;
+UPDATE submission_file_validation_message AS sfvm
+    INNER JOIN submission_file_validation AS sfv ON sfv.fk_submission_file_id = sfvm.fk_submission_file_id
+SET sfvm.created_at = sfv.created_at;
+UPDATE submission_file_validation_message
+SET code = CASE
+               WHEN REGEXP_LIKE(message, '^meta file is missing', 'i') THEN 'meta-missing'
+               WHEN REGEXP_LIKE(message, '^failed to parse the meta file', 'i') THEN 'meta-invalid'
+               WHEN REGEXP_LIKE(message, '^logo file is missing', 'i') THEN 'logo-missing'
+               WHEN REGEXP_LIKE(message, '^screenshot file is missing', 'i') THEN 'screenshot-missing'
+               WHEN REGEXP_LIKE(message, '^title is missing', 'i') THEN 'title-missing'
+               WHEN REGEXP_LIKE(message, '^tags (is|are) missing', 'i') THEN 'tags-missing'
+               WHEN REGEXP_LIKE(message, '^source is missing', 'i') THEN 'source-missing'
+               WHEN REGEXP_LIKE(message, '^platform is missing', 'i') THEN 'platform-missing'
+               WHEN REGEXP_LIKE(message, '^application path is missing', 'i') THEN 'application-path-missing'
+               WHEN REGEXP_LIKE(message, '^launch command is missing', 'i') THEN 'launch-command-missing'
+               WHEN REGEXP_LIKE(message, '^library .* is not valid', 'i') THEN 'library-invalid'
+               WHEN REGEXP_LIKE(message, '^extreme .* is not valid', 'i') THEN 'extreme-invalid'
+               WHEN REGEXP_LIKE(message, '^tag .* is not a known tag', 'i') THEN 'tag-unknown'
+               WHEN REGEXP_LIKE(message, '^tags could not be verified', 'i') THEN 'tags-unverified'
+               WHEN REGEXP_LIKE(message, '^don''t use .* as the platform', 'i') THEN 'platform-discouraged'
+               ELSE 'unknown'
+    END;
+INSERT INTO submission_file_validation_message (fk_submission_file_id, source, kind, code, message, explanation_url, created_at)
+SELECT fk_submission_file_id, 'rule', severity, rule_id, message, explanation_url, created_at
+FROM submission_file_validation_result
+ORDER BY id;
+DROP TABLE submission_file_validation_result;
+CREATE INDEX idx_submission_file_validation_message_source ON submission_file_validation_message (fk_submission_file_id, source);
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE comment_revision
+    MODIFY COLUMN message TEXT;
\ No newline at end of file
@@ -0,0 +1,2 @@
+ALTER TABLE comment_revision
+    MODIFY COLUMN message MEDIUMTEXT;
\ No newline at end of file
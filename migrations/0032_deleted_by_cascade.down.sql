@@ -0,0 +1,4 @@
+ALTER TABLE comment
+    DROP COLUMN is_deleted_by_cascade;
+ALTER TABLE submission_file
+    DROP COLUMN is_deleted_by_cascade;
\ No newline at end of file
@@ -0,0 +1,14 @@
+ALTER TABLE submission_file
+    ADD COLUMN is_deleted_by_cascade BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE comment
+    ADD COLUMN is_deleted_by_cascade BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE submission_file
+    JOIN submission ON submission.id = submission_file.fk_submission_id
+SET submission_file.is_deleted_by_cascade = TRUE
+WHERE submission.deleted_at IS NOT NULL
+  AND submission_file.deleted_at = submission.deleted_at;
+UPDATE comment
+    JOIN submission ON submission.id = comment.fk_submission_id
+SET comment.is_deleted_by_cascade = TRUE
+WHERE submission.deleted_at IS NOT NULL
+  AND comment.deleted_at = submission.deleted_at;
\ No newline at end of file
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// RunAssignmentSweeper periodically unassigns users whose assignments expired, and warns them a day before it happens
+func (s *SiteService) RunAssignmentSweeper(logger *logrus.Entry, ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	l := logger.WithField("serviceName", "assignmentSweeper")
+	defer l.Info("sweeper stopped")
+
+	ticker := time.NewTicker(constants.AssignmentSweepInterval)
+	defer ticker.Stop()
+
+	sctx := context.WithValue(ctx, utils.CtxKeys.Log, l)
+
+	for {
+		expired, noticed, err := s.sweepAssignments(sctx)
+		if err != nil && err != context.Canceled {
+			l.Error(err)
+		} else {
+			l.WithField("expired", expired).WithField("noticed", noticed).Debug("assignments swept")
+		}
+
+		select {
+		case <-ctx.Done():
+			l.Info("context cancelled, stopping assignment sweeper")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepAssignments posts unassign comments for expired assignments and notifies assignees about assignments which are about to expire.
+// Every assignment is swept in a session of its own, one which fails is logged and left for the next sweep.
+func (s *SiteService) sweepAssignments(ctx context.Context) (int, int, error) {
+	assignments, expiries, err := func() ([]*types.ActiveAssignment, map[int64]time.Duration, error) {
+		dbs, err := s.dal.NewSession(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer dbs.Rollback()
+
+		assignments, err := s.dal.GetActiveAssignments(dbs)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		expiries := make(map[int64]time.Duration)
+		for _, a := range assignments {
+			if _, ok := expiries[a.UserID]; ok {
+				continue
+			}
+			roles, err := s.dal.GetDiscordUserRoles(dbs, a.UserID)
+			if err != nil {
+				return nil, nil, err
+			}
+			expiries[a.UserID] = s.assignmentExpiry.For(roles)
+		}
+
+		return assignments, expiries, nil
+	}()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	now := s.clock.Now()
+	expired := 0
+	noticed := 0
+
+	defer func() {
+		if expired > 0 || noticed > 0 {
+			s.announceNotification()
+		}
+	}()
+
+	for _, a := range assignments {
+		if err := ctx.Err(); err != nil {
+			return expired, noticed, err
+		}
+
+		expiry := expiries[a.UserID]
+		expire, notice := assignmentSweepDecision(a, expiry, now)
+		if !expire && !notice {
+			continue
+		}
+
+		if err := s.sweepAssignment(ctx, a, expiry, expire, now); err != nil {
+			utils.LogCtx(ctx).WithField("submissionID", a.SubmissionID).WithField("commentID", a.CommentID).Error(err)
+			continue
+		}
+		if expire {
+			expired++
+		} else {
+			noticed++
+		}
+	}
+
+	return expired, noticed, nil
+}
+
+// sweepAssignment expires the assignment, or notifies the assignee about the upcoming expiry if it is not expired yet
+func (s *SiteService) sweepAssignment(ctx context.Context, a *types.ActiveAssignment, expiry time.Duration, expire bool, now time.Time) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbs.Rollback()
+
+	if expire {
+		if err := s.expireAssignment(dbs, a, expiry, now); err != nil {
+			return err
+		}
+	} else {
+		if err := s.createAssignmentExpiryNotification(dbs, a, a.AssignedAt.Add(expiry)); err != nil {
+			return err
+		}
+		if err := s.dal.StoreAssignmentExpiryNotice(dbs, a.CommentID, now); err != nil {
+			return err
+		}
+	}
+
+	return dbs.Commit()
+}
+
+// assignmentSweepDecision tells if the assignment has expired, or if the assignee should be notified about the upcoming expiry
+func assignmentSweepDecision(a *types.ActiveAssignment, expiry time.Duration, now time.Time) (expire bool, notice bool) {
+	if expiry <= 0 {
+		return false, false
+	}
+	expiresAt := a.AssignedAt.Add(expiry)
+	if !now.Before(expiresAt) {
+		return true, false
+	}
+	return false, !a.IsNoticeSent && !now.Before(expiresAt.Add(-constants.AssignmentExpiryNoticeBefore))
+}
+
+// expireAssignment unassigns the assignee on their behalf
+func (s *SiteService) expireAssignment(dbs database.DBSession, a *types.ActiveAssignment, expiry time.Duration, now time.Time) error {
+	unassignAction := constants.ActionUnassignTesting
+	kind := "testing"
+	if a.Action == constants.ActionAssignVerification {
+		unassignAction = constants.ActionUnassignVerification
+		kind = "verification"
+	}
+
+	uid := a.UserID
+	assignee, err := s.dal.GetDiscordUser(dbs, uid)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("The %s assignment of %s expired after %d days.", kind, assignee.Username, int64(expiry.Hours()/24))
+
+	c := &types.Comment{
+		AuthorID:     constants.SystemID,
+		SubmissionID: a.SubmissionID,
+		Message:      &msg,
+		Action:       unassignAction,
+		CreatedAt:    now,
+		TargetUserID: &uid,
+	}
+
+	if err := s.dal.StoreComment(dbs, c); err != nil {
+		return err
+	}
+
+	if err := s.dal.UpdateSubmissionCacheTable(dbs, a.SubmissionID); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("You've got mail! <@%d>\n", uid))
+	b.WriteString(fmt.Sprintf("<https://fpfss.unstable.life/web/submission/%d>\n", a.SubmissionID))
+	b.WriteString(fmt.Sprintf("Your %s assignment of this submission has expired, so you have been unassigned.", kind))
+	b.WriteString("\n----------------------------------------------------------\n")
+
+	return s.dal.StoreNotification(dbs, b.String(), constants.NotificationDefault)
+}
+
+// createAssignmentExpiryNotification warns the assignee that the assignment is about to expire
+func (s *SiteService) createAssignmentExpiryNotification(dbs database.DBSession, a *types.ActiveAssignment, expiresAt time.Time) error {
+	kind := "testing"
+	if a.Action == constants.ActionAssignVerification {
+		kind = "verification"
+	}
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("You've got mail! <@%d>\n", a.UserID))
+	b.WriteString(fmt.Sprintf("<https://fpfss.unstable.life/web/submission/%d>\n", a.SubmissionID))
+	b.WriteString(fmt.Sprintf("Your %s assignment of this submission expires at %s. Unassign and assign the submission again if you are still working on it.",
+		kind, expiresAt.UTC().Format("2006-01-02 15:04 MST")))
+	b.WriteString("\n----------------------------------------------------------\n")
+
+	return s.dal.StoreNotification(dbs, b.String(), constants.NotificationDefault)
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_assignmentSweepDecision(t *testing.T) {
+	assignedAt := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	expiry := time.Hour * 24 * 14
+
+	tests := []struct {
+		name         string
+		isNoticeSent bool
+		expiry       time.Duration
+		now          time.Time
+		wantExpire   bool
+		wantNotice   bool
+	}{
+		{name: "fresh assignment", expiry: expiry, now: assignedAt.Add(time.Hour)},
+		{name: "notice a day before expiry", expiry: expiry, now: assignedAt.Add(expiry - time.Hour*23), wantNotice: true},
+		{name: "notice only once", isNoticeSent: true, expiry: expiry, now: assignedAt.Add(expiry - time.Hour*23)},
+		{name: "expired", expiry: expiry, now: assignedAt.Add(expiry), wantExpire: true},
+		{name: "expired without notice", isNoticeSent: false, expiry: expiry, now: assignedAt.Add(expiry * 2), wantExpire: true},
+		{name: "no expiry", expiry: 0, now: assignedAt.Add(expiry * 10)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &types.ActiveAssignment{AssignedAt: assignedAt, IsNoticeSent: tt.isNoticeSent}
+			expire, notice := assignmentSweepDecision(a, tt.expiry, tt.now)
+			assert.Equal(t, tt.wantExpire, expire)
+			assert.Equal(t, tt.wantNotice, notice)
+		})
+	}
+}
+
+func Test_AssignmentExpiry_For(t *testing.T) {
+	ae := types.AssignmentExpiry{
+		Default: time.Hour * 24 * 14,
+		ByRole:  map[string]time.Duration{"Tester": time.Hour * 24 * 21, "Curator": time.Hour * 24 * 7},
+	}
+
+	assert.Equal(t, time.Hour*24*14, ae.For(nil))
+	assert.Equal(t, time.Hour*24*14, ae.For([]string{"Member"}))
+	assert.Equal(t, time.Hour*24*7, ae.For([]string{"Curator"}), "role override shortens the default")
+	assert.Equal(t, time.Hour*24*21, ae.For([]string{"Curator", "Tester"}))
+
+	ae.ByRole["Moderator"] = 0
+	assert.Equal(t, time.Duration(0), ae.For([]string{"Tester", "Moderator"}), "zero override never expires")
+
+	unlimited := types.AssignmentExpiry{ByRole: map[string]time.Duration{"Trial Tester": time.Hour * 24 * 3}}
+	assert.Equal(t, time.Duration(0), unlimited.For([]string{"Tester"}), "zero default never expires")
+	assert.Equal(t, time.Hour*24*3, unlimited.For([]string{"Trial Tester"}), "role override applies instead of zero default")
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+// newAuditLogEntry creates an audit log entry of the user and request in the context, nil states are not stored
+func newAuditLogEntry(ctx context.Context, now time.Time, action, targetType string, targetID *int64, before, after interface{}) (*types.AuditLogEntry, error) {
+	e := &types.AuditLogEntry{
+		UserID:    utils.UserID(ctx),
+		Action:    action,
+		TargetID:  targetID,
+		RequestID: utils.RequestID(ctx),
+		CreatedAt: now,
+	}
+
+	if targetType != "" {
+		e.TargetType = &targetType
+	}
+
+	var err error
+	if before != nil {
+		if e.Before, err = json.Marshal(before); err != nil {
+			return nil, err
+		}
+	}
+	if after != nil {
+		if e.After, err = json.Marshal(after); err != nil {
+			return nil, err
+		}
+	}
+
+	return e, nil
+}
+
+// storeAuditLogEntry records the action in the transaction which performs it, so that there's no action without a record
+func (s *SiteService) storeAuditLogEntry(dbs database.DBSession, action, targetType string, targetID *int64, before, after interface{}) error {
+	e, err := newAuditLogEntry(dbs.Ctx(), s.clock.Now(), action, targetType, targetID, before, after)
+	if err != nil {
+		return err
+	}
+	return s.dal.StoreAuditLogEntry(dbs, e)
+}
+
+// RecordAuditLogEntry records an action that does not run in a single transaction, such as the maintenance jobs.
+// It's called before the action starts.
+func (s *SiteService) RecordAuditLogEntry(ctx context.Context, action, targetType string, targetID *int64, before, after interface{}) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	if err := s.storeAuditLogEntry(dbs, action, targetType, targetID, before, after); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	return nil
+}
+
+func (s *SiteService) GetAuditLogPageData(ctx context.Context, filter *types.AuditLogFilter) (*types.AuditLogPageData, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	bpd, err := s.GetBasePageData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, count, err := s.dal.SearchAuditLog(dbs, filter)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	pageData := &types.AuditLogPageData{
+		BasePageData: *bpd,
+		Entries:      entries,
+		TotalCount:   count,
+		Filter:       *filter,
+		Actions:      constants.GetAuditActions(),
+	}
+
+	return pageData, nil
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newAuditLogEntry(t *testing.T) {
+	ctx := context.WithValue(context.Background(), utils.CtxKeys.UserID, int64(7))
+	ctx = context.WithValue(ctx, utils.CtxKeys.RequestID, "abcdef")
+	now := time.Unix(1600000000, 0)
+	var sid int64 = 3
+
+	e, err := newAuditLogEntry(ctx, now, constants.AuditActionSoftDeleteSubmission, constants.AuditTargetSubmission, &sid,
+		map[string]string{"title": "foo"}, map[string]string{"deleted_reason": "spam"})
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(7), e.UserID)
+	assert.Equal(t, "abcdef", e.RequestID)
+	assert.Equal(t, constants.AuditActionSoftDeleteSubmission, e.Action)
+	assert.Equal(t, constants.AuditTargetSubmission, *e.TargetType)
+	assert.Equal(t, &sid, e.TargetID)
+	assert.JSONEq(t, `{"title": "foo"}`, string(e.Before))
+	assert.JSONEq(t, `{"deleted_reason": "spam"}`, string(e.After))
+	assert.Equal(t, now, e.CreatedAt)
+
+	e, err = newAuditLogEntry(ctx, now, constants.AuditActionUpdateMasterDB, "", nil, nil, nil)
+	require.NoError(t, err)
+
+	assert.Nil(t, e.TargetType)
+	assert.Nil(t, e.TargetID)
+	assert.Nil(t, e.Before)
+	assert.Nil(t, e.After)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// goBackground runs the function in a goroutine which the shutdown waits for, its context is cancelled when the shutdown starts.
+// Nothing is started once the shutdown has started.
+func (s *SiteService) goBackground(fn func(ctx context.Context)) error {
+	s.backgroundMutex.Lock()
+	defer s.backgroundMutex.Unlock()
+
+	if err := s.backgroundCtx.Err(); err != nil {
+		return err
+	}
+
+	s.backgroundWG.Add(1)
+	go func() {
+		defer s.backgroundWG.Done()
+		fn(s.backgroundCtx)
+	}()
+
+	return nil
+}
+
+// RunBackgroundTaskKeeper cancels the goroutines started by goBackground on shutdown and waits until they finish
+func (s *SiteService) RunBackgroundTaskKeeper(logger *logrus.Entry, ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	l := logger.WithField("serviceName", "backgroundTaskKeeper")
+	defer l.Info("background task keeper stopped")
+
+	<-ctx.Done()
+	l.Info("context cancelled, waiting for background tasks to finish")
+
+	s.backgroundMutex.Lock()
+	s.cancelBackground()
+	s.backgroundMutex.Unlock()
+
+	s.backgroundWG.Wait()
+}
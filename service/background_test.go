@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_goBackground(t *testing.T) {
+	backgroundCtx, cancelBackground := context.WithCancel(context.Background())
+	s := &SiteService{backgroundCtx: backgroundCtx, cancelBackground: cancelBackground}
+
+	started := make(chan struct{})
+	finished := false
+	require.NoError(t, s.goBackground(func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		finished = true
+	}))
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+	l := logrus.New()
+	l.Out = ioutil.Discard
+	wg.Add(1)
+	go s.RunBackgroundTaskKeeper(logrus.NewEntry(l), ctx, wg)
+
+	cancel()
+	wg.Wait()
+
+	// the keeper returns only after the background goroutine has finished
+	assert.True(t, finished)
+	assert.Error(t, s.goBackground(func(ctx context.Context) {}))
+}
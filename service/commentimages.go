@@ -0,0 +1,148 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+// convertCommentImage decodes an uploaded png, jpeg or gif image and encodes it as png.
+// Re-encoding makes sure only actual images get stored and strips any metadata the file carried.
+func convertCommentImage(data []byte) ([]byte, error) {
+	if int64(len(data)) > constants.CommentImageMaxFilesize {
+		return nil, fmt.Errorf("image is larger than %s", utils.SizeToString(constants.CommentImageMaxFilesize))
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("unsupported image format, use png, jpeg or gif")
+	}
+	if config.Width > constants.CommentImageMaxDimension || config.Height > constants.CommentImageMaxDimension {
+		return nil, fmt.Errorf("image cannot be larger than %dx%d pixels", constants.CommentImageMaxDimension, constants.CommentImageMaxDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image")
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// convertCommentImages converts all images of a comment, the errors are public
+func convertCommentImages(images [][]byte) ([][]byte, error) {
+	if len(images) > constants.CommentImageMaxCount {
+		return nil, perr(fmt.Sprintf("cannot attach more than %d images to a comment", constants.CommentImageMaxCount), http.StatusBadRequest)
+	}
+
+	result := make([][]byte, 0, len(images))
+	for i, data := range images {
+		converted, err := convertCommentImage(data)
+		if err != nil {
+			return nil, perr(fmt.Sprintf("image %d: %s", i+1, err.Error()), http.StatusBadRequest)
+		}
+		result = append(result, converted)
+	}
+
+	return result, nil
+}
+
+// storeCommentImages writes the images into the submission images directory and attaches them to the comment,
+// paths of the written files are returned even on failure so that the caller can clean them up
+func (s *SiteService) storeCommentImages(dbs database.DBSession, cid int64, images [][]byte) ([]string, error) {
+	imageFilePaths := make([]string, 0, len(images))
+
+	for _, data := range images {
+		var imageFilename string
+		var imageFilePath string
+		for {
+			imageFilename = s.randomStringProvider.RandomString(64)
+			imageFilePath = fmt.Sprintf("%s/%s", s.submissionImagesDir, imageFilename)
+			if !utils.FileExists(imageFilePath) {
+				break
+			}
+		}
+
+		imageFilePaths = append(imageFilePaths, imageFilePath)
+
+		if err := ioutil.WriteFile(imageFilePath, data, 0644); err != nil {
+			return imageFilePaths, err
+		}
+
+		ci := &types.CommentImage{
+			CommentID: cid,
+			Filename:  imageFilename,
+			Size:      int64(len(data)),
+			CreatedAt: s.clock.Now(),
+		}
+
+		if _, err := s.dal.StoreCommentImage(dbs, ci); err != nil {
+			return imageFilePaths, err
+		}
+	}
+
+	return imageFilePaths, nil
+}
+
+// attachCommentImages fills in the image IDs of the comments
+func attachCommentImages(comments []*types.ExtendedComment, images []*types.CommentImage) {
+	byComment := make(map[int64][]int64)
+	for _, ci := range images {
+		byComment[ci.CommentID] = append(byComment[ci.CommentID], ci.ID)
+	}
+	for _, c := range comments {
+		c.ImageIDs = byComment[c.CommentID]
+	}
+}
+
+// GetCommentImage returns a comment image that belongs to the given submission
+func (s *SiteService) GetCommentImage(ctx context.Context, sid, ciid int64) (*types.CommentImage, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	ci, err := s.dal.GetCommentImage(dbs, ciid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, perr("comment image not found", http.StatusNotFound)
+		}
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	if ci.SubmissionID != sid {
+		return nil, perr("comment image not found", http.StatusNotFound)
+	}
+
+	return ci, nil
+}
+
+// removeFiles removes files written during a failed operation
+func removeFiles(ctx context.Context, filePaths []string) {
+	for _, fp := range filePaths {
+		utils.LogCtx(ctx).Debugf("cleaning up file '%s'...", fp)
+		if err := os.Remove(fp); err != nil {
+			utils.LogCtx(ctx).Error(err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodedTestImage(t *testing.T, width, height int, encode func(*bytes.Buffer, image.Image) error) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	require.NoError(t, encode(&buf, img))
+	return buf.Bytes()
+}
+
+func Test_convertCommentImage(t *testing.T) {
+	encodePNG := func(buf *bytes.Buffer, img image.Image) error { return png.Encode(buf, img) }
+	encodeJPEG := func(buf *bytes.Buffer, img image.Image) error { return jpeg.Encode(buf, img, nil) }
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "png", data: encodedTestImage(t, 4, 3, encodePNG)},
+		{name: "jpeg is converted", data: encodedTestImage(t, 4, 3, encodeJPEG)},
+		{name: "not an image", data: []byte("<html><script>alert(1)</script></html>"), wantErr: true},
+		{name: "too many pixels", data: encodedTestImage(t, constants.CommentImageMaxDimension+1, 1, encodePNG), wantErr: true},
+		{name: "too large", data: make([]byte, constants.CommentImageMaxFilesize+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertCommentImage(tt.data)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			img, format, err := image.Decode(bytes.NewReader(got))
+			require.NoError(t, err)
+			assert.Equal(t, "png", format)
+			assert.Equal(t, image.Rect(0, 0, 4, 3), img.Bounds())
+		})
+	}
+}
+
+func Test_convertCommentImages_tooMany(t *testing.T) {
+	images := make([][]byte, constants.CommentImageMaxCount+1)
+	_, err := convertCommentImages(images)
+	assert.Error(t, err)
+}
+
+func Test_attachCommentImages(t *testing.T) {
+	comments := []*types.ExtendedComment{{CommentID: 1}, {CommentID: 2}}
+	images := []*types.CommentImage{{ID: 10, CommentID: 2}, {ID: 11, CommentID: 2}}
+
+	attachCommentImages(comments, images)
+
+	assert.Nil(t, comments[0].ImageIDs)
+	assert.Equal(t, []int64{10, 11}, comments[1].ImageIDs)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/Dri0m/flashpoint-submission-system/constants"
 	"github.com/Dri0m/flashpoint-submission-system/types"
 	"net/http"
+	"strings"
 )
 
 func uidIn(uid int64, ids []int64) bool {
@@ -16,14 +17,58 @@ func uidIn(uid int64, ids []int64) bool {
 	return false
 }
 
+// actionDescriptions describe comment actions in error messages
+var actionDescriptions = map[string]string{
+	constants.ActionComment:              "comment on",
+	constants.ActionApprove:              "approve",
+	constants.ActionRequestChanges:       "request changes on",
+	constants.ActionMarkAdded:            "mark as added",
+	constants.ActionUpload:               "upload a new version of",
+	constants.ActionVerify:               "verify",
+	constants.ActionAssignTesting:        "assign for testing",
+	constants.ActionUnassignTesting:      "unassign from testing",
+	constants.ActionAssignVerification:   "assign for verification",
+	constants.ActionUnassignVerification: "unassign from verification",
+	constants.ActionReject:               "reject",
+	constants.ActionReopen:               "reopen",
+}
+
+// isTransitionValidForSubmission checks the action against the workflow state of the submission
+func isTransitionValidForSubmission(formAction string, submission *types.ExtendedSubmission) error {
+	if !constants.IsWorkflowAction(formAction) {
+		return nil
+	}
+	if _, ok := constants.NextSubmissionState(submission.State, formAction); ok {
+		return nil
+	}
+
+	sid := submission.SubmissionID
+	description, ok := actionDescriptions[formAction]
+	if !ok {
+		description = formAction
+	}
+
+	msg := fmt.Sprintf("submission %d is in state '%s' so you cannot %s it, this is only possible in states: %s",
+		sid, submission.State, description, strings.Join(constants.AllowedSubmissionStates(formAction), ", "))
+	if submission.State == constants.SubmissionStateAdded {
+		msg = fmt.Sprintf("submission %d is already marked as added so you cannot %s it, please submit a bug report or a pending fix if there is a problem with the submission", sid, description)
+	}
+
+	return perr(msg, http.StatusConflict)
+}
+
 func isActionValidForSubmission(uid int64, formAction string, submission *types.ExtendedSubmission) error {
-	markedAdded := false
 	sid := submission.SubmissionID
 
-	for _, distinctAction := range submission.DistinctActions {
-		if constants.ActionMarkAdded == distinctAction {
-			markedAdded = true
-			break
+	if err := isTransitionValidForSubmission(formAction, submission); err != nil {
+		return err
+	}
+
+	// don't let users verify until the submission has enough approvals from distinct testers
+	if formAction == constants.ActionAssignVerification || formAction == constants.ActionVerify || formAction == constants.ActionMarkAdded {
+		if missing := submission.MissingApprovals(); missing > 0 {
+			return perr(fmt.Sprintf("submission %d has %d of %d required approvals so you cannot %s it, %d more needed",
+				sid, len(submission.ApprovedUserIDs), submission.RequiredApprovals, actionDescriptions[formAction], missing), http.StatusConflict)
 		}
 	}
 
@@ -71,18 +116,10 @@ func isActionValidForSubmission(uid int64, formAction string, submission *types.
 			return perr(fmt.Sprintf("you have already approved submission %d", sid), http.StatusBadRequest)
 		}
 
-	} else if formAction == constants.ActionRequestChanges {
-		if markedAdded {
-			return perr(fmt.Sprintf("submission %d is alrady marked as added so you cannot request changes on it, please submit a bug report or a pending fix if there is a problem with the submission", sid), http.StatusBadRequest)
-		}
 	} else if formAction == constants.ActionVerify {
 		if uidIn(uid, submission.VerifiedUserIDs) {
 			return perr(fmt.Sprintf("you have already verified submission %d", sid), http.StatusBadRequest)
 		}
-	} else if formAction == constants.ActionMarkAdded {
-		if markedAdded {
-			return perr(fmt.Sprintf("submission %d is alrady marked as added so it cannot be marked again", sid), http.StatusBadRequest)
-		}
 	}
 
 	// don't let the same user assign the submission to himself for more than one type of assignment
@@ -126,17 +163,6 @@ func isActionValidForSubmission(uid int64, formAction string, submission *types.
 		}
 	}
 
-	// don't let users verify before approve
-	if formAction == constants.ActionAssignVerification {
-		if len(submission.ApprovedUserIDs) == 0 {
-			return perr(fmt.Sprintf("submission %d is not approved (tested) so you cannot assign it for verification", sid), http.StatusBadRequest)
-		}
-	} else if formAction == constants.ActionVerify {
-		if len(submission.ApprovedUserIDs) == 0 {
-			return perr(fmt.Sprintf("submission %d is not approved (tested) so you cannot verify it", sid), http.StatusBadRequest)
-		}
-	}
-
 	// don't let users assign submission they have already confirmed to be good
 	if formAction == constants.ActionAssignTesting {
 		if uidIn(uid, submission.ApprovedUserIDs) {
@@ -148,43 +174,40 @@ func isActionValidForSubmission(uid int64, formAction string, submission *types.
 		}
 	}
 
-	// don't let user mark submission as added until it's verified
-	if formAction == constants.ActionMarkAdded {
-		if len(submission.VerifiedUserIDs) == 0 {
-			return perr(fmt.Sprintf("submission %d is not verified so you cannot mark it as added", sid), http.StatusBadRequest)
-		}
-	}
+	return nil
+}
 
-	// cannot assign if marked as added
-	if formAction == constants.ActionAssignTesting {
-		if markedAdded {
-			return perr(fmt.Sprintf("submission %d is alrady marked as added so you cannot assign it for testing, please submit a bug report or a pending fix if there is a problem with the submission", sid), http.StatusBadRequest)
-		}
-	}
+// editableActions are the actions whose message is written by the author of the comment
+var editableActions = map[string]bool{
+	constants.ActionComment:        true,
+	constants.ActionApprove:        true,
+	constants.ActionRequestChanges: true,
+	constants.ActionVerify:         true,
+	constants.ActionMarkAdded:      true,
+	constants.ActionReject:         true,
+	constants.ActionReopen:         true,
+}
 
-	// cannot reject if marked as added
-	if formAction == constants.ActionReject {
-		if markedAdded {
-			return perr(fmt.Sprintf("submission %d is alrady marked as added so you cannot reject it", sid), http.StatusBadRequest)
-		}
+// isCommentEditable checks if the user can change the message of the comment to the new one
+func isCommentEditable(uid int64, comment *types.ExtendedComment, submission *types.ExtendedSubmission, message *string) error {
+	if comment.AuthorID != uid {
+		return perr("you can only edit your own comments", http.StatusForbidden)
+	}
+	if !editableActions[comment.Action] {
+		return perr(fmt.Sprintf("comments with action '%s' cannot be edited", comment.Action), http.StatusBadRequest)
+	}
+	if submission.State == constants.SubmissionStateAdded {
+		return perr(fmt.Sprintf("submission %d is already marked as added so its comments cannot be edited", submission.SubmissionID), http.StatusConflict)
 	}
 
-	// cannot double reject
-	if formAction == constants.ActionReject {
-		for _, distinctAction := range submission.DistinctActions {
-			if constants.ActionReject == distinctAction {
-				return perr(fmt.Sprintf("submission %d is alrady rejected so you cannot reject it", sid), http.StatusBadRequest)
-			}
+	for _, a := range constants.GetActionsWithMandatoryMessage() {
+		if comment.Action == a && message == nil {
+			return perr(fmt.Sprintf("comment with action '%s' cannot be left without a message", comment.Action), http.StatusBadRequest)
 		}
 	}
 
-	// cannot upload if rejected
-	if formAction == constants.ActionUpload {
-		for _, distinctAction := range submission.DistinctActions {
-			if constants.ActionReject == distinctAction {
-				return perr(fmt.Sprintf("submission %d is alrady rejected so you cannot upload a new version", sid), http.StatusBadRequest)
-			}
-		}
+	if (comment.Message == nil && message == nil) || (comment.Message != nil && message != nil && *comment.Message == *message) {
+		return perr("the message has not changed", http.StatusBadRequest)
 	}
 
 	return nil
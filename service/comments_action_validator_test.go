@@ -26,7 +26,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        lastUploaderID,
 				formAction: constants.ActionAssignTesting,
-				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID},
+				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID, State: constants.SubmissionStateNew},
 			},
 			wantErr: true,
 		},
@@ -35,7 +35,25 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        lastUploaderID,
 				formAction: constants.ActionAssignVerification,
-				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID},
+				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID, RequiredApprovals: 1, State: constants.SubmissionStateApproved},
+			},
+			wantErr: true,
+		},
+		{
+			name: "uploader can assign an approved submission for verification",
+			args: args{
+				uid:        lastUploaderID,
+				formAction: constants.ActionAssignVerification,
+				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID, ApprovedUserIDs: []int64{commenterID}, RequiredApprovals: 1, State: constants.SubmissionStateApproved},
+			},
+			wantErr: false,
+		},
+		{
+			name: "uploader cannot unassign from verification",
+			args: args{
+				uid:        lastUploaderID,
+				formAction: constants.ActionUnassignVerification,
+				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID, AssignedVerificationUserIDs: []int64{lastUploaderID}, State: constants.SubmissionStateApproved},
 			},
 			wantErr: true,
 		},
@@ -44,7 +62,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        lastUploaderID,
 				formAction: constants.ActionApprove,
-				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID},
+				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID, State: constants.SubmissionStateInTesting},
 			},
 			wantErr: true,
 		},
@@ -53,7 +71,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        lastUploaderID,
 				formAction: constants.ActionRequestChanges,
-				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID},
+				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID, State: constants.SubmissionStateInTesting},
 			},
 			wantErr: true,
 		},
@@ -62,7 +80,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        lastUploaderID,
 				formAction: constants.ActionVerify,
-				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID},
+				submission: &types.ExtendedSubmission{LastUploaderID: lastUploaderID, State: constants.SubmissionStateApproved},
 			},
 			wantErr: true,
 		},
@@ -71,7 +89,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignTesting,
-				submission: &types.ExtendedSubmission{AssignedTestingUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{AssignedTestingUserIDs: []int64{commenterID}, State: constants.SubmissionStateNew},
 			},
 			wantErr: true,
 		},
@@ -80,7 +98,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionUnassignTesting,
-				submission: &types.ExtendedSubmission{},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateInTesting},
 			},
 			wantErr: true,
 		},
@@ -89,7 +107,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignVerification,
-				submission: &types.ExtendedSubmission{AssignedVerificationUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{AssignedVerificationUserIDs: []int64{commenterID}, State: constants.SubmissionStateApproved},
 			},
 			wantErr: true,
 		},
@@ -98,7 +116,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionUnassignVerification,
-				submission: &types.ExtendedSubmission{},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateApproved},
 			},
 			wantErr: true,
 		},
@@ -107,7 +125,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionApprove,
-				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{commenterID}, State: constants.SubmissionStateInTesting},
 			},
 			wantErr: true,
 		},
@@ -116,7 +134,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionRequestChanges,
-				submission: &types.ExtendedSubmission{RequestedChangesUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{RequestedChangesUserIDs: []int64{commenterID}, State: constants.SubmissionStateChangesRequested},
 			},
 			wantErr: false,
 		},
@@ -125,7 +143,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignTesting,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionMarkAdded}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateAdded},
 			},
 			wantErr: true,
 		},
@@ -134,7 +152,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionUnassignTesting,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionMarkAdded}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateAdded},
 			},
 			wantErr: true,
 		},
@@ -143,7 +161,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignVerification,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionMarkAdded}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateAdded},
 			},
 			wantErr: true,
 		},
@@ -152,7 +170,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionUnassignVerification,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionMarkAdded}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateAdded},
 			},
 			wantErr: true,
 		},
@@ -161,7 +179,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionRequestChanges,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionMarkAdded}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateAdded},
 			},
 			wantErr: true,
 		},
@@ -170,7 +188,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionApprove,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionMarkAdded}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateAdded},
 			},
 			wantErr: true,
 		},
@@ -179,7 +197,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionVerify,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionMarkAdded}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateAdded},
 			},
 			wantErr: true,
 		},
@@ -188,7 +206,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignTesting,
-				submission: &types.ExtendedSubmission{AssignedVerificationUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{AssignedVerificationUserIDs: []int64{commenterID}, State: constants.SubmissionStateNew},
 			},
 			wantErr: true,
 		},
@@ -197,7 +215,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignVerification,
-				submission: &types.ExtendedSubmission{AssignedTestingUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{AssignedTestingUserIDs: []int64{commenterID}, State: constants.SubmissionStateApproved},
 			},
 			wantErr: true,
 		},
@@ -206,7 +224,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignVerification,
-				submission: &types.ExtendedSubmission{AssignedTestingUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{AssignedTestingUserIDs: []int64{commenterID}, State: constants.SubmissionStateApproved},
 			},
 			wantErr: true,
 		},
@@ -215,7 +233,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionApprove,
-				submission: &types.ExtendedSubmission{},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateInTesting},
 			},
 			wantErr: true,
 		},
@@ -224,7 +242,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionApprove,
-				submission: &types.ExtendedSubmission{},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateInTesting},
 			},
 			wantErr: true,
 		},
@@ -233,7 +251,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionMarkAdded,
-				submission: &types.ExtendedSubmission{},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateApproved},
 			},
 			wantErr: true,
 		},
@@ -242,7 +260,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignTesting,
-				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{commenterID}, State: constants.SubmissionStateNew},
 			},
 			wantErr: true,
 		},
@@ -251,7 +269,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignTesting,
-				submission: &types.ExtendedSubmission{VerifiedUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{VerifiedUserIDs: []int64{commenterID}, State: constants.SubmissionStateNew},
 			},
 			wantErr: true,
 		},
@@ -260,7 +278,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignTesting,
-				submission: &types.ExtendedSubmission{VerifiedUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{VerifiedUserIDs: []int64{commenterID}, State: constants.SubmissionStateNew},
 			},
 			wantErr: true,
 		},
@@ -269,7 +287,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionAssignVerification,
-				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{commenterID}},
+				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{commenterID}, State: constants.SubmissionStateApproved},
 			},
 			wantErr: true,
 		},
@@ -278,7 +296,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionReject,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionMarkAdded}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateAdded},
 			},
 			wantErr: true,
 		},
@@ -287,7 +305,7 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionReject,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionReject}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateRejected},
 			},
 			wantErr: true,
 		},
@@ -296,7 +314,43 @@ func Test_isActionValidForSubmission(t *testing.T) {
 			args: args{
 				uid:        commenterID,
 				formAction: constants.ActionUpload,
-				submission: &types.ExtendedSubmission{DistinctActions: []string{constants.ActionReject}},
+				submission: &types.ExtendedSubmission{State: constants.SubmissionStateRejected},
+			},
+			wantErr: true,
+		},
+		{
+			name: "user cannot assign for verification without approval quorum",
+			args: args{
+				uid:        commenterID,
+				formAction: constants.ActionAssignVerification,
+				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{3}, RequiredApprovals: 2, State: constants.SubmissionStateApproved},
+			},
+			wantErr: true,
+		},
+		{
+			name: "user can assign for verification with approval quorum",
+			args: args{
+				uid:        commenterID,
+				formAction: constants.ActionAssignVerification,
+				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{3, 4}, RequiredApprovals: 2, State: constants.SubmissionStateApproved},
+			},
+			wantErr: false,
+		},
+		{
+			name: "user cannot verify without approval quorum",
+			args: args{
+				uid:        commenterID,
+				formAction: constants.ActionVerify,
+				submission: &types.ExtendedSubmission{AssignedVerificationUserIDs: []int64{commenterID}, ApprovedUserIDs: []int64{3}, RequiredApprovals: 2, State: constants.SubmissionStateApproved},
+			},
+			wantErr: true,
+		},
+		{
+			name: "user cannot mark as added without approval quorum",
+			args: args{
+				uid:        commenterID,
+				formAction: constants.ActionMarkAdded,
+				submission: &types.ExtendedSubmission{ApprovedUserIDs: []int64{3}, VerifiedUserIDs: []int64{4}, RequiredApprovals: 2, State: constants.SubmissionStateVerified},
 			},
 			wantErr: true,
 		},
@@ -309,3 +363,132 @@ func Test_isActionValidForSubmission(t *testing.T) {
 		})
 	}
 }
+
+func Test_isTransitionValidForSubmission(t *testing.T) {
+	tests := []struct {
+		name       string
+		formAction string
+		state      string
+		wantErr    bool
+	}{
+		{name: "new submission can be assigned for testing", formAction: constants.ActionAssignTesting, state: constants.SubmissionStateNew},
+		{name: "new submission cannot be assigned for verification", formAction: constants.ActionAssignVerification, state: constants.SubmissionStateNew, wantErr: true},
+		{name: "submission in testing cannot be verified", formAction: constants.ActionVerify, state: constants.SubmissionStateInTesting, wantErr: true},
+		{name: "approved submission can be verified", formAction: constants.ActionVerify, state: constants.SubmissionStateApproved},
+		{name: "approved submission cannot be marked as added", formAction: constants.ActionMarkAdded, state: constants.SubmissionStateApproved, wantErr: true},
+		{name: "verified submission can be marked as added", formAction: constants.ActionMarkAdded, state: constants.SubmissionStateVerified},
+		{name: "added submission can be commented on", formAction: constants.ActionComment, state: constants.SubmissionStateAdded},
+		{name: "rejected submission can be commented on", formAction: constants.ActionComment, state: constants.SubmissionStateRejected},
+		{name: "rejected submission cannot get a new version", formAction: constants.ActionUpload, state: constants.SubmissionStateRejected, wantErr: true},
+		{name: "rejected submission cannot be assigned", formAction: constants.ActionAssignTesting, state: constants.SubmissionStateRejected, wantErr: true},
+		{name: "rejected submission can be reopened", formAction: constants.ActionReopen, state: constants.SubmissionStateRejected},
+		{name: "submission in testing cannot be reopened", formAction: constants.ActionReopen, state: constants.SubmissionStateInTesting, wantErr: true},
+		{name: "added submission cannot be reopened", formAction: constants.ActionReopen, state: constants.SubmissionStateAdded, wantErr: true},
+		{name: "non-workflow actions are not checked", formAction: constants.ActionSystem, state: constants.SubmissionStateRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := isTransitionValidForSubmission(tt.formAction, &types.ExtendedSubmission{State: tt.state})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("isTransitionValidForSubmission() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_NextSubmissionState(t *testing.T) {
+	actions := []string{
+		constants.ActionUpload,
+		constants.ActionReject,
+		constants.ActionReopen,
+		constants.ActionAssignTesting,
+		constants.ActionRequestChanges,
+		constants.ActionUpload,
+		constants.ActionApprove,
+		constants.ActionUnassignTesting,
+		constants.ActionAssignVerification,
+		constants.ActionVerify,
+		constants.ActionUnassignVerification,
+		constants.ActionMarkAdded,
+	}
+	want := []string{
+		constants.SubmissionStateNew,
+		constants.SubmissionStateRejected,
+		constants.SubmissionStateNew,
+		constants.SubmissionStateInTesting,
+		constants.SubmissionStateChangesRequested,
+		constants.SubmissionStateInTesting,
+		constants.SubmissionStateApproved,
+		constants.SubmissionStateApproved,
+		constants.SubmissionStateApproved,
+		constants.SubmissionStateVerified,
+		constants.SubmissionStateVerified,
+		constants.SubmissionStateAdded,
+	}
+
+	state := constants.SubmissionStateNew
+	for i, action := range actions {
+		next, ok := constants.NextSubmissionState(state, action)
+		if !ok {
+			t.Fatalf("action '%s' is not allowed in state '%s'", action, state)
+		}
+		if next != want[i] {
+			t.Errorf("action '%s' in state '%s' leads to '%s', want '%s'", action, state, next, want[i])
+		}
+		state = next
+	}
+}
+
+func Test_NextSubmissionStateWithApprovals(t *testing.T) {
+	next, ok := constants.NextSubmissionStateWithApprovals(constants.SubmissionStateInTesting, constants.ActionApprove, 1, 2)
+	if !ok || next != constants.SubmissionStateInTesting {
+		t.Errorf("approval below quorum leads to '%s', want '%s'", next, constants.SubmissionStateInTesting)
+	}
+
+	next, ok = constants.NextSubmissionStateWithApprovals(constants.SubmissionStateInTesting, constants.ActionApprove, 2, 2)
+	if !ok || next != constants.SubmissionStateApproved {
+		t.Errorf("approval meeting quorum leads to '%s', want '%s'", next, constants.SubmissionStateApproved)
+	}
+
+	next, ok = constants.NextSubmissionStateWithApprovals(constants.SubmissionStateVerified, constants.ActionApprove, 1, 2)
+	if !ok || next != constants.SubmissionStateVerified {
+		t.Errorf("approval of verified submission leads to '%s', want '%s'", next, constants.SubmissionStateVerified)
+	}
+}
+
+func Test_isCommentEditable(t *testing.T) {
+	var authorID int64 = 1
+	oldMessage := "this has a tpyo"
+	newMessage := "this has no typo"
+	emptyMessage := (*string)(nil)
+
+	submission := &types.ExtendedSubmission{SubmissionID: 1, State: constants.SubmissionStateChangesRequested}
+	added := &types.ExtendedSubmission{SubmissionID: 1, State: constants.SubmissionStateAdded}
+
+	tests := []struct {
+		name       string
+		uid        int64
+		action     string
+		submission *types.ExtendedSubmission
+		message    *string
+		wantErr    bool
+	}{
+		{name: "author edits request changes", uid: authorID, action: constants.ActionRequestChanges, submission: submission, message: &newMessage},
+		{name: "author adds message to approval", uid: authorID, action: constants.ActionApprove, submission: submission, message: &newMessage},
+		{name: "someone else edits", uid: 2, action: constants.ActionComment, submission: submission, message: &newMessage, wantErr: true},
+		{name: "assignment cannot be edited", uid: authorID, action: constants.ActionAssignTesting, submission: submission, message: &newMessage, wantErr: true},
+		{name: "added submission is frozen", uid: authorID, action: constants.ActionComment, submission: added, message: &newMessage, wantErr: true},
+		{name: "mandatory message cannot be removed", uid: authorID, action: constants.ActionReject, submission: submission, message: emptyMessage, wantErr: true},
+		{name: "optional message can be removed", uid: authorID, action: constants.ActionVerify, submission: submission, message: emptyMessage},
+		{name: "unchanged message", uid: authorID, action: constants.ActionComment, submission: submission, message: &oldMessage, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := oldMessage
+			comment := &types.ExtendedComment{CommentID: 1, AuthorID: authorID, Action: tt.action, Message: &m}
+			if err := isCommentEditable(tt.uid, comment, tt.submission, tt.message); (err != nil) != tt.wantErr {
+				t.Errorf("isCommentEditable() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCronSchedule_invalid(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a * * * *",
+		"1-a * * * *",
+	}
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			_, err := parseCronSchedule(expr)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_cronSchedule_next(t *testing.T) {
+	// a wednesday
+	now := time.Date(2021, 6, 16, 10, 20, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{expr: "* * * * *", want: time.Date(2021, 6, 16, 10, 21, 0, 0, time.UTC)},
+		{expr: "30 4 * * *", want: time.Date(2021, 6, 17, 4, 30, 0, 0, time.UTC)},
+		{expr: "*/15 * * * *", want: time.Date(2021, 6, 16, 10, 30, 0, 0, time.UTC)},
+		{expr: "5/15 * * * *", want: time.Date(2021, 6, 16, 10, 35, 0, 0, time.UTC)},
+		{expr: "0 */6 * * *", want: time.Date(2021, 6, 16, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 9,18 * * *", want: time.Date(2021, 6, 16, 18, 0, 0, 0, time.UTC)},
+		{expr: "0 12 * * 1", want: time.Date(2021, 6, 21, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 12 * * 7", want: time.Date(2021, 6, 20, 12, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 * *", want: time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 1 1 *", want: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 29 2 *", want: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// either day field matches when both are restricted
+		{expr: "0 0 20 * 5", want: time.Date(2021, 6, 18, 0, 0, 0, 0, time.UTC)},
+		{expr: "0 0 30 2 *", want: time.Time{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := parseCronSchedule(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, c.next(now))
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// RunFilePurger periodically removes files which were deleted longer than the retention period ago
+func (s *SiteService) RunFilePurger(logger *logrus.Entry, ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	l := logger.WithField("serviceName", "filePurger")
+	defer l.Info("file purger stopped")
+
+	if s.fileRetention <= 0 {
+		l.Info("file retention is not set, deleted files are kept forever")
+		return
+	}
+
+	ticker := time.NewTicker(constants.FilePurgeInterval)
+	defer ticker.Stop()
+
+	pctx := context.WithValue(ctx, utils.CtxKeys.Log, l)
+
+	for {
+		purged, reclaimed, err := s.purgeDeletedFiles(pctx)
+		if err != nil && err != context.Canceled {
+			l.Error(err)
+		}
+		if purged > 0 {
+			l.WithField("purged", purged).WithField("reclaimed", utils.SizeToString(reclaimed)).Info("deleted files purged")
+		}
+
+		select {
+		case <-ctx.Done():
+			l.Info("context cancelled, stopping file purger")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeDeletedFiles purges a batch of files past their retention period, returns the number of purged files and reclaimed bytes
+func (s *SiteService) purgeDeletedFiles(ctx context.Context) (int, int64, error) {
+	deletedBefore := s.clock.Now().Add(-s.fileRetention)
+
+	files, err := func() ([]*types.PurgeableFile, error) {
+		dbs, err := s.dal.NewSession(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer dbs.Rollback()
+		return s.dal.GetPurgeableFiles(dbs, deletedBefore, constants.FilePurgeBatchSize)
+	}()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	purged := 0
+	var reclaimed int64
+
+	for _, f := range files {
+		if err := ctx.Err(); err != nil {
+			return purged, reclaimed, err
+		}
+		fp, err := s.purgeFile(ctx, f, deletedBefore)
+		if err != nil {
+			return purged, reclaimed, err
+		}
+		if fp == nil {
+			continue
+		}
+		purged++
+		reclaimed += fp.ReclaimedBytes
+	}
+
+	return purged, reclaimed, nil
+}
+
+// purgeFile removes a deleted file together with its curation images and records the purge.
+// The file is locked and checked again before the purge is recorded, so that a file restored in the meantime stays on the disk.
+// The files are removed only after the record is committed, the ones which fail to be removed are logged and left on the disk.
+// Returns nil if the file is not purgeable anymore.
+func (s *SiteService) purgeFile(ctx context.Context, f *types.PurgeableFile, deletedBefore time.Time) (*types.FilePurge, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.LockPurgeableFile(dbs, f.Type, f.ID, deletedBefore); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	filePath, err := purgeableFilePath(f, s.submissionsDir, s.fixesDir, s.flashfreezeDir)
+	if err != nil {
+		return nil, err
+	}
+	filePaths := []string{filePath}
+
+	if f.Type == constants.PurgedFileSubmission {
+		images, err := s.dal.GetCurationImagesBySubmissionFileID(dbs, f.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, ci := range images {
+			filePaths = append(filePaths, fmt.Sprintf("%s/%s", s.submissionImagesDir, ci.Filename))
+		}
+	}
+
+	fp := &types.FilePurge{
+		FileType:   f.Type,
+		FileID:     f.ID,
+		Filename:   f.CurrentFilename,
+		ImageCount: int64(len(filePaths) - 1),
+		PurgedAt:   s.clock.Now(),
+	}
+
+	for _, p := range filePaths {
+		size, err := purgedFileSize(p)
+		if err != nil {
+			return nil, err
+		}
+		fp.ReclaimedBytes += size
+	}
+
+	if err := s.dal.StoreFilePurge(dbs, fp); err != nil {
+		return nil, err
+	}
+
+	if err := dbs.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range filePaths {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			utils.LogCtx(ctx).WithField("filePath", p).Error(err)
+		}
+	}
+
+	return fp, nil
+}
+
+// purgeableFilePath returns the location of the file on the disk
+func purgeableFilePath(f *types.PurgeableFile, submissionsDir, fixesDir, flashfreezeDir string) (string, error) {
+	switch f.Type {
+	case constants.PurgedFileSubmission:
+		return fmt.Sprintf("%s/%s", submissionsDir, f.CurrentFilename), nil
+	case constants.PurgedFileFixes:
+		return fmt.Sprintf("%s/%s", fixesDir, f.CurrentFilename), nil
+	case constants.PurgedFileFlashfreeze:
+		return fmt.Sprintf("%s/%s", flashfreezeDir, f.CurrentFilename), nil
+	}
+	return "", fmt.Errorf("unknown purgeable file type '%s'", f.Type)
+}
+
+// purgedFileSize returns the size of a file about to be purged, a file that is already gone reclaims nothing
+func purgedFileSize(filePath string) (int64, error) {
+	fi, err := os.Stat(filePath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return fi.Size(), nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_purgeableFilePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileType string
+		want     string
+		wantErr  bool
+	}{
+		{name: "submission file", fileType: constants.PurgedFileSubmission, want: "/submissions/foo.7z"},
+		{name: "fixes file", fileType: constants.PurgedFileFixes, want: "/fixes/foo.7z"},
+		{name: "flashfreeze file", fileType: constants.PurgedFileFlashfreeze, want: "/flashfreeze/foo.7z"},
+		{name: "unknown type", fileType: "comment", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &types.PurgeableFile{Type: tt.fileType, ID: 1, CurrentFilename: "foo.7z"}
+			got, err := purgeableFilePath(f, "/submissions", "/fixes", "/flashfreeze")
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_purgedFileSize(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "foo.7z")
+	require.NoError(t, ioutil.WriteFile(filePath, make([]byte, 1234), 0644))
+
+	size, err := purgedFileSize(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1234), size)
+	assert.True(t, utils.FileExists(filePath), "the file is removed only after the purge is recorded")
+
+	// already gone, nothing reclaimed
+	size, err = purgedFileSize(filepath.Join(dir, "bar.7z"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+}
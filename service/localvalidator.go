@@ -0,0 +1,453 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/bodgit/sevenzip"
+	"gopkg.in/yaml.v3"
+)
+
+// localCurationValidator is a native implementation of the Validator, it only checks curation metadata and does not look into the content.
+// It's used when the validator server is not available.
+type localCurationValidator struct {
+	tagsFilePath string
+	tags         []types.Tag
+	tagsMutex    sync.RWMutex
+}
+
+func NewLocalValidator(tagsFilePath string) *localCurationValidator {
+	return &localCurationValidator{
+		tagsFilePath: tagsFilePath,
+	}
+}
+
+// archiveFile is a single file inside a curation archive, independent of the archive format
+type archiveFile struct {
+	name string
+	size int64
+	open func() (io.ReadCloser, error)
+}
+
+func (c *localCurationValidator) ProvideArchiveForValidation(filePath string) (*types.ValidatorResponse, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	vr, err := c.validateArchive(f, fi.Size(), filepath.Base(filePath))
+	if err != nil {
+		return nil, err
+	}
+	vr.Path = filePath
+
+	return vr, nil
+}
+
+func (c *localCurationValidator) Validate(_ context.Context, file io.Reader, filename string) (*types.ValidatorResponse, error) {
+	b, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.validateArchive(bytes.NewReader(b), int64(len(b)), filename)
+}
+
+// GetTags returns the cached tag list, which is refreshed whenever the validator server provides one
+func (c *localCurationValidator) GetTags(_ context.Context) ([]types.Tag, error) {
+	c.tagsMutex.RLock()
+	tags := c.tags
+	c.tagsMutex.RUnlock()
+
+	if tags != nil {
+		return tags, nil
+	}
+
+	b, err := ioutil.ReadFile(c.tagsFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("tag list is not available: %w", err)
+	}
+
+	if err := json.Unmarshal(b, &tags); err != nil {
+		return nil, err
+	}
+
+	c.tagsMutex.Lock()
+	defer c.tagsMutex.Unlock()
+	c.tags = tags
+
+	return tags, nil
+}
+
+// cacheTags stores the tag list in memory and in the tags file, so that it survives the restart of the application
+func (c *localCurationValidator) cacheTags(tags []types.Tag) error {
+	c.tagsMutex.Lock()
+	defer c.tagsMutex.Unlock()
+
+	// the tag list rarely changes, don't rewrite the file every time
+	if reflect.DeepEqual(c.tags, tags) {
+		return nil
+	}
+
+	c.tags = tags
+
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(c.tagsFilePath, b, 0644)
+}
+
+// listArchiveFiles lists files of a zip or 7z archive, directories are left out
+func listArchiveFiles(r io.ReaderAt, size int64, filename string) ([]*archiveFile, error) {
+	var files []*archiveFile
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".zip":
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return nil, err
+		}
+		for _, zf := range zr.File {
+			if zf.FileInfo().IsDir() {
+				continue
+			}
+			files = append(files, &archiveFile{name: normalizeArchivePath(zf.Name), size: int64(zf.UncompressedSize64), open: zf.Open})
+		}
+	case ".7z":
+		szr, err := sevenzip.NewReader(r, size)
+		if err != nil {
+			return nil, err
+		}
+		for _, szf := range szr.File {
+			if szf.FileInfo().IsDir() {
+				continue
+			}
+			files = append(files, &archiveFile{name: normalizeArchivePath(szf.Name), size: szf.FileInfo().Size(), open: szf.Open})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported archive type '%s'", filepath.Ext(filename))
+	}
+
+	return files, nil
+}
+
+func (c *localCurationValidator) validateArchive(r io.ReaderAt, size int64, filename string) (*types.ValidatorResponse, error) {
+	files, err := listArchiveFiles(r, size, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	vr := &types.ValidatorResponse{
+		Filename:         filename,
+		CurationErrors:   make([]string, 0),
+		CurationWarnings: make([]string, 0),
+		Images:           make([]types.ValidatorResponseImage, 0),
+		IsDegraded:       true,
+	}
+
+	metaFile, root := findCurationMetaFile(files)
+	if metaFile == nil {
+		vr.CurationErrors = append(vr.CurationErrors, "Meta file is missing, expected meta.yaml or meta.txt in the root of the curation.")
+		return vr, nil
+	}
+
+	metaBytes, err := readArchiveFile(metaFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta *types.CurationMeta
+	if strings.HasSuffix(strings.ToLower(metaFile.name), ".txt") {
+		meta = parseCurationMetaTXT(metaBytes)
+	} else {
+		meta, err = parseCurationMetaYAML(metaBytes)
+		if err != nil {
+			vr.CurationErrors = append(vr.CurationErrors, fmt.Sprintf("Failed to parse the meta file: %s", err.Error()))
+			return vr, nil
+		}
+	}
+	vr.Meta = *meta
+	vr.IsExtreme = strings.EqualFold(utils.Unpointify(meta.Extreme), "Yes")
+
+	images := []struct {
+		imageType string
+		name      string
+		missing   string
+	}{
+		{"logo", "logo.png", "Logo file is missing."},
+		{"screenshot", "ss.png", "Screenshot file is missing."},
+	}
+
+	for _, image := range images {
+		f := findArchiveFile(files, root+image.name)
+		if f == nil {
+			vr.CurationErrors = append(vr.CurationErrors, image.missing)
+			continue
+		}
+		b, err := readArchiveFile(f)
+		if err != nil {
+			return nil, err
+		}
+		vr.Images = append(vr.Images, types.ValidatorResponseImage{Type: image.imageType, Data: base64.StdEncoding.EncodeToString(b)})
+	}
+
+	tags, err := c.GetTags(context.Background())
+	if err != nil {
+		tags = nil
+	}
+
+	errs, warns := checkCurationMeta(meta, tags)
+	vr.CurationErrors = append(vr.CurationErrors, errs...)
+	vr.CurationWarnings = append(vr.CurationWarnings, warns...)
+
+	return vr, nil
+}
+
+// findCurationMetaFile finds the meta file in the root of the archive or in the curation folder, and returns the path prefix of the curation
+func findCurationMetaFile(files []*archiveFile) (*archiveFile, string) {
+	metaNames := map[string]bool{"meta.yaml": true, "meta.yml": true, "meta.txt": true}
+
+	candidates := make([]*archiveFile, 0)
+	for _, f := range files {
+		if strings.Count(f.name, "/") > 1 || !metaNames[strings.ToLower(path.Base(f.name))] {
+			continue
+		}
+		candidates = append(candidates, f)
+	}
+
+	if len(candidates) == 0 {
+		return nil, ""
+	}
+
+	// prefer the shallowest and yaml over txt
+	sort.SliceStable(candidates, func(i, j int) bool {
+		di, dj := strings.Count(candidates[i].name, "/"), strings.Count(candidates[j].name, "/")
+		if di != dj {
+			return di < dj
+		}
+		return !strings.HasSuffix(strings.ToLower(candidates[i].name), ".txt") && strings.HasSuffix(strings.ToLower(candidates[j].name), ".txt")
+	})
+
+	metaFile := candidates[0]
+	root := ""
+	if dir := path.Dir(metaFile.name); dir != "." {
+		root = dir + "/"
+	}
+
+	return metaFile, root
+}
+
+// normalizeArchivePath converts windows path separators and removes the leading slash
+func normalizeArchivePath(name string) string {
+	return strings.TrimPrefix(strings.ReplaceAll(name, "\\", "/"), "/")
+}
+
+func findArchiveFile(files []*archiveFile, name string) *archiveFile {
+	for _, f := range files {
+		if strings.EqualFold(f.name, name) {
+			return f
+		}
+	}
+	return nil
+}
+
+func readArchiveFile(f *archiveFile) ([]byte, error) {
+	rc, err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+// curationMetaFields maps meta file keys to curation meta fields, including the legacy names
+func curationMetaFields(meta *types.CurationMeta) map[string]**string {
+	return map[string]**string{
+		"application path":     &meta.ApplicationPath,
+		"developer":            &meta.Developer,
+		"extreme":              &meta.Extreme,
+		"game notes":           &meta.GameNotes,
+		"notes":                &meta.GameNotes,
+		"languages":            &meta.Languages,
+		"launch command":       &meta.LaunchCommand,
+		"original description": &meta.OriginalDescription,
+		"play mode":            &meta.PlayMode,
+		"platform":             &meta.Platform,
+		"publisher":            &meta.Publisher,
+		"release date":         &meta.ReleaseDate,
+		"series":               &meta.Series,
+		"source":               &meta.Source,
+		"status":               &meta.Status,
+		"tags":                 &meta.Tags,
+		"genre":                &meta.Tags,
+		"genres":               &meta.Tags,
+		"tag categories":       &meta.TagCategories,
+		"title":                &meta.Title,
+		"alternate titles":     &meta.AlternateTitles,
+		"library":              &meta.Library,
+		"version":              &meta.Version,
+		"curation notes":       &meta.CurationNotes,
+		"author notes":         &meta.CurationNotes,
+		"mount parameters":     &meta.MountParameters,
+	}
+}
+
+// parseCurationMetaYAML parses meta.yaml, lists are joined with semicolons and booleans are converted to Yes/No
+func parseCurationMetaYAML(b []byte) (*types.CurationMeta, error) {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+
+	meta := &types.CurationMeta{}
+	fields := curationMetaFields(meta)
+
+	for key, value := range raw {
+		field, ok := fields[strings.ToLower(strings.TrimSpace(key))]
+		if !ok || value == nil {
+			continue
+		}
+
+		var s string
+		switch v := value.(type) {
+		case string:
+			s = v
+		case bool:
+			s = "No"
+			if v {
+				s = "Yes"
+			}
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			s = strings.Join(items, "; ")
+		case map[string]interface{}:
+			continue
+		default:
+			s = fmt.Sprint(v)
+		}
+
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		*field = &s
+	}
+
+	return meta, nil
+}
+
+// parseCurationMetaTXT parses the legacy meta.txt format, lines not starting with a known key belong to the previous key
+func parseCurationMetaTXT(b []byte) *types.CurationMeta {
+	meta := &types.CurationMeta{}
+	fields := curationMetaFields(meta)
+
+	var current **string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if i := strings.Index(line, ":"); i > 0 {
+			if field, ok := fields[strings.ToLower(strings.TrimSpace(line[:i]))]; ok {
+				current = field
+				value := strings.TrimSpace(line[i+1:])
+				if value == "" {
+					*current = nil
+				} else {
+					*current = &value
+				}
+				continue
+			}
+		}
+
+		if current == nil || strings.TrimSpace(line) == "" {
+			continue
+		}
+		value := strings.TrimSpace(line)
+		if *current != nil {
+			value = **current + "\n" + value
+		}
+		*current = &value
+	}
+
+	return meta
+}
+
+// checkCurationMeta checks required fields and tags of curation meta, tags are not checked if the tag list is nil
+func checkCurationMeta(meta *types.CurationMeta, tags []types.Tag) ([]string, []string) {
+	errs := make([]string, 0)
+	warns := make([]string, 0)
+
+	required := []struct {
+		name  string
+		value *string
+	}{
+		{"Title", meta.Title},
+		{"Tags", meta.Tags},
+		{"Source", meta.Source},
+		{"Platform", meta.Platform},
+		{"Application Path", meta.ApplicationPath},
+		{"Launch Command", meta.LaunchCommand},
+	}
+
+	for _, r := range required {
+		if strings.TrimSpace(utils.Unpointify(r.value)) == "" {
+			errs = append(errs, fmt.Sprintf("%s is missing.", r.name))
+		}
+	}
+
+	if library := utils.Unpointify(meta.Library); library != "" && library != "arcade" && library != "theatre" {
+		errs = append(errs, fmt.Sprintf("Library '%s' is not valid, use 'arcade' or 'theatre'.", library))
+	}
+
+	if extreme := utils.Unpointify(meta.Extreme); extreme != "" && !strings.EqualFold(extreme, "Yes") && !strings.EqualFold(extreme, "No") {
+		errs = append(errs, fmt.Sprintf("Extreme '%s' is not valid, use 'Yes' or 'No'.", extreme))
+	}
+
+	if tags == nil {
+		warns = append(warns, "Tags could not be verified, the tag list is not available.")
+		return errs, warns
+	}
+
+	knownTags := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		knownTags[strings.ToLower(tag.Name)] = true
+	}
+
+	for _, tag := range splitMetaList(meta.Tags) {
+		if !knownTags[strings.ToLower(tag)] {
+			errs = append(errs, fmt.Sprintf("Tag '%s' is not a known tag, please verify (did you write it correctly?).", tag))
+		}
+	}
+
+	return errs, warns
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseCurationMetaYAML(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    *types.CurationMeta
+		wantErr bool
+	}{
+		{
+			name: "strings",
+			yaml: "Title: Foo\nLaunch Command: http://example.com/foo.swf\nTags: Action; Puzzle\n",
+			want: &types.CurationMeta{
+				Title:         utils.StrPtr("Foo"),
+				LaunchCommand: utils.StrPtr("http://example.com/foo.swf"),
+				Tags:          utils.StrPtr("Action; Puzzle"),
+			},
+		},
+		{
+			name: "lists, booleans and legacy keys",
+			yaml: "title: Foo\nGenres:\n  - Action\n  - Puzzle\nExtreme: false\nAdditional Applications:\n  Extras: foo\nRelease Date: 2005\n",
+			want: &types.CurationMeta{
+				Title:       utils.StrPtr("Foo"),
+				Tags:        utils.StrPtr("Action; Puzzle"),
+				Extreme:     utils.StrPtr("No"),
+				ReleaseDate: utils.StrPtr("2005"),
+			},
+		},
+		{
+			name: "empty values are ignored",
+			yaml: "Title: Foo\nSeries: \nDeveloper: ''\n",
+			want: &types.CurationMeta{
+				Title: utils.StrPtr("Foo"),
+			},
+		},
+		{
+			name:    "invalid yaml",
+			yaml:    "Title: [Foo\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCurationMetaYAML([]byte(tt.yaml))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseCurationMetaYAML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCurationMetaYAML() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseCurationMetaTXT(t *testing.T) {
+	tests := []struct {
+		name string
+		txt  string
+		want *types.CurationMeta
+	}{
+		{
+			name: "single line values",
+			txt:  "Title: Foo\r\nGenre: Action; Puzzle\r\nExtreme: No\r\n",
+			want: &types.CurationMeta{
+				Title:   utils.StrPtr("Foo"),
+				Tags:    utils.StrPtr("Action; Puzzle"),
+				Extreme: utils.StrPtr("No"),
+			},
+		},
+		{
+			name: "multiline values",
+			txt:  "Title: Foo\nOriginal Description:\nfirst line\nsecond: line\n\nNotes: bar\n",
+			want: &types.CurationMeta{
+				Title:               utils.StrPtr("Foo"),
+				OriginalDescription: utils.StrPtr("first line\nsecond: line"),
+				GameNotes:           utils.StrPtr("bar"),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCurationMetaTXT([]byte(tt.txt)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCurationMetaTXT() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkCurationMeta(t *testing.T) {
+	validMeta := func() *types.CurationMeta {
+		return &types.CurationMeta{
+			Title:           utils.StrPtr("Foo"),
+			Tags:            utils.StrPtr("Action; puzzle"),
+			Source:          utils.StrPtr("http://example.com"),
+			Platform:        utils.StrPtr("Flash"),
+			ApplicationPath: utils.StrPtr("FPSoftware\\Flash\\flashplayer_32_sa.exe"),
+			LaunchCommand:   utils.StrPtr("http://example.com/foo.swf"),
+			Library:         utils.StrPtr("arcade"),
+			Extreme:         utils.StrPtr("No"),
+		}
+	}
+	tags := []types.Tag{{Name: "Action"}, {Name: "Puzzle"}}
+
+	tests := []struct {
+		name      string
+		meta      func() *types.CurationMeta
+		tags      []types.Tag
+		wantErrs  []string
+		wantWarns []string
+	}{
+		{
+			name:      "valid",
+			meta:      validMeta,
+			tags:      tags,
+			wantErrs:  []string{},
+			wantWarns: []string{},
+		},
+		{
+			name: "missing required fields",
+			meta: func() *types.CurationMeta {
+				m := validMeta()
+				m.Title = nil
+				m.LaunchCommand = utils.StrPtr("  ")
+				return m
+			},
+			tags:      tags,
+			wantErrs:  []string{"Title is missing.", "Launch Command is missing."},
+			wantWarns: []string{},
+		},
+		{
+			name: "unknown tag and invalid library",
+			meta: func() *types.CurationMeta {
+				m := validMeta()
+				m.Tags = utils.StrPtr("Action; Puzzel")
+				m.Library = utils.StrPtr("games")
+				return m
+			},
+			tags: tags,
+			wantErrs: []string{
+				"Library 'games' is not valid, use 'arcade' or 'theatre'.",
+				"Tag 'Puzzel' is not a known tag, please verify (did you write it correctly?).",
+			},
+			wantWarns: []string{},
+		},
+		{
+			name:      "tag list not available",
+			meta:      validMeta,
+			tags:      nil,
+			wantErrs:  []string{},
+			wantWarns: []string{"Tags could not be verified, the tag list is not available."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErrs, gotWarns := checkCurationMeta(tt.meta(), tt.tags)
+			assert.Equal(t, tt.wantErrs, gotErrs)
+			assert.Equal(t, tt.wantWarns, gotWarns)
+		})
+	}
+}
+
+func Test_localCurationValidator_Validate(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{
+		"Foo/meta.yaml":       "Title: Foo\nTags: Action\nSource: http://example.com\nPlatform: Flash\nApplication Path: flash.exe\nLaunch Command: http://example.com/foo.swf\nExtreme: Yes\n",
+		"Foo/logo.png":        "logo",
+		"Foo/content/foo.swf": "swf",
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+
+	v := NewLocalValidator("")
+	v.tags = []types.Tag{{Name: "Action"}}
+
+	vr, err := v.Validate(context.Background(), &buf, "foo.zip")
+	require.NoError(t, err)
+
+	assert.True(t, vr.IsDegraded)
+	assert.True(t, vr.IsExtreme)
+	assert.Equal(t, "Foo", *vr.Meta.Title)
+	assert.Equal(t, []string{"Screenshot file is missing."}, vr.CurationErrors)
+	assert.Equal(t, []types.ValidatorResponseImage{{Type: "logo", Data: "bG9nbw=="}}, vr.Images)
+}
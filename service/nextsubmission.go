@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+// nextSubmissionFilter finds the submissions waiting for a tester, oldest first
+func nextSubmissionFilter() *types.SubmissionsFilter {
+	unassigned := "unassigned"
+	yes := "yes"
+	uploaded := "uploaded"
+	asc := "asc"
+	var resultsPerPage int64 = 100
+	return &types.SubmissionsFilter{
+		ResultsPerPage:        &resultsPerPage,
+		BotActions:            []string{constants.ActionApprove},
+		AssignedStatusTesting: &unassigned,
+		States:                []string{constants.SubmissionStateNew, constants.SubmissionStateInTesting},
+		LastUploaderNotMe:     &yes,
+		OrderBy:               &uploaded,
+		AscDesc:               &asc,
+		ExcludeLegacy:         true,
+	}
+}
+
+// pickNextSubmission returns the first candidate the user can assign for testing, or nil if there is none
+func pickNextSubmission(uid int64, candidates []*types.ExtendedSubmission) *types.ExtendedSubmission {
+	for _, candidate := range candidates {
+		if isActionValidForSubmission(uid, constants.ActionAssignTesting, candidate) == nil {
+			return candidate
+		}
+	}
+	return nil
+}
+
+// lockTestingWorkload returns the number of unfinished submissions the user is assigned to test and the limit given by the user's roles, zero if there is none.
+// The user is locked first, so that concurrent assignments of the same user wait for each other and cannot exceed the limit,
+// which is why it has to be called before any other read of the session.
+func (s *SiteService) lockTestingWorkload(ctx context.Context, dbs database.DBSession, uid int64) (int64, int64, error) {
+	if err := s.dal.LockUser(dbs, uid); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, 0, dberr(err)
+	}
+
+	roles, err := s.dal.GetDiscordUserRoles(dbs, uid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, 0, dberr(err)
+	}
+
+	workloadCap := s.workloadCaps.For(roles)
+	if workloadCap == 0 {
+		return 0, 0, nil
+	}
+
+	assigned, err := s.dal.CountActiveTestingAssignments(dbs, uid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, 0, dberr(err)
+	}
+
+	return assigned, workloadCap, nil
+}
+
+// workloadCapReachedError is returned when the user cannot be assigned to test another submission
+func workloadCapReachedError(assigned, workloadCap int64) error {
+	return perr(fmt.Sprintf("you are already assigned to test %d submissions and your limit is %d, please finish or unassign some of them first", assigned, workloadCap), http.StatusConflict)
+}
+
+// AssignNextSubmission assigns the oldest submission waiting for a tester to the user and returns its ID
+func (s *SiteService) AssignNextSubmission(ctx context.Context, uid int64) (int64, error) {
+	// one pick at a time, so that testers asking at the same time don't get the same submission
+	s.nextSubmissionMutex.Lock()
+	defer s.nextSubmissionMutex.Unlock()
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	assigned, workloadCap, err := s.lockTestingWorkload(ctx, dbs, uid)
+	if err != nil {
+		return 0, err
+	}
+	if workloadCap > 0 && assigned >= workloadCap {
+		return 0, workloadCapReachedError(assigned, workloadCap)
+	}
+
+	// the filter cannot express every condition of the assignment, so the pages are checked until an eligible submission shows up
+	var submission *types.ExtendedSubmission
+	filter := nextSubmissionFilter()
+	for page := int64(1); submission == nil; page++ {
+		filter.Page = &page
+		candidates, _, err := s.dal.SearchSubmissions(dbs, filter)
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return 0, dberr(err)
+		}
+		if len(candidates) == 0 {
+			return 0, perr("there are no submissions waiting for a tester right now", http.StatusNotFound)
+		}
+		submission = pickNextSubmission(uid, candidates)
+	}
+	sid := submission.SubmissionID
+
+	subscribed, err := s.dal.IsUserSubscribedToSubmission(dbs, uid, sid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, dberr(err)
+	}
+	if !subscribed {
+		if err := s.dal.SubscribeUserToSubmission(dbs, uid, sid); err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return 0, dberr(err)
+		}
+	}
+
+	c := &types.Comment{
+		AuthorID:     uid,
+		SubmissionID: sid,
+		Action:       constants.ActionAssignTesting,
+		CreatedAt:    s.clock.Now(),
+	}
+
+	if err := s.dal.StoreComment(dbs, c); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, dberr(err)
+	}
+
+	if err := s.createNotification(dbs, uid, sid, constants.ActionAssignTesting, nil); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, dberr(err)
+	}
+
+	if err := s.dal.UpdateSubmissionCacheTable(dbs, sid); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, dberr(err)
+	}
+
+	utils.LogCtx(ctx).WithField("sid", sid).Debug("next submission assigned")
+
+	s.announceNotification()
+
+	return sid, nil
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_pickNextSubmission(t *testing.T) {
+	var uid int64 = 1
+
+	ownUpload := &types.ExtendedSubmission{SubmissionID: 1, LastUploaderID: uid, State: constants.SubmissionStateNew}
+	approvedByMe := &types.ExtendedSubmission{SubmissionID: 2, LastUploaderID: 2, State: constants.SubmissionStateInTesting, ApprovedUserIDs: []int64{uid}}
+	available := &types.ExtendedSubmission{SubmissionID: 3, LastUploaderID: 2, State: constants.SubmissionStateNew}
+	newer := &types.ExtendedSubmission{SubmissionID: 4, LastUploaderID: 2, State: constants.SubmissionStateNew}
+
+	tests := []struct {
+		name       string
+		candidates []*types.ExtendedSubmission
+		want       *types.ExtendedSubmission
+	}{
+		{name: "no candidates", candidates: nil, want: nil},
+		{name: "oldest candidate", candidates: []*types.ExtendedSubmission{available, newer}, want: available},
+		{name: "skip own upload", candidates: []*types.ExtendedSubmission{ownUpload, available}, want: available},
+		{name: "skip already approved", candidates: []*types.ExtendedSubmission{approvedByMe, newer}, want: newer},
+		{name: "nothing eligible", candidates: []*types.ExtendedSubmission{ownUpload, approvedByMe}, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pickNextSubmission(uid, tt.candidates))
+		})
+	}
+}
+
+func Test_WorkloadCaps_For(t *testing.T) {
+	wc := types.WorkloadCaps{Default: 5, ByRole: map[string]int64{"Tester": 10, "Curator": 3}}
+
+	assert.Equal(t, int64(5), wc.For(nil))
+	assert.Equal(t, int64(5), wc.For([]string{"Member"}))
+	assert.Equal(t, int64(3), wc.For([]string{"Curator"}), "role override lowers the default")
+	assert.Equal(t, int64(10), wc.For([]string{"Curator", "Tester"}))
+
+	wc.ByRole["Moderator"] = 0
+	assert.Equal(t, int64(0), wc.For([]string{"Tester", "Moderator"}), "zero override is unlimited")
+
+	unlimited := types.WorkloadCaps{ByRole: map[string]int64{"Trial Tester": 2}}
+	assert.Equal(t, int64(0), unlimited.For([]string{"Tester"}), "zero default is unlimited")
+	assert.Equal(t, int64(2), unlimited.For([]string{"Trial Tester"}), "role override applies instead of zero default")
+}
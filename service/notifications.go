@@ -11,8 +11,8 @@ import (
 	"time"
 )
 
-// createNotification formats and stores notification
-func (s *SiteService) createNotification(dbs database.DBSession, authorID, sid int64, action string) error {
+// createNotification formats and stores notification, users mentioned in the comment are notified even if they are not subscribed
+func (s *SiteService) createNotification(dbs database.DBSession, authorID, sid int64, action string, mentionedUserIDs []int64) error {
 	validAction := false
 	for _, a := range constants.GetActionsWithNotification() {
 		if action == a {
@@ -20,17 +20,29 @@ func (s *SiteService) createNotification(dbs database.DBSession, authorID, sid i
 			break
 		}
 	}
-	if !validAction {
-		return nil
+
+	mentionUserIDs := make([]int64, 0)
+	if validAction {
+		subscribedUserIDs, err := s.dal.GetUsersForNotification(dbs, authorID, sid, action)
+		if err != nil {
+			utils.LogCtx(dbs.Ctx()).Error(err)
+			return err
+		}
+		for _, userID := range subscribedUserIDs {
+			if !uidIn(userID, mentionedUserIDs) {
+				mentionUserIDs = append(mentionUserIDs, userID)
+			}
+		}
 	}
 
-	mentionUserIDs, err := s.dal.GetUsersForNotification(dbs, authorID, sid, action)
-	if err != nil {
-		utils.LogCtx(dbs.Ctx()).Error(err)
-		return err
+	directMentionUserIDs := make([]int64, 0, len(mentionedUserIDs))
+	for _, userID := range mentionedUserIDs {
+		if userID != authorID && !uidIn(userID, directMentionUserIDs) {
+			directMentionUserIDs = append(directMentionUserIDs, userID)
+		}
 	}
 
-	if len(mentionUserIDs) == 0 {
+	if len(mentionUserIDs) == 0 && len(directMentionUserIDs) == 0 {
 		return nil
 	}
 
@@ -50,6 +62,8 @@ func (s *SiteService) createNotification(dbs database.DBSession, authorID, sid i
 		b.WriteString(fmt.Sprintf("A new version has been uploaded by <@%d>", authorID))
 	} else if action == constants.ActionReject {
 		b.WriteString("The submission has been rejected.")
+	} else if action == constants.ActionReopen {
+		b.WriteString("The submission has been reopened.")
 	}
 	b.WriteString("\n")
 
@@ -57,6 +71,16 @@ func (s *SiteService) createNotification(dbs database.DBSession, authorID, sid i
 		b.WriteString(fmt.Sprintf(" <@%d>", userID))
 	}
 
+	if len(directMentionUserIDs) > 0 {
+		if len(mentionUserIDs) > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf("<@%d> mentioned you in a comment:", authorID))
+		for _, userID := range directMentionUserIDs {
+			b.WriteString(fmt.Sprintf(" <@%d>", userID))
+		}
+	}
+
 	b.WriteString("\n----------------------------------------------------------\n")
 	msg := b.String()
 
@@ -377,6 +401,12 @@ func (s *SiteService) ProduceRemindersAboutRequestedChanges(ctx context.Context)
 		}
 	}
 
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionSendRemindersAboutRequestedChanges, "", nil,
+		nil, map[string]int{"reminded_users": len(authors)}); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, dberr(err)
+	}
+
 	if err := dbs.Commit(); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return 0, dberr(err)
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// operation is a long-running internal operation, it reports its progress through the context it runs with
+type operation struct {
+	mu      sync.Mutex
+	state   types.Operation
+	logTail []string
+	cancel  context.CancelFunc
+	err     error
+	done    chan struct{} // closed when the operation finishes
+}
+
+// snapshot returns a copy of the operation state which is safe to read
+func (op *operation) snapshot() *types.Operation {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	result := op.state
+	result.LogTail = append([]string{}, op.logTail...)
+	if result.Total > 0 {
+		result.Progress = float64(result.Done) * 100 / float64(result.Total)
+		if result.Progress > 100 {
+			result.Progress = 100
+		}
+	}
+	return &result
+}
+
+// setTotal sets the amount of work of the operation, it's a no-op outside of an operation
+func (op *operation) setTotal(total int64) {
+	if op == nil {
+		return
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.state.Total = total
+}
+
+// advance marks a part of the work as done, it's a no-op outside of an operation
+func (op *operation) advance(n int64) {
+	if op == nil {
+		return
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.state.Done += n
+}
+
+func (op *operation) appendLog(line string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.logTail = append(op.logTail, line)
+	if len(op.logTail) > constants.OperationLogTailSize {
+		op.logTail = op.logTail[len(op.logTail)-constants.OperationLogTailSize:]
+	}
+}
+
+func (op *operation) finish(err error, isCancelled bool, now time.Time) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	op.err = err
+	op.state.FinishedAt = &now
+	switch {
+	case err != nil && isCancelled:
+		op.state.Status = constants.OperationStatusCancelled
+	case err != nil:
+		op.state.Status = constants.OperationStatusFailed
+		e := err.Error()
+		op.state.Error = &e
+	default:
+		op.state.Status = constants.OperationStatusSucceeded
+	}
+}
+
+func (op *operation) isRunning() bool {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	return op.state.Status == constants.OperationStatusRunning
+}
+
+type operationCtxKey struct{}
+
+// operationFromContext returns the operation the context belongs to, or nil
+func operationFromContext(ctx context.Context) *operation {
+	op, _ := ctx.Value(operationCtxKey{}).(*operation)
+	return op
+}
+
+// operationTracker keeps the running operations and a few finished ones
+type operationTracker struct {
+	mu         sync.Mutex
+	operations []*operation
+}
+
+func newOperationTracker() *operationTracker {
+	return &operationTracker{operations: make([]*operation, 0)}
+}
+
+// add registers a new running operation, unless an operation with the same name is already running
+func (t *operationTracker) add(op *operation) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, o := range t.operations {
+		if o.state.Name == op.state.Name && o.isRunning() {
+			return fmt.Errorf("%s is already running", op.state.Name)
+		}
+	}
+
+	// forget the oldest finished operations
+	finished := 0
+	for i := len(t.operations) - 1; i >= 0; i-- {
+		if t.operations[i].isRunning() {
+			continue
+		}
+		finished++
+		if finished > constants.OperationHistorySize {
+			t.operations = append(t.operations[:i], t.operations[i+1:]...)
+		}
+	}
+
+	t.operations = append(t.operations, op)
+	return nil
+}
+
+func (t *operationTracker) remove(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, o := range t.operations {
+		if o.state.ID == id {
+			t.operations = append(t.operations[:i], t.operations[i+1:]...)
+			return
+		}
+	}
+}
+
+func (t *operationTracker) get(id string) *operation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, o := range t.operations {
+		if o.state.ID == id {
+			return o
+		}
+	}
+	return nil
+}
+
+// list returns snapshots of all kept operations, newest first
+func (t *operationTracker) list() []*types.Operation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]*types.Operation, 0, len(t.operations))
+	for i := len(t.operations) - 1; i >= 0; i-- {
+		result = append(result, t.operations[i].snapshot())
+	}
+	return result
+}
+
+// operationLogHook copies log lines of an operation into its log tail and passes them on to the base logger
+type operationLogHook struct {
+	op   *operation
+	base *logrus.Entry
+}
+
+func (h *operationLogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h *operationLogHook) Fire(entry *logrus.Entry) error {
+	h.op.appendLog(formatOperationLogLine(entry, h.base.Data))
+	if h.base.Logger.IsLevelEnabled(entry.Level) {
+		h.base.WithTime(entry.Time).WithFields(entry.Data).Log(entry.Level, entry.Message)
+	}
+	return nil
+}
+
+// formatOperationLogLine formats the entry with the fields which are not already present in every line
+func formatOperationLogLine(entry *logrus.Entry, baseFields logrus.Fields) string {
+	keys := make([]string, 0, len(entry.Data))
+	for k := range entry.Data {
+		if _, ok := baseFields[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("%s %s %s", entry.Time.UTC().Format("15:04:05"), strings.ToUpper(entry.Level.String()), entry.Message))
+	for _, k := range keys {
+		b.WriteString(fmt.Sprintf(" %s=%v", k, entry.Data[k]))
+	}
+	return b.String()
+}
+
+// newOperationLogger creates a logger which records everything into the operation log tail,
+// even the levels the base logger discards
+func newOperationLogger(base *logrus.Entry, op *operation) *logrus.Entry {
+	l := &logrus.Logger{
+		Out:       ioutil.Discard,
+		Formatter: base.Logger.Formatter,
+		Hooks:     make(logrus.LevelHooks),
+		Level:     logrus.DebugLevel,
+		ExitFunc:  base.Logger.ExitFunc,
+	}
+	l.AddHook(&operationLogHook{op: op, base: base})
+	return l.WithFields(base.Data)
+}
+
+// StartOperation runs the function in the background as a tracked operation which can be cancelled,
+// only one operation of the same name runs at a time
+func (s *SiteService) StartOperation(ctx context.Context, name, auditAction string, fn func(ctx context.Context) error) (*types.Operation, error) {
+	op, err := s.startOperation(ctx, name, auditAction, fn)
+	if err != nil {
+		return nil, err
+	}
+	return op.snapshot(), nil
+}
+
+// startOperation starts the operation, it runs until it finishes, is cancelled or the server shuts down
+func (s *SiteService) startOperation(ctx context.Context, name, auditAction string, fn func(ctx context.Context) error) (*operation, error) {
+	opCtx, cancel := context.WithCancel(s.backgroundCtx)
+
+	op := &operation{
+		state: types.Operation{
+			ID:        s.randomStringProvider.RandomString(16),
+			Name:      name,
+			UserID:    utils.UserID(ctx),
+			Status:    constants.OperationStatusRunning,
+			StartedAt: s.clock.Now(),
+		},
+		logTail: make([]string, 0),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	if err := s.operations.add(op); err != nil {
+		cancel()
+		return nil, perr(err.Error(), http.StatusForbidden)
+	}
+
+	if err := s.RecordAuditLogEntry(ctx, auditAction, "", nil, nil, map[string]string{"operation_id": op.state.ID}); err != nil {
+		s.operations.remove(op.state.ID)
+		cancel()
+		return nil, err
+	}
+
+	// the operation acts as the user who started it
+	l := newOperationLogger(utils.LogCtx(ctx), op).WithField("operationID", op.state.ID)
+	opCtx = context.WithValue(opCtx, utils.CtxKeys.Log, l)
+	opCtx = context.WithValue(opCtx, utils.CtxKeys.UserID, utils.UserID(ctx))
+	opCtx = context.WithValue(opCtx, utils.CtxKeys.RequestID, utils.RequestID(ctx))
+	opCtx = context.WithValue(opCtx, operationCtxKey{}, op)
+
+	err := s.goBackground(func(_ context.Context) {
+		defer close(op.done)
+		defer cancel()
+		l.Info("operation started")
+		err := callOperation(opCtx, fn)
+		op.finish(err, opCtx.Err() == context.Canceled, s.clock.Now())
+		if err != nil {
+			l.Error(err)
+		}
+		l.WithField("status", op.snapshot().Status).Info("operation finished")
+	})
+	if err != nil {
+		s.operations.remove(op.state.ID)
+		cancel()
+		return nil, perr("the server is shutting down", http.StatusServiceUnavailable)
+	}
+
+	return op, nil
+}
+
+// runOperation runs the function as a tracked operation and waits until it finishes,
+// so that it never runs together with the same operation started by hand
+func (s *SiteService) runOperation(ctx context.Context, name, auditAction string, fn func(ctx context.Context) error) error {
+	op, err := s.startOperation(ctx, name, auditAction, fn)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-op.done:
+	case <-ctx.Done():
+		op.cancel()
+		<-op.done
+	}
+
+	return op.err
+}
+
+// callOperation runs the operation, a panic is turned into an error so that the operation gets marked as failed
+func callOperation(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("operation panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
+
+// CancelOperation cancels the context of a running operation, the operation stops at its next check
+func (s *SiteService) CancelOperation(ctx context.Context, id string) error {
+	op := s.operations.get(id)
+	if op == nil {
+		return perr("operation not found", http.StatusNotFound)
+	}
+	if !op.isRunning() {
+		return perr("operation is not running", http.StatusConflict)
+	}
+
+	if err := s.RecordAuditLogEntry(ctx, constants.AuditActionCancelOperation, "", nil, nil,
+		map[string]string{"operation_id": id, "operation": op.state.Name}); err != nil {
+		return err
+	}
+
+	op.cancel()
+	return nil
+}
+
+func (s *SiteService) GetOperation(ctx context.Context, id string) (*types.Operation, error) {
+	op := s.operations.get(id)
+	if op == nil {
+		return nil, perr("operation not found", http.StatusNotFound)
+	}
+	return op.snapshot(), nil
+}
+
+func (s *SiteService) GetOperations(ctx context.Context) []*types.Operation {
+	return s.operations.list()
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestOperation(id, name, status string) *operation {
+	op := &operation{cancel: func() {}}
+	op.state.ID = id
+	op.state.Name = name
+	op.state.Status = status
+	return op
+}
+
+func Test_operationTracker_add(t *testing.T) {
+	tracker := newOperationTracker()
+
+	require.NoError(t, tracker.add(newTestOperation("a", constants.OperationUpdateMasterDB, constants.OperationStatusRunning)))
+	require.NoError(t, tracker.add(newTestOperation("b", constants.OperationRecomputeSubmissionCacheAll, constants.OperationStatusRunning)))
+	assert.Error(t, tracker.add(newTestOperation("c", constants.OperationUpdateMasterDB, constants.OperationStatusRunning)))
+
+	tracker.get("a").finish(nil, false, time.Now())
+	assert.NoError(t, tracker.add(newTestOperation("d", constants.OperationUpdateMasterDB, constants.OperationStatusRunning)))
+
+	ids := make([]string, 0)
+	for _, op := range tracker.list() {
+		ids = append(ids, op.ID)
+	}
+	assert.Equal(t, []string{"d", "b", "a"}, ids)
+
+	tracker.remove("b")
+	assert.Nil(t, tracker.get("b"))
+}
+
+func Test_operationTracker_add_history(t *testing.T) {
+	tracker := newOperationTracker()
+
+	for i := 0; i < constants.OperationHistorySize+5; i++ {
+		require.NoError(t, tracker.add(newTestOperation(fmt.Sprint(i), constants.OperationUpdateMasterDB, constants.OperationStatusFailed)))
+	}
+	require.NoError(t, tracker.add(newTestOperation("running", constants.OperationUpdateMasterDB, constants.OperationStatusRunning)))
+
+	ops := tracker.list()
+	assert.Len(t, ops, constants.OperationHistorySize+1)
+	assert.Equal(t, "running", ops[0].ID)
+	assert.Equal(t, fmt.Sprint(constants.OperationHistorySize+4), ops[1].ID)
+	assert.Nil(t, tracker.get("0"))
+}
+
+func Test_operation_progress(t *testing.T) {
+	tests := []struct {
+		name         string
+		total        int64
+		done         int64
+		wantProgress float64
+	}{
+		{name: "unknown total", total: 0, done: 5, wantProgress: 0},
+		{name: "half", total: 200, done: 100, wantProgress: 50},
+		{name: "overshoot", total: 10, done: 12, wantProgress: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := newTestOperation("a", constants.OperationUpdateMasterDB, constants.OperationStatusRunning)
+			op.setTotal(tt.total)
+			op.advance(tt.done)
+			assert.Equal(t, tt.wantProgress, op.snapshot().Progress)
+		})
+	}
+}
+
+func Test_operation_outsideOfOperation(t *testing.T) {
+	op := operationFromContext(context.Background())
+	assert.Nil(t, op)
+	assert.NotPanics(t, func() {
+		op.setTotal(10)
+		op.advance(1)
+	})
+}
+
+func Test_operation_finish(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		isCancelled bool
+		wantStatus  string
+		wantError   bool
+	}{
+		{name: "succeeded", wantStatus: constants.OperationStatusSucceeded},
+		{name: "failed", err: fmt.Errorf("boom"), wantStatus: constants.OperationStatusFailed, wantError: true},
+		{name: "cancelled", err: context.Canceled, isCancelled: true, wantStatus: constants.OperationStatusCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := newTestOperation("a", constants.OperationUpdateMasterDB, constants.OperationStatusRunning)
+			op.finish(tt.err, tt.isCancelled, time.Now())
+
+			snapshot := op.snapshot()
+			assert.Equal(t, tt.wantStatus, snapshot.Status)
+			assert.Equal(t, tt.wantError, snapshot.Error != nil)
+			assert.NotNil(t, snapshot.FinishedAt)
+			assert.False(t, op.isRunning())
+		})
+	}
+}
+
+func Test_newOperationLogger(t *testing.T) {
+	out := &bytes.Buffer{}
+	base := logrus.New()
+	base.Out = out
+	base.Level = logrus.InfoLevel
+
+	op := newTestOperation("a", constants.OperationUpdateMasterDB, constants.OperationStatusRunning)
+	l := newOperationLogger(base.WithField("requestID", "r1"), op)
+
+	l.WithField("submissionID", 7).Debug("recomputing")
+	l.Info("done")
+
+	tail := op.snapshot().LogTail
+	require.Len(t, tail, 2)
+	assert.Contains(t, tail[0], "DEBUG recomputing submissionID=7")
+	assert.NotContains(t, tail[0], "requestID")
+	assert.Contains(t, tail[1], "INFO done")
+
+	// the base logger gets only the levels it has enabled
+	assert.NotContains(t, out.String(), "recomputing")
+	assert.Contains(t, out.String(), "done")
+	assert.Contains(t, out.String(), "requestID=r1")
+}
+
+func Test_operation_appendLog(t *testing.T) {
+	op := newTestOperation("a", constants.OperationUpdateMasterDB, constants.OperationStatusRunning)
+	for i := 0; i < constants.OperationLogTailSize+10; i++ {
+		op.appendLog(fmt.Sprint(i))
+	}
+
+	tail := op.snapshot().LogTail
+	assert.Len(t, tail, constants.OperationLogTailSize)
+	assert.Equal(t, "10", tail[0])
+}
+
+func Test_callOperation(t *testing.T) {
+	err := callOperation(context.Background(), func(ctx context.Context) error {
+		panic("boom")
+	})
+	assert.EqualError(t, err, "operation panicked: boom")
+
+	assert.NoError(t, callOperation(context.Background(), func(ctx context.Context) error { return nil }))
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// scheduledTaskFunc runs a maintenance task and returns a short summary of the result, if there's anything to say
+type scheduledTaskFunc func(s *SiteService, ctx context.Context) (string, error)
+
+// scheduledTaskFuncs are the maintenance tasks which can be scheduled, by their name in the scheduled tasks file.
+// Tasks which can be started by hand as operations run as operations too, so that the two never run at the same time.
+var scheduledTaskFuncs = map[string]scheduledTaskFunc{
+	constants.ScheduledTaskSendRemindersAboutRequestedChanges: func(s *SiteService, ctx context.Context) (string, error) {
+		count, err := s.ProduceRemindersAboutRequestedChanges(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d notifications added to the queue", count), nil
+	},
+	constants.ScheduledTaskIndexUnindexedFlashfreezeFiles: func(s *SiteService, ctx context.Context) (string, error) {
+		return "", s.IndexUnindexedFlashfreezeItems(utils.LogCtx(ctx))
+	},
+	constants.ScheduledTaskRecomputeSubmissionCacheAll: func(s *SiteService, ctx context.Context) (string, error) {
+		return "", s.runOperation(ctx, constants.OperationRecomputeSubmissionCacheAll, constants.AuditActionRecomputeSubmissionCacheAll, s.RecomputeSubmissionCacheAll)
+	},
+	constants.ScheduledTaskUpdateMasterDB: func(s *SiteService, ctx context.Context) (string, error) {
+		return "", s.runOperation(ctx, constants.OperationUpdateMasterDB, constants.AuditActionUpdateMasterDB, s.UpdateMasterDB)
+	},
+	constants.ScheduledTaskRevalidateDegradedSubmissionFiles: func(s *SiteService, ctx context.Context) (string, error) {
+		revalidatedCount, failedCount, err := s.RevalidateDegradedSubmissionFiles(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d files revalidated, %d failed", revalidatedCount, failedCount), nil
+	},
+}
+
+// scheduledTask is a maintenance task with its parsed schedule
+type scheduledTask struct {
+	name     string
+	schedule string
+	cron     *cronSchedule
+	run      scheduledTaskFunc
+}
+
+// LoadScheduledTasks loads the schedules of maintenance tasks from a JSON file
+func LoadScheduledTasks(filePath string) ([]*scheduledTask, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseScheduledTasks(b)
+}
+
+// parseScheduledTasks parses a JSON array of task schedules, every task can be scheduled only once
+func parseScheduledTasks(b []byte) ([]*scheduledTask, error) {
+	var configs []*types.ScheduledTaskConfig
+	if err := json.Unmarshal(b, &configs); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*scheduledTask, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+
+	for _, c := range configs {
+		run, ok := scheduledTaskFuncs[c.Task]
+		if !ok {
+			return nil, fmt.Errorf("unknown scheduled task '%s'", c.Task)
+		}
+		if names[c.Task] {
+			return nil, fmt.Errorf("task '%s' is scheduled more than once", c.Task)
+		}
+		names[c.Task] = true
+
+		cron, err := parseCronSchedule(c.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("task '%s': %s", c.Task, err.Error())
+		}
+		if cron.next(time.Now().UTC()).IsZero() {
+			return nil, fmt.Errorf("task '%s': schedule '%s' never runs", c.Task, c.Schedule)
+		}
+
+		tasks = append(tasks, &scheduledTask{
+			name:     c.Task,
+			schedule: c.Schedule,
+			cron:     cron,
+			run:      run,
+		})
+	}
+
+	return tasks, nil
+}
+
+// newSchedulerInstanceID identifies this process as the holder of scheduled task locks
+func newSchedulerInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	if len(hostname) > 48 {
+		hostname = hostname[:48]
+	}
+	return fmt.Sprintf("%s:%d", hostname, os.Getpid())
+}
+
+// RunScheduler runs the scheduled maintenance tasks at their scheduled times
+func (s *SiteService) RunScheduler(logger *logrus.Entry, ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	l := logger.WithField("serviceName", "scheduler")
+	defer l.Info("scheduler stopped")
+
+	if len(s.scheduledTasks) == 0 {
+		l.Info("no tasks are scheduled")
+		return
+	}
+
+	taskWG := &sync.WaitGroup{}
+	for _, task := range s.scheduledTasks {
+		taskWG.Add(1)
+		go s.runTaskSchedule(l.WithField("task", task.name), ctx, taskWG, task)
+	}
+	taskWG.Wait()
+}
+
+// runTaskSchedule waits for the scheduled times of a task and runs it, times missed while the task was running are skipped
+func (s *SiteService) runTaskSchedule(l *logrus.Entry, ctx context.Context, wg *sync.WaitGroup, task *scheduledTask) {
+	defer wg.Done()
+
+	var last time.Time
+	for {
+		from := s.clock.Now().UTC()
+		if from.Before(last) {
+			from = last
+		}
+		next := task.cron.next(from)
+		last = next
+		l.WithField("nextRunAt", next).Debug("waiting for the next run")
+
+		timer := time.NewTimer(next.Sub(s.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := s.runScheduledTask(l, ctx, task); err != nil && err != context.Canceled {
+			l.Error(err)
+		}
+	}
+}
+
+// runScheduledTask runs the task and records the run, unless the task is already running on another instance
+func (s *SiteService) runScheduledTask(l *logrus.Entry, ctx context.Context, task *scheduledTask) error {
+	acquired, err := s.lockScheduledTask(ctx, task.name)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		l.Debug("task is already running elsewhere, skipping")
+		return nil
+	}
+	defer func() {
+		// released even when the scheduler is stopping, otherwise the task stays blocked until the lease runs out
+		if err := s.unlockScheduledTask(context.Background(), task.name); err != nil {
+			l.Error(err)
+		}
+	}()
+
+	run := &types.ScheduledTaskRun{
+		TaskName:  task.name,
+		Instance:  s.schedulerInstanceID,
+		Status:    constants.ScheduledTaskRunRunning,
+		StartedAt: s.clock.Now(),
+	}
+	if run.ID, err = s.storeScheduledTaskRun(ctx, run); err != nil {
+		return err
+	}
+
+	// the task acts as the system user, its audit log entries can be found by the run
+	taskCtx := context.WithValue(ctx, utils.CtxKeys.Log, l.WithField("taskRunID", run.ID))
+	taskCtx = context.WithValue(taskCtx, utils.CtxKeys.UserID, int64(constants.SystemID))
+	taskCtx = context.WithValue(taskCtx, utils.CtxKeys.RequestID, fmt.Sprintf("task-run-%d", run.ID))
+
+	stopRenewing := make(chan struct{})
+	renewingStopped := make(chan struct{})
+	go func() {
+		defer close(renewingStopped)
+		s.renewScheduledTaskLock(l, ctx, task.name, stopRenewing)
+	}()
+
+	l.Info("running scheduled task")
+	message, runErr := callScheduledTask(s, taskCtx, task)
+
+	close(stopRenewing)
+	<-renewingStopped
+
+	status := constants.ScheduledTaskRunSucceeded
+	var msg *string
+	if runErr != nil {
+		status = constants.ScheduledTaskRunFailed
+		e := runErr.Error()
+		msg = &e
+	} else if message != "" {
+		msg = &message
+	}
+	l.WithField("status", status).Info("scheduled task finished")
+
+	// a run cut short by a shutdown is still recorded
+	return s.finishScheduledTaskRun(context.Background(), run.ID, status, msg)
+}
+
+// callScheduledTask runs the task, a panic is turned into an error so that the run gets recorded as failed
+func callScheduledTask(s *SiteService, ctx context.Context, task *scheduledTask) (message string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task panicked: %v", r)
+		}
+	}()
+	return task.run(s, ctx)
+}
+
+// renewScheduledTaskLock keeps extending the lock lease until stopped
+func (s *SiteService) renewScheduledTaskLock(l *logrus.Entry, ctx context.Context, taskName string, stop chan struct{}) {
+	ticker := time.NewTicker(constants.ScheduledTaskLockLease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		acquired, err := s.lockScheduledTask(ctx, taskName)
+		if err != nil {
+			l.Error(err)
+		} else if !acquired {
+			l.Warn("lost the scheduled task lock, the task may run twice")
+		}
+	}
+}
+
+func (s *SiteService) lockScheduledTask(ctx context.Context, taskName string) (bool, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer dbs.Rollback()
+
+	now := s.clock.Now()
+	acquired, err := s.dal.AcquireScheduledTaskLock(dbs, taskName, s.schedulerInstanceID, now, now.Add(constants.ScheduledTaskLockLease))
+	if err != nil {
+		return false, err
+	}
+
+	if err := dbs.Commit(); err != nil {
+		return false, err
+	}
+
+	return acquired, nil
+}
+
+func (s *SiteService) unlockScheduledTask(ctx context.Context, taskName string) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.ReleaseScheduledTaskLock(dbs, taskName, s.schedulerInstanceID); err != nil {
+		return err
+	}
+
+	return dbs.Commit()
+}
+
+func (s *SiteService) storeScheduledTaskRun(ctx context.Context, run *types.ScheduledTaskRun) (int64, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer dbs.Rollback()
+
+	id, err := s.dal.StoreScheduledTaskRun(dbs, run)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := dbs.Commit(); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (s *SiteService) finishScheduledTaskRun(ctx context.Context, id int64, status string, message *string) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.FinishScheduledTaskRun(dbs, id, status, message, s.clock.Now()); err != nil {
+		return err
+	}
+
+	return dbs.Commit()
+}
+
+// scheduledTaskStatuses pairs the configured tasks with their last runs
+func scheduledTaskStatuses(tasks []*scheduledTask, lastRuns []*types.ScheduledTaskRun, now time.Time) []*types.ScheduledTaskStatus {
+	byTask := make(map[string]*types.ScheduledTaskRun, len(lastRuns))
+	for _, r := range lastRuns {
+		byTask[r.TaskName] = r
+	}
+
+	result := make([]*types.ScheduledTaskStatus, 0, len(tasks))
+	for _, task := range tasks {
+		result = append(result, &types.ScheduledTaskStatus{
+			Task:      task.name,
+			Schedule:  task.schedule,
+			NextRunAt: task.cron.next(now.UTC()),
+			LastRun:   byTask[task.name],
+		})
+	}
+
+	return result
+}
+
+func (s *SiteService) GetInternalPageData(ctx context.Context) (*types.InternalPageData, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	bpd, err := s.GetBasePageData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	lastRuns, err := s.dal.GetLastScheduledTaskRuns(dbs)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	recentRuns, err := s.dal.GetScheduledTaskRuns(dbs, constants.ScheduledTaskRunHistorySize)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	pageData := &types.InternalPageData{
+		BasePageData:   *bpd,
+		ScheduledTasks: scheduledTaskStatuses(s.scheduledTasks, lastRuns, s.clock.Now()),
+		RecentRuns:     recentRuns,
+		Operations:     s.GetOperations(ctx),
+	}
+
+	return pageData, nil
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseScheduledTasks(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		wantTasks []string
+		wantErr   bool
+	}{
+		{
+			name:      "valid",
+			input:     `[{"task": "recompute-submission-cache-all", "schedule": "30 4 * * *"}, {"task": "update-master-db", "schedule": "0 0 * * 0"}]`,
+			wantTasks: []string{constants.ScheduledTaskRecomputeSubmissionCacheAll, constants.ScheduledTaskUpdateMasterDB},
+		},
+		{
+			name:      "empty",
+			input:     `[]`,
+			wantTasks: []string{},
+		},
+		{
+			name:    "unknown task",
+			input:   `[{"task": "format-disk", "schedule": "* * * * *"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "duplicate task",
+			input:   `[{"task": "update-master-db", "schedule": "0 0 * * 0"}, {"task": "update-master-db", "schedule": "0 0 * * 3"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid schedule",
+			input:   `[{"task": "update-master-db", "schedule": "0 0 * *"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "schedule never runs",
+			input:   `[{"task": "update-master-db", "schedule": "0 0 31 4 *"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid json",
+			input:   `{`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, err := parseScheduledTasks([]byte(tt.input))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, 0, len(tasks))
+			for _, task := range tasks {
+				names = append(names, task.name)
+			}
+			assert.Equal(t, tt.wantTasks, names)
+		})
+	}
+}
+
+func Test_scheduledTaskStatuses(t *testing.T) {
+	tasks, err := parseScheduledTasks([]byte(`[{"task": "recompute-submission-cache-all", "schedule": "30 4 * * *"}, {"task": "update-master-db", "schedule": "0 0 * * 0"}]`))
+	require.NoError(t, err)
+
+	now := time.Date(2021, 6, 16, 10, 20, 30, 0, time.UTC)
+	lastRun := &types.ScheduledTaskRun{ID: 7, TaskName: constants.ScheduledTaskUpdateMasterDB, Status: constants.ScheduledTaskRunSucceeded}
+	// runs of tasks which are not scheduled anymore are left out
+	oldRun := &types.ScheduledTaskRun{ID: 3, TaskName: constants.ScheduledTaskSendRemindersAboutRequestedChanges}
+
+	statuses := scheduledTaskStatuses(tasks, []*types.ScheduledTaskRun{lastRun, oldRun}, now)
+
+	require.Len(t, statuses, 2)
+	assert.Equal(t, constants.ScheduledTaskRecomputeSubmissionCacheAll, statuses[0].Task)
+	assert.Equal(t, time.Date(2021, 6, 17, 4, 30, 0, 0, time.UTC), statuses[0].NextRunAt)
+	assert.Nil(t, statuses[0].LastRun)
+	assert.Equal(t, constants.ScheduledTaskUpdateMasterDB, statuses[1].Task)
+	assert.Equal(t, time.Date(2021, 6, 20, 0, 0, 0, 0, time.UTC), statuses[1].NextRunAt)
+	assert.Equal(t, lastRun, statuses[1].LastRun)
+}
@@ -3,10 +3,13 @@ package service
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/database"
 	"github.com/Dri0m/flashpoint-submission-system/types"
 	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/go-sql-driver/mysql"
 	"github.com/sirupsen/logrus"
 )
 
@@ -18,6 +21,12 @@ func perr(msg string, status int) error {
 	return constants.PublicError{Msg: msg, Status: status}
 }
 
+// isDuplicateEntry tells if the error is a violation of a unique key
+func isDuplicateEntry(err error) bool {
+	me, ok := err.(*mysql.MySQLError)
+	return ok && me.Number == 1062
+}
+
 func resumableLog(ctx context.Context, resumableParams *types.ResumableParams) *logrus.Entry {
 	if resumableParams == nil {
 		panic("invalid arguments provided")
@@ -35,59 +44,101 @@ func resumableLog(ctx context.Context, resumableParams *types.ResumableParams) *
 	})
 }
 
+// SubmissionStatusKeeper persists the status of submission jobs, so that it can be read by any instance and survives restarts
 type SubmissionStatusKeeper struct {
-	m map[string]*types.SubmissionStatus
+	dal database.DAL
+	// last status written by this instance for every job it's processing, used to throttle progress updates
+	last map[string]*submissionStatusUpdate
 	sync.Mutex
 }
 
-func (s *SubmissionStatusKeeper) SetReceived(tempName string) {
-	s.Lock()
-	defer s.Unlock()
-	s.m[tempName] = &types.SubmissionStatus{Status: constants.SubmissionStatusReceived}
+type submissionStatusUpdate struct {
+	status string
+	at     time.Time
 }
 
-func (s *SubmissionStatusKeeper) SetCopying(tempName string, message string) {
-	s.Lock()
-	defer s.Unlock()
-	s.m[tempName].Status = constants.SubmissionStatusCopying
-	s.m[tempName].Message = &message
+// submissionStatusProgressInterval is the minimal delay between two stored status updates within the same stage
+const submissionStatusProgressInterval = time.Second
+
+func NewSubmissionStatusKeeper(dal database.DAL) SubmissionStatusKeeper {
+	return SubmissionStatusKeeper{
+		dal:  dal,
+		last: make(map[string]*submissionStatusUpdate),
+	}
 }
 
-func (s *SubmissionStatusKeeper) SetValidating(tempName string) {
-	s.Lock()
-	defer s.Unlock()
-	s.m[tempName].Status = constants.SubmissionStatusValidating
-	s.m[tempName].Message = nil
+func (s *SubmissionStatusKeeper) SetReceived(ctx context.Context, tempName string) {
+	s.set(ctx, tempName, constants.SubmissionStatusReceived, nil)
 }
 
-func (s *SubmissionStatusKeeper) SetFinalizing(tempName string) {
-	s.Lock()
-	defer s.Unlock()
-	s.m[tempName].Status = constants.SubmissionStatusFinalizing
-	s.m[tempName].Message = nil
+func (s *SubmissionStatusKeeper) SetCopying(ctx context.Context, tempName string, message string) {
+	s.set(ctx, tempName, constants.SubmissionStatusCopying, &message)
 }
 
-func (s *SubmissionStatusKeeper) SetFailed(tempName, message string) {
-	s.Lock()
-	defer s.Unlock()
-	s.m[tempName].Status = constants.SubmissionStatusFailed
-	s.m[tempName].Message = &message
+func (s *SubmissionStatusKeeper) SetValidating(ctx context.Context, tempName string) {
+	s.set(ctx, tempName, constants.SubmissionStatusValidating, nil)
 }
 
-func (s *SubmissionStatusKeeper) SetSuccess(tempName string, sid int64) {
+func (s *SubmissionStatusKeeper) SetFinalizing(ctx context.Context, tempName string) {
+	s.set(ctx, tempName, constants.SubmissionStatusFinalizing, nil)
+}
+
+func (s *SubmissionStatusKeeper) SetFailed(ctx context.Context, tempName, message string) {
+	s.set(ctx, tempName, constants.SubmissionStatusFailed, &message)
+}
+
+func (s *SubmissionStatusKeeper) SetSuccess(ctx context.Context, tempName string) {
+	s.set(ctx, tempName, constants.SubmissionStatusSuccess, nil)
+}
+
+// set stores the current status of a job, and a timeline entry if the job entered a new stage
+func (s *SubmissionStatusKeeper) set(ctx context.Context, tempName, status string, message *string) {
+	now := time.Now()
+
 	s.Lock()
-	defer s.Unlock()
-	s.m[tempName].Status = constants.SubmissionStatusSuccess
-	s.m[tempName].Message = nil
-	s.m[tempName].SubmissionID = &sid
+	last, ok := s.last[tempName]
+	isNewStage := !ok || last.status != status
+	if !isNewStage && now.Sub(last.at) < submissionStatusProgressInterval {
+		s.Unlock()
+		return
+	}
+	if status == constants.SubmissionStatusSuccess || status == constants.SubmissionStatusFailed {
+		delete(s.last, tempName)
+	} else {
+		s.last[tempName] = &submissionStatusUpdate{status: status, at: now}
+	}
+	s.Unlock()
+
+	err := func() error {
+		dbs, err := s.dal.NewSession(ctx)
+		if err != nil {
+			return err
+		}
+		defer dbs.Rollback()
+
+		if err := s.dal.UpdateSubmissionJobStatus(dbs, tempName, status, message); err != nil {
+			return err
+		}
+		if isNewStage {
+			if err := s.dal.StoreSubmissionJobStatusHistory(dbs, tempName, status, message); err != nil {
+				return err
+			}
+		}
+
+		return dbs.Commit()
+	}()
+	if err != nil {
+		utils.LogCtx(ctx).WithField("tempName", tempName).Error(err)
+	}
 }
 
-func (s *SubmissionStatusKeeper) Get(tempName string) *types.SubmissionStatus {
+// prune forgets jobs which were not updated since olderThan, those were abandoned by this instance
+func (s *SubmissionStatusKeeper) prune(olderThan time.Time) {
 	s.Lock()
 	defer s.Unlock()
-	ss, ok := s.m[tempName]
-	if !ok {
-		return nil
+	for tempName, last := range s.last {
+		if last.at.Before(olderThan) {
+			delete(s.last, tempName)
+		}
 	}
-	return ss
 }
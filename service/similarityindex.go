@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/agnivade/levenshtein"
+)
+
+// similarityIndexEntry is a single submission or masterdb game in the similarity index
+type similarityIndexEntry struct {
+	attributes              types.SimilarityAttributes
+	normalizedTitle         string
+	normalizedLaunchCommand string // without any rule applied, the rule matching the query is applied at search time
+	isRemoved               bool
+}
+
+// similarityIndex is an in-memory trigram index of titles and launch commands,
+// it provides candidates for the similarity scoring so that uploads do not have to compare against every curation.
+// Trigrams of launch commands are taken from lower-cased launch commands without any rule applied, so that the rules do not affect the content of the index.
+type similarityIndex struct {
+	mutex              sync.RWMutex
+	isBuilt            bool
+	rules              *similarityRuleSet
+	entries            []*similarityIndexEntry
+	byID               map[string]int32
+	titleGrams         map[string][]int32
+	launchCommandGrams map[string][]int32
+}
+
+func newSimilarityIndex() *similarityIndex {
+	return &similarityIndex{
+		entries:            make([]*similarityIndexEntry, 0),
+		byID:               make(map[string]int32),
+		titleGrams:         make(map[string][]int32),
+		launchCommandGrams: make(map[string][]int32),
+	}
+}
+
+// normalizeSimilarityAttribute removes characters which should not affect the similarity of titles and launch commands
+func normalizeSimilarityAttribute(s string) string {
+	return strings.ReplaceAll(
+		strings.ReplaceAll(
+			strings.ReplaceAll(
+				strings.ReplaceAll(
+					s, "`", ""),
+				" ", ""),
+			"'", ""),
+		`"`, "")
+}
+
+// similarityTrigrams returns distinct trigrams of a normalized string, padded so that short strings have trigrams as well
+func similarityTrigrams(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	runes := append(append([]rune{0, 0}, []rune(s)...), 0)
+	seen := make(map[string]bool, len(runes))
+	result := make([]string, 0, len(runes))
+
+	for i := 0; i+3 <= len(runes); i++ {
+		gram := string(runes[i : i+3])
+		if seen[gram] {
+			continue
+		}
+		seen[gram] = true
+		result = append(result, gram)
+	}
+
+	return result
+}
+
+// rebuild replaces the content and the rules of the index
+func (si *similarityIndex) rebuild(sas []*types.SimilarityAttributes, rules *similarityRuleSet) {
+	fresh := newSimilarityIndex()
+	fresh.rules = rules
+	for _, sa := range sas {
+		fresh.add(sa)
+	}
+
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+
+	si.rules = fresh.rules
+	si.entries = fresh.entries
+	si.byID = fresh.byID
+	si.titleGrams = fresh.titleGrams
+	si.launchCommandGrams = fresh.launchCommandGrams
+	si.isBuilt = true
+}
+
+// setRules replaces the rules, the entries stay as they are because rules are applied to them at search time
+func (si *similarityIndex) setRules(rules *similarityRuleSet) {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+
+	si.rules = rules
+}
+
+// upsert adds or replaces an entry, it does nothing until the index is built as the build will pick it up from the database
+func (si *similarityIndex) upsert(sa *types.SimilarityAttributes) {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+
+	if !si.isBuilt {
+		return
+	}
+
+	si.add(sa)
+}
+
+// remove removes an entry, its trigrams stay in the index until the next rebuild
+func (si *similarityIndex) remove(id string) {
+	si.mutex.Lock()
+	defer si.mutex.Unlock()
+
+	if i, ok := si.byID[id]; ok {
+		si.entries[i].isRemoved = true
+		delete(si.byID, id)
+	}
+}
+
+// add must be called with the lock held or on an index which is not shared yet
+func (si *similarityIndex) add(sa *types.SimilarityAttributes) {
+	if i, ok := si.byID[sa.ID]; ok {
+		si.entries[i].isRemoved = true
+	}
+
+	e := &similarityIndexEntry{
+		attributes: types.SimilarityAttributes{ID: sa.ID, Title: sa.Title, LaunchCommand: sa.LaunchCommand},
+	}
+	if sa.Title != nil {
+		e.normalizedTitle = normalizeSimilarityAttribute(*sa.Title)
+	}
+	if sa.LaunchCommand != nil {
+		e.normalizedLaunchCommand = normalizeSimilarityAttribute(*sa.LaunchCommand)
+	}
+
+	i := int32(len(si.entries))
+	si.entries = append(si.entries, e)
+	si.byID[sa.ID] = i
+
+	for _, gram := range similarityTrigrams(e.normalizedTitle) {
+		si.titleGrams[gram] = append(si.titleGrams[gram], i)
+	}
+	for _, gram := range similarityTrigrams(strings.ToLower(e.normalizedLaunchCommand)) {
+		si.launchCommandGrams[gram] = append(si.launchCommandGrams[gram], i)
+	}
+}
+
+// search finds entries with similar title or launch command, the threshold and normalization of launch commands are given by the rules
+func (si *similarityIndex) search(title, launchCommand *string, defaultMinimumMatch float64) *types.SimilarityTestResult {
+	si.mutex.RLock()
+
+	result := &types.SimilarityTestResult{
+		MatchedRule:             si.rules.match(launchCommand),
+		MinimumMatch:            si.rules.minimumMatch(launchCommand, defaultMinimumMatch),
+		NormalizedLaunchCommand: si.rules.normalizeLaunchCommand(launchCommand),
+	}
+	if title != nil {
+		result.NormalizedTitle = normalizeSimilarityAttribute(*title)
+	}
+
+	titleCandidates, launchCommandCandidates := si.candidates(result.NormalizedTitle, result.NormalizedLaunchCommand, result.MatchedRule, result.MinimumMatch)
+
+	si.mutex.RUnlock()
+
+	result.TitleMatches, result.LaunchCommandMatches = scoreSimilarityCandidates(titleCandidates, launchCommandCandidates,
+		result.NormalizedTitle, result.NormalizedLaunchCommand, result.MinimumMatch)
+
+	return result
+}
+
+// similarityCandidate is a copy of an index entry, so that the scoring does not have to hold the lock
+type similarityCandidate struct {
+	attributes *types.SimilarityAttributes
+	normalized string
+}
+
+// candidates returns entries which may reach the minimum match ratio for the normalized title and launch command,
+// launch commands of the entries are normalized by the rule matching the query.
+// One edit destroys at most three trigrams, so entries sharing too few trigrams with the query cannot be similar enough.
+// It must be called with the read lock held.
+func (si *similarityIndex) candidates(normalizedTitle, normalizedLaunchCommand string, rule *types.SimilarityRule, minimumMatch float64) ([]*similarityCandidate, []*similarityCandidate) {
+	byTitle := si.lookup(si.titleGrams, similarityTrigrams(normalizedTitle), 0, normalizedTitle, minimumMatch,
+		func(e *similarityIndexEntry) string { return e.normalizedTitle })
+
+	// cutting off the host or the query string of an entry loses up to three of its padded edge trigrams
+	missingGrams := 0
+	if rule != nil && (rule.IgnoreHost || rule.StripQueryString) {
+		missingGrams = 3
+	}
+	byLaunchCommand := si.lookup(si.launchCommandGrams, similarityTrigrams(strings.ToLower(normalizedLaunchCommand)), missingGrams, normalizedLaunchCommand, minimumMatch,
+		func(e *similarityIndexEntry) string { return applySimilarityRule(rule, e.normalizedLaunchCommand) })
+
+	return byTitle, byLaunchCommand
+}
+
+// lookup must be called with the read lock held
+func (si *similarityIndex) lookup(grams map[string][]int32, queryGrams []string, missingGrams int, query string, minimumMatch float64, field func(e *similarityIndexEntry) string) []*similarityCandidate {
+	result := make([]*similarityCandidate, 0)
+
+	if len(queryGrams) == 0 {
+		return result
+	}
+
+	shared := make([]int32, len(si.entries))
+	touched := make([]int32, 0, 64)
+	for _, gram := range queryGrams {
+		for _, i := range grams[gram] {
+			if shared[i] == 0 {
+				touched = append(touched, i)
+			}
+			shared[i]++
+		}
+	}
+
+	for _, i := range touched {
+		count := int(shared[i])
+		e := si.entries[i]
+		if e.isRemoved {
+			continue
+		}
+
+		normalized := field(e)
+		maxLength := math.Max(float64(len(query)), float64(len(normalized)))
+		maxDistance := int(math.Ceil((1-minimumMatch)*maxLength)) - 1
+		if maxDistance < 0 {
+			maxDistance = 0
+		}
+
+		if count < len(queryGrams)-3*maxDistance-missingGrams {
+			continue
+		}
+
+		sa := e.attributes
+		result = append(result, &similarityCandidate{attributes: &sa, normalized: normalized})
+	}
+
+	return result
+}
+
+// scoreSimilarityCandidates computes match ratios of the candidates and returns those above the minimum match, best matches first
+func scoreSimilarityCandidates(titleCandidates, launchCommandCandidates []*similarityCandidate, normalizedTitle, normalizedLaunchCommand string, minimumMatch float64) ([]*types.SimilarityAttributes, []*types.SimilarityAttributes) {
+	matchRatio := func(a, b string) float64 {
+		distance := similarityDistance(a, b)
+		return 1 - (float64(distance) / math.Max(float64(len(a)), float64(len(b))))
+	}
+
+	byTitle := make([]*types.SimilarityAttributes, 0)
+	byLaunchCommand := make([]*types.SimilarityAttributes, 0)
+
+	for _, c := range titleCandidates {
+		if c.attributes.Title == nil {
+			continue
+		}
+		if ratio := matchRatio(normalizedTitle, c.normalized); ratio > minimumMatch {
+			c.attributes.TitleRatio = ratio
+			byTitle = append(byTitle, c.attributes)
+		}
+	}
+
+	for _, c := range launchCommandCandidates {
+		if c.attributes.LaunchCommand == nil {
+			continue
+		}
+		if ratio := matchRatio(normalizedLaunchCommand, c.normalized); ratio > minimumMatch {
+			c.attributes.LaunchCommandRatio = ratio
+			byLaunchCommand = append(byLaunchCommand, c.attributes)
+		}
+	}
+
+	sort.Slice(byTitle, func(i, j int) bool {
+		return byTitle[i].TitleRatio > byTitle[j].TitleRatio
+	})
+
+	sort.Slice(byLaunchCommand, func(i, j int) bool {
+		return byLaunchCommand[i].LaunchCommandRatio > byLaunchCommand[j].LaunchCommandRatio
+	})
+
+	return byTitle, byLaunchCommand
+}
+
+// similarityDistance computes levenshtein distance, common prefix and suffix do not change the distance so they are left out,
+// which makes comparing launch commands differing only in a game ID cheap
+func similarityDistance(a, b string) int {
+	for len(a) > 0 && len(b) > 0 {
+		ra, size := utf8.DecodeRuneInString(a)
+		rb, _ := utf8.DecodeRuneInString(b)
+		if ra != rb {
+			break
+		}
+		a, b = a[size:], b[size:]
+	}
+	for len(a) > 0 && len(b) > 0 {
+		ra, size := utf8.DecodeLastRuneInString(a)
+		rb, _ := utf8.DecodeLastRuneInString(b)
+		if ra != rb {
+			break
+		}
+		a, b = a[:len(a)-size], b[:len(b)-size]
+	}
+	return levenshtein.ComputeDistance(a, b)
+}
+
+// ensureSimilarityIndex builds the similarity index from the database if it was not built yet
+func (s *SiteService) ensureSimilarityIndex(dbs database.DBSession) error {
+	s.similarityIndex.mutex.RLock()
+	isBuilt := s.similarityIndex.isBuilt
+	s.similarityIndex.mutex.RUnlock()
+
+	if isBuilt {
+		return nil
+	}
+
+	return s.rebuildSimilarityIndex(dbs)
+}
+
+// rebuildSimilarityIndexFromDB rebuilds the similarity index in its own session
+func (s *SiteService) rebuildSimilarityIndexFromDB(ctx context.Context) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	return s.rebuildSimilarityIndex(dbs)
+}
+
+// rebuildSimilarityIndex loads all similarity attributes and rules from the database into the similarity index
+func (s *SiteService) rebuildSimilarityIndex(dbs database.DBSession) error {
+	rules, err := s.loadSimilarityRules(dbs)
+	if err != nil {
+		return err
+	}
+
+	sas, err := s.dal.GetAllSimilarityAttributes(dbs)
+	if err != nil {
+		utils.LogCtx(dbs.Ctx()).Error(err)
+		return dberr(err)
+	}
+
+	s.similarityIndex.rebuild(sas, rules)
+
+	utils.LogCtx(dbs.Ctx()).WithField("amount", len(sas)).Debug("similarity index rebuilt")
+	return nil
+}
+
+// updateSimilarityIndex puts the curation meta of a submission file into the similarity index, failures are only logged as the index is rebuilt from the database eventually
+func (s *SiteService) updateSimilarityIndex(ctx context.Context, sid, sfid int64) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+	defer dbs.Rollback()
+
+	meta, err := s.dal.GetCurationMetaBySubmissionFileID(dbs, sfid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+
+	s.similarityIndex.upsert(&types.SimilarityAttributes{
+		ID:            strconv.FormatInt(sid, 10),
+		Title:         meta.Title,
+		LaunchCommand: meta.LaunchCommand,
+	})
+}
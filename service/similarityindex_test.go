@@ -0,0 +1,170 @@
+package service
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var similarityTestWords = []string{
+	"super", "mega", "ultra", "dragon", "ninja", "pixel", "quest", "racer", "escape", "room",
+	"puzzle", "kitty", "space", "zombie", "castle", "tower", "defense", "legend", "island", "bubble",
+}
+
+// generateSimilarityAttributes generates deterministic titles and launch commands resembling the real ones
+func generateSimilarityAttributes(n int) []*types.SimilarityAttributes {
+	r := rand.New(rand.NewSource(42))
+	result := make([]*types.SimilarityAttributes, 0, n)
+
+	for i := 0; i < n; i++ {
+		title := ""
+		for j := 0; j < 2+r.Intn(3); j++ {
+			if j > 0 {
+				title += " "
+			}
+			title += similarityTestWords[r.Intn(len(similarityTestWords))]
+		}
+		title += fmt.Sprintf(" %d", r.Intn(1000))
+		launchCommand := fmt.Sprintf("http://www.%s.com/games/%d/%s.swf", similarityTestWords[r.Intn(len(similarityTestWords))], i, similarityTestWords[r.Intn(len(similarityTestWords))])
+
+		result = append(result, &types.SimilarityAttributes{ID: fmt.Sprint(i), Title: &title, LaunchCommand: &launchCommand})
+	}
+
+	return result
+}
+
+func similarityIDs(sas []*types.SimilarityAttributes) []string {
+	result := make([]string, 0, len(sas))
+	for _, sa := range sas {
+		result = append(result, sa.ID)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// fullScanCandidates makes every entry a candidate, the way similarity was computed before the index
+func fullScanCandidates(sas []*types.SimilarityAttributes) ([]*similarityCandidate, []*similarityCandidate) {
+	byTitle := make([]*similarityCandidate, 0, len(sas))
+	byLaunchCommand := make([]*similarityCandidate, 0, len(sas))
+	for _, sa := range sas {
+		c := *sa
+		byTitle = append(byTitle, &similarityCandidate{attributes: &c, normalized: normalizeSimilarityAttribute(*sa.Title)})
+		byLaunchCommand = append(byLaunchCommand, &similarityCandidate{attributes: &c, normalized: normalizeSimilarityAttribute(*sa.LaunchCommand)})
+	}
+	return byTitle, byLaunchCommand
+}
+
+func Test_similarityTrigrams(t *testing.T) {
+	assert.Nil(t, similarityTrigrams(""))
+	assert.Equal(t, []string{"\x00\x00a", "\x00a\x00"}, similarityTrigrams("a"))
+	assert.Equal(t, []string{"\x00\x00a", "\x00aa", "aaa", "aa\x00"}, similarityTrigrams("aaaa"))
+}
+
+func Test_similarityIndex_search(t *testing.T) {
+	sas := generateSimilarityAttributes(2000)
+	si := newSimilarityIndex()
+	si.rebuild(sas, nil)
+
+	for _, minimumMatch := range []float64{0.9, 0.8, 0.5} {
+		for _, query := range sas[:50] {
+			title := *query.Title + "x"
+			launchCommand := *query.LaunchCommand
+
+			titleCandidates, launchCommandCandidates := fullScanCandidates(sas)
+			wantTitle, wantLaunchCommand := scoreSimilarityCandidates(titleCandidates, launchCommandCandidates,
+				normalizeSimilarityAttribute(title), normalizeSimilarityAttribute(launchCommand), minimumMatch)
+			got := si.search(&title, &launchCommand, minimumMatch)
+
+			assert.Equal(t, similarityIDs(wantTitle), similarityIDs(got.TitleMatches))
+			assert.Equal(t, similarityIDs(wantLaunchCommand), similarityIDs(got.LaunchCommandMatches))
+		}
+	}
+}
+
+func Test_similarityIndex_searchWithRules(t *testing.T) {
+	first := "http://www.example.com/games/foo.swf?v=1"
+	second := "http://CDN.example.com/games/Foo.swf?v=2"
+	otherHost := "http://cdn.mirror.net/games/foo.swf"
+	query := "https://example.com/games/foo.swf"
+
+	rules, err := newSimilarityRuleSet([]*types.SimilarityRule{
+		{ID: 1, Pattern: `example\.com/games`, Threshold: 0.99, StripQueryString: true, IgnoreHost: true, CaseFold: true},
+	})
+	require.NoError(t, err)
+
+	si := newSimilarityIndex()
+	si.rebuild([]*types.SimilarityAttributes{{ID: "1", LaunchCommand: &first}, {ID: "2", LaunchCommand: &second}, {ID: "3", LaunchCommand: &otherHost}}, nil)
+
+	got := si.search(nil, &query, 0.9)
+	assert.Nil(t, got.MatchedRule)
+	assert.Empty(t, got.LaunchCommandMatches)
+
+	si.setRules(rules)
+
+	got = si.search(nil, &query, 0.9)
+	assert.Equal(t, int64(1), got.MatchedRule.ID)
+	assert.Equal(t, 0.99, got.MinimumMatch)
+	assert.Equal(t, "/games/foo.swf", got.NormalizedLaunchCommand)
+	// the rule matching the query applies to the entries as well, even to those on a host the rule does not match
+	assert.Equal(t, []string{"1", "2", "3"}, similarityIDs(got.LaunchCommandMatches))
+
+	// entries added after the rules were set are normalized by the same rule
+	si.upsert(&types.SimilarityAttributes{ID: "4", LaunchCommand: &otherHost})
+	got = si.search(nil, &query, 0.9)
+	assert.Equal(t, []string{"1", "2", "3", "4"}, similarityIDs(got.LaunchCommandMatches))
+}
+
+func Test_similarityIndex_upsertAndRemove(t *testing.T) {
+	title := "Super Dragon Quest"
+	changedTitle := "Escape The Kitty Room"
+
+	si := newSimilarityIndex()
+	si.upsert(&types.SimilarityAttributes{ID: "1", Title: &title})
+	titles := si.search(&title, nil, 0.9).TitleMatches
+	assert.Empty(t, titles, "upsert before the build is left to the build")
+
+	si.rebuild([]*types.SimilarityAttributes{{ID: "1", Title: &title}}, nil)
+	titles = si.search(&title, nil, 0.9).TitleMatches
+	assert.Equal(t, []string{"1"}, similarityIDs(titles))
+
+	si.upsert(&types.SimilarityAttributes{ID: "1", Title: &changedTitle})
+	titles = si.search(&title, nil, 0.9).TitleMatches
+	assert.Empty(t, titles)
+	titles = si.search(&changedTitle, nil, 0.9).TitleMatches
+	assert.Equal(t, []string{"1"}, similarityIDs(titles))
+
+	si.remove("1")
+	titles = si.search(&changedTitle, nil, 0.9).TitleMatches
+	assert.Empty(t, titles)
+}
+
+func BenchmarkSimilarityScores(b *testing.B) {
+	sas := generateSimilarityAttributes(200000)
+	title := *sas[1234].Title + "x"
+	launchCommand := *sas[1234].LaunchCommand
+
+	b.Run("full-scan", func(b *testing.B) {
+		titleCandidates, launchCommandCandidates := fullScanCandidates(sas)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			scoreSimilarityCandidates(titleCandidates, launchCommandCandidates,
+				normalizeSimilarityAttribute(title), normalizeSimilarityAttribute(launchCommand), 0.9)
+		}
+	})
+
+	b.Run("indexed", func(b *testing.B) {
+		si := newSimilarityIndex()
+		si.rebuild(sas, nil)
+		b.ResetTimer()
+
+		for i := 0; i < b.N; i++ {
+			si.search(&title, &launchCommand, 0.9)
+		}
+	})
+}
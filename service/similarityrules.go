@@ -0,0 +1,100 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+)
+
+// similarityRuleSet applies similarity rules to launch commands, the first matching rule wins
+type similarityRuleSet struct {
+	rules    []*types.SimilarityRule
+	patterns []*regexp.Regexp
+}
+
+// newSimilarityRuleSet compiles rules, it fails on rules which could not be applied
+func newSimilarityRuleSet(rules []*types.SimilarityRule) (*similarityRuleSet, error) {
+	rs := &similarityRuleSet{
+		rules:    rules,
+		patterns: make([]*regexp.Regexp, 0, len(rules)),
+	}
+
+	for _, rule := range rules {
+		if err := checkSimilarityRule(rule); err != nil {
+			return nil, err
+		}
+		rs.patterns = append(rs.patterns, regexp.MustCompile(rule.Pattern))
+	}
+
+	return rs, nil
+}
+
+// checkSimilarityRule checks a rule before it's stored
+func checkSimilarityRule(rule *types.SimilarityRule) error {
+	if rule.Pattern == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	if _, err := regexp.Compile(rule.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern '%s': %w", rule.Pattern, err)
+	}
+	if rule.Threshold <= 0 || rule.Threshold > 1 {
+		return fmt.Errorf("threshold must be greater than 0 and at most 1")
+	}
+	return nil
+}
+
+// match returns the first rule matching the launch command, or nil
+func (rs *similarityRuleSet) match(launchCommand *string) *types.SimilarityRule {
+	if rs == nil || launchCommand == nil {
+		return nil
+	}
+	for i, pattern := range rs.patterns {
+		if pattern.MatchString(*launchCommand) {
+			return rs.rules[i]
+		}
+	}
+	return nil
+}
+
+// minimumMatch returns the threshold of the rule matching the launch command, or the default one
+func (rs *similarityRuleSet) minimumMatch(launchCommand *string, defaultMinimumMatch float64) float64 {
+	if rule := rs.match(launchCommand); rule != nil {
+		return rule.Threshold
+	}
+	return defaultMinimumMatch
+}
+
+var similarityURLHostRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://[^/]*`)
+
+// normalizeLaunchCommand normalizes a launch command according to the rule matching it
+func (rs *similarityRuleSet) normalizeLaunchCommand(launchCommand *string) string {
+	if launchCommand == nil {
+		return ""
+	}
+	return applySimilarityRule(rs.match(launchCommand), normalizeSimilarityAttribute(*launchCommand))
+}
+
+// applySimilarityRule applies normalization of a rule to an already normalized launch command.
+// Launch commands compared with a query are normalized by the rule matching the query, not by their own one.
+func applySimilarityRule(rule *types.SimilarityRule, normalizedLaunchCommand string) string {
+	result := normalizedLaunchCommand
+	if rule == nil {
+		return result
+	}
+
+	if rule.StripQueryString {
+		if i := strings.IndexAny(result, "?#"); i >= 0 {
+			result = result[:i]
+		}
+	}
+	if rule.IgnoreHost {
+		result = similarityURLHostRegexp.ReplaceAllString(result, "")
+	}
+	if rule.CaseFold {
+		result = strings.ToLower(result)
+	}
+
+	return result
+}
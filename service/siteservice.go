@@ -11,15 +11,12 @@ import (
 	"io"
 	"io/fs"
 	"io/ioutil"
-	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -34,7 +31,6 @@ import (
 	"github.com/Dri0m/flashpoint-submission-system/notificationbot"
 	"github.com/Dri0m/flashpoint-submission-system/types"
 	"github.com/Dri0m/flashpoint-submission-system/utils"
-	"github.com/agnivade/levenshtein"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gofrs/uuid"
 	"github.com/sirupsen/logrus"
@@ -122,54 +118,98 @@ func MapAuthToken(token *authToken) map[string]string {
 }
 
 type SiteService struct {
-	authBot                   authbot.DiscordRoleReader
-	notificationBot           notificationbot.DiscordNotificationSender
-	dal                       database.DAL
-	validator                 Validator
-	clock                     Clock
-	randomStringProvider      utils.RandomStringer
-	authTokenProvider         AuthTokenizer
-	sessionExpirationSeconds  int64
-	submissionsDir            string
-	submissionImagesDir       string
-	flashfreezeDir            string
-	notificationQueueNotEmpty chan bool
-	isDev                     bool
-	submissionReceiverMutex   sync.Mutex
-	discordRoleCache          *memoize.Memoizer
-	resumableUploadService    *resumableuploadservice.ResumableUploadService
-	archiveIndexerServerURL   string
-	flashfreezeIngestDir      string
-	fixesDir                  string
-	SSK                       SubmissionStatusKeeper
+	authBot                    authbot.DiscordRoleReader
+	notificationBot            notificationbot.DiscordNotificationSender
+	dal                        database.DAL
+	validator                  Validator
+	validationRuleEngine       *ValidationRuleEngine
+	clock                      Clock
+	randomStringProvider       utils.RandomStringer
+	authTokenProvider          AuthTokenizer
+	sessionExpirationSeconds   int64
+	submissionsDir             string
+	submissionImagesDir        string
+	flashfreezeDir             string
+	notificationQueueNotEmpty  chan bool
+	submissionJobQueueNotEmpty chan bool
+	isDev                      bool
+	submissionReceiverMutex    sync.Mutex
+	discordRoleCache           *memoize.Memoizer
+	resumableUploadService     *resumableuploadservice.ResumableUploadService
+	archiveIndexerServerURL    string
+	flashfreezeIngestDir       string
+	fixesDir                   string
+	SSK                        SubmissionStatusKeeper
+	similarityIndex            *similarityIndex
+	assignmentExpiry           types.AssignmentExpiry
+	workloadCaps               types.WorkloadCaps
+	nextSubmissionMutex        sync.Mutex
+	fileRetention              time.Duration
+	scheduledTasks             []*scheduledTask
+	schedulerInstanceID        string
+	operations                 *operationTracker
+	backgroundCtx              context.Context
+	cancelBackground           context.CancelFunc
+	backgroundMutex            sync.Mutex
+	backgroundWG               sync.WaitGroup
 }
 
 func New(l *logrus.Entry, db *sql.DB, authBotSession, notificationBotSession *discordgo.Session,
 	flashpointServerID, notificationChannelID, curationFeedChannelID, validatorServerURL string,
-	sessionExpirationSeconds int64, submissionsDir, submissionImagesDir, flashfreezeDir string, isDev bool, rsu *resumableuploadservice.ResumableUploadService, archiveIndexerServerURL, flashfreezeIngestDir, fixesDir string) *SiteService {
+	sessionExpirationSeconds int64, submissionsDir, submissionImagesDir, flashfreezeDir string, isDev bool, rsu *resumableuploadservice.ResumableUploadService, archiveIndexerServerURL, flashfreezeIngestDir, fixesDir,
+	validatorMode, validatorTagsFilePath, validationRulesFilePath, scheduledTasksFilePath string, approvalQuorums types.ApprovalQuorums, assignmentExpiry types.AssignmentExpiry, workloadCaps types.WorkloadCaps,
+	fileRetention time.Duration) *SiteService {
+
+	dal := database.NewMysqlDAL(db, approvalQuorums)
+
+	validationRules, err := LoadOptionalValidationRules(l.WithField("serviceName", "validationRuleEngine"), validationRulesFilePath)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load validation rules: %s", err.Error()))
+	}
+
+	scheduledTasks, err := LoadScheduledTasks(scheduledTasksFilePath)
+	if err != nil {
+		panic(fmt.Sprintf("failed to load scheduled tasks: %s", err.Error()))
+	}
+
+	validator, err := newValidatorForMode(l.WithField("serviceName", "validator"), validatorMode, validatorServerURL, validatorTagsFilePath)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create the validator: %s", err.Error()))
+	}
+
+	backgroundCtx, cancelBackground := context.WithCancel(context.Background())
 
 	return &SiteService{
-		authBot:                   authbot.NewBot(authBotSession, flashpointServerID, l.WithField("botName", "authBot"), isDev),
-		notificationBot:           notificationbot.NewBot(notificationBotSession, flashpointServerID, notificationChannelID, curationFeedChannelID, l.WithField("botName", "notificationBot"), isDev),
-		dal:                       database.NewMysqlDAL(db),
-		validator:                 NewValidator(validatorServerURL),
-		clock:                     &RealClock{},
-		randomStringProvider:      utils.NewRealRandomStringProvider(),
-		authTokenProvider:         NewAuthTokenProvider(),
-		sessionExpirationSeconds:  sessionExpirationSeconds,
-		submissionsDir:            submissionsDir,
-		submissionImagesDir:       submissionImagesDir,
-		flashfreezeDir:            flashfreezeDir,
-		notificationQueueNotEmpty: make(chan bool, 1),
-		isDev:                     isDev,
-		discordRoleCache:          memoize.NewMemoizer(2*time.Minute, 60*time.Minute),
-		resumableUploadService:    rsu,
-		archiveIndexerServerURL:   archiveIndexerServerURL,
-		flashfreezeIngestDir:      flashfreezeIngestDir,
-		fixesDir:                  fixesDir,
-		SSK: SubmissionStatusKeeper{
-			m: make(map[string]*types.SubmissionStatus),
-		},
+		authBot:                    authbot.NewBot(authBotSession, flashpointServerID, l.WithField("botName", "authBot"), isDev),
+		notificationBot:            notificationbot.NewBot(notificationBotSession, flashpointServerID, notificationChannelID, curationFeedChannelID, l.WithField("botName", "notificationBot"), isDev),
+		dal:                        dal,
+		validator:                  validator,
+		validationRuleEngine:       NewValidationRuleEngine(validationRules),
+		clock:                      &RealClock{},
+		randomStringProvider:       utils.NewRealRandomStringProvider(),
+		authTokenProvider:          NewAuthTokenProvider(),
+		sessionExpirationSeconds:   sessionExpirationSeconds,
+		submissionsDir:             submissionsDir,
+		submissionImagesDir:        submissionImagesDir,
+		flashfreezeDir:             flashfreezeDir,
+		notificationQueueNotEmpty:  make(chan bool, 1),
+		submissionJobQueueNotEmpty: make(chan bool, 1),
+		isDev:                      isDev,
+		discordRoleCache:           memoize.NewMemoizer(2*time.Minute, 60*time.Minute),
+		resumableUploadService:     rsu,
+		archiveIndexerServerURL:    archiveIndexerServerURL,
+		flashfreezeIngestDir:       flashfreezeIngestDir,
+		fixesDir:                   fixesDir,
+		SSK:                        NewSubmissionStatusKeeper(dal),
+		similarityIndex:            newSimilarityIndex(),
+		assignmentExpiry:           assignmentExpiry,
+		workloadCaps:               workloadCaps,
+		fileRetention:              fileRetention,
+		scheduledTasks:             scheduledTasks,
+		schedulerInstanceID:        newSchedulerInstanceID(),
+		operations:                 newOperationTracker(),
+		backgroundCtx:              backgroundCtx,
+		cancelBackground:           cancelBackground,
 	}
 }
 
@@ -250,6 +290,21 @@ func (s *SiteService) GetViewSubmissionPageData(ctx context.Context, uid, sid in
 		return nil, dberr(err)
 	}
 
+	commentRevisions, err := s.dal.GetCommentRevisionsBySubmissionID(dbs, sid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	attachCommentRevisions(comments, commentRevisions)
+
+	commentImages, err := s.dal.GetCommentImagesBySubmissionID(dbs, sid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	attachCommentImages(comments, commentImages)
+	comments = threadComments(comments)
+
 	isUserSubscribed, err := s.dal.IsUserSubscribedToSubmission(dbs, uid, sid)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
@@ -262,6 +317,12 @@ func (s *SiteService) GetViewSubmissionPageData(ctx context.Context, uid, sid in
 		return nil, dberr(err)
 	}
 
+	validationResults, err := s.dal.GetValidationRuleResultsBySubmissionFileID(dbs, submission.FileID)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
 	ciids := make([]int64, 0, len(curationImages))
 
 	for _, curationImage := range curationImages {
@@ -308,12 +369,15 @@ func (s *SiteService) GetViewSubmissionPageData(ctx context.Context, uid, sid in
 		NextSubmissionID:     nextSID,
 		PreviousSubmissionID: prevSID,
 		TagList:              tagList,
+		ValidationResults:    validationResults,
 	}
 
 	return pageData, nil
 }
 
-func (s *SiteService) GetSubmissionsFilesPageData(ctx context.Context, sid int64) (*types.SubmissionsFilesPageData, error) {
+// GetSubmissionsFilesPageData returns files of a submission, and a curation meta diff between the given files,
+// or between the two newest files if fromFID and toFID are not provided
+func (s *SiteService) GetSubmissionsFilesPageData(ctx context.Context, sid int64, fromFID, toFID *int64) (*types.SubmissionsFilesPageData, error) {
 	dbs, err := s.dal.NewSession(ctx)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
@@ -332,9 +396,35 @@ func (s *SiteService) GetSubmissionsFilesPageData(ctx context.Context, sid int64
 		return nil, dberr(err)
 	}
 
+	if fromFID == nil && toFID == nil && len(sf) >= 2 {
+		fromFID = &sf[1].FileID
+		toFID = &sf[0].FileID
+	}
+
+	var metaDiff *types.CurationMetaDiff
+	if fromFID != nil && toFID != nil {
+		metaDiff, err = s.getCurationMetaDiff(dbs, sid, *fromFID, *toFID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var contentDiff *types.ArchiveContentDiff
+	if fromFID != nil && toFID != nil {
+		contentDiff, err = s.getArchiveContentDiff(dbs, sid, *fromFID, *toFID)
+		if err != nil {
+			// files which are not indexed yet are not worth failing the whole page
+			if pe, ok := err.(constants.PublicError); !ok || pe.Status != http.StatusConflict {
+				return nil, err
+			}
+		}
+	}
+
 	pageData := &types.SubmissionsFilesPageData{
 		BasePageData:    *bpd,
 		SubmissionFiles: sf,
+		MetaDiff:        metaDiff,
+		ContentDiff:     contentDiff,
 	}
 
 	return pageData, nil
@@ -437,7 +527,7 @@ func (s *SiteService) SoftDeleteSubmissionFile(ctx context.Context, sfid int64,
 	authorID := sfs[0].SubmitterID
 	sid := sfs[0].SubmissionID
 
-	if err := s.dal.SoftDeleteSubmissionFile(dbs, sfid, deleteReason); err != nil {
+	if err := s.dal.SoftDeleteSubmissionFile(dbs, sfid, deleteReason, uid); err != nil {
 		if err.Error() == constants.ErrorCannotDeleteLastSubmissionFile {
 			return perr(err.Error(), http.StatusBadRequest)
 		}
@@ -450,6 +540,12 @@ func (s *SiteService) SoftDeleteSubmissionFile(ctx context.Context, sfid int64,
 		return dberr(err)
 	}
 
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionSoftDeleteSubmissionFile, constants.AuditTargetSubmissionFile, &sfid,
+		sfs[0], map[string]string{"deleted_reason": deleteReason}); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
 	if err := dbs.Commit(); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return dberr(err)
@@ -478,7 +574,7 @@ func (s *SiteService) SoftDeleteSubmission(ctx context.Context, sid int64, delet
 
 	authorID := submissions[0].SubmitterID
 
-	if err := s.dal.SoftDeleteSubmission(dbs, sid, deleteReason); err != nil {
+	if err := s.dal.SoftDeleteSubmission(dbs, sid, deleteReason, uid, s.clock.Now()); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return dberr(err)
 	}
@@ -488,11 +584,18 @@ func (s *SiteService) SoftDeleteSubmission(ctx context.Context, sid int64, delet
 		return dberr(err)
 	}
 
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionSoftDeleteSubmission, constants.AuditTargetSubmission, &sid,
+		submissions[0], map[string]string{"deleted_reason": deleteReason}); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
 	if err := dbs.Commit(); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return dberr(err)
 	}
 
+	s.similarityIndex.remove(strconv.FormatInt(sid, 10))
 	s.announceNotification()
 
 	return nil
@@ -520,7 +623,7 @@ func (s *SiteService) SoftDeleteComment(ctx context.Context, cid int64, deleteRe
 		return err
 	}
 
-	if err := s.dal.SoftDeleteComment(dbs, cid, deleteReason); err != nil {
+	if err := s.dal.SoftDeleteComment(dbs, cid, deleteReason, uid); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return dberr(err)
 	}
@@ -530,6 +633,12 @@ func (s *SiteService) SoftDeleteComment(ctx context.Context, cid int64, deleteRe
 		return dberr(err)
 	}
 
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionSoftDeleteComment, constants.AuditTargetComment, &cid,
+		c, map[string]string{"deleted_reason": deleteReason}); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
 	if err := dbs.Commit(); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return dberr(err)
@@ -550,6 +659,15 @@ func (s *SiteService) OverrideBot(ctx context.Context, sid int64) error {
 	}
 	defer dbs.Rollback()
 
+	before, _, err := s.dal.SearchSubmissions(dbs, &types.SubmissionsFilter{SubmissionIDs: []int64{sid}})
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	if len(before) == 0 {
+		return perr(fmt.Sprintf("submission %d not found", sid), http.StatusNotFound)
+	}
+
 	msg := fmt.Sprintf("Approval override by user %d", uid)
 
 	c := &types.Comment{
@@ -570,6 +688,17 @@ func (s *SiteService) OverrideBot(ctx context.Context, sid int64) error {
 		return dberr(err)
 	}
 
+	after, _, err := s.dal.SearchSubmissions(dbs, &types.SubmissionsFilter{SubmissionIDs: []int64{sid}})
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionOverrideBot, constants.AuditTargetSubmission, &sid, before[0], after[0]); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
 	if err := dbs.Commit(); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return dberr(err)
@@ -953,6 +1082,9 @@ func (s *SiteService) UpdateMasterDB(ctx context.Context) error {
 		games = append(games, g)
 	}
 
+	op := operationFromContext(ctx)
+	op.setTotal(int64(len(games)))
+
 	dbs, err := s.dal.NewSession(ctx)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
@@ -974,12 +1106,17 @@ func (s *SiteService) UpdateMasterDB(ctx context.Context) error {
 		batch = append(batch, g)
 
 		if i%1000 == 0 || i == len(games)-1 {
+			// cancelling rolls back the whole update, the old masterdb stays in place
+			if err := ctx.Err(); err != nil {
+				return err
+			}
 			utils.LogCtx(ctx).Debug("inserting masterdb batch into fpfssdb")
 			err = s.dal.StoreMasterDBGames(dbs, batch)
 			if err != nil {
 				utils.LogCtx(ctx).Error(err)
 				return dberr(err)
 			}
+			op.advance(int64(len(batch)))
 			batch = make([]*types.MasterDatabaseGame, 0, 1000)
 		}
 	}
@@ -989,92 +1126,30 @@ func (s *SiteService) UpdateMasterDB(ctx context.Context) error {
 		return dberr(err)
 	}
 
+	utils.LogCtx(ctx).Debug("rebuilding similarity index")
+	if err := s.rebuildSimilarityIndexFromDB(ctx); err != nil {
+		return err
+	}
+
 	utils.LogCtx(ctx).Debug("masterdb update finished")
 	return nil
 }
 
-func (s *SiteService) getSimilarityScores(dbs database.DBSession, minimumMatch float64, title, launchCommand *string) ([]*types.SimilarityAttributes, []*types.SimilarityAttributes, error) {
+func (s *SiteService) getSimilarityScores(dbs database.DBSession, defaultMinimumMatch float64, title, launchCommand *string) ([]*types.SimilarityAttributes, []*types.SimilarityAttributes, error) {
 	ctx := dbs.Ctx()
 	start := time.Now()
 
-	// TODO refactor this into something more proper
-	// change minimum match for some specific launch commands
-	if launchCommand != nil {
-		// itch.io games
-		if strings.Contains(*launchCommand, "ssl.hwcdn.net/html") {
-			minimumMatch = 0.95
-		}
-	}
-
-	sas, err := s.dal.GetAllSimilarityAttributes(dbs)
-	if err != nil {
-		utils.LogCtx(ctx).Error(err)
-		return nil, nil, dberr(err)
-	}
-
-	byTitle := make([]*types.SimilarityAttributes, 0)
-	byLaunchCommand := make([]*types.SimilarityAttributes, 0)
-
-	if len(sas) < 2 {
-		return byTitle, byLaunchCommand, nil
-	}
-
-	normalize := func(s string) string {
-		return strings.ReplaceAll(
-			strings.ReplaceAll(
-				strings.ReplaceAll(
-					strings.ReplaceAll(
-						s, "`", ""),
-					" ", ""),
-				"'", ""),
-			`"`, "")
-	}
-
-	var nt string
-	if title != nil {
-		nt = normalize(*title)
-	}
-
-	var nlc string
-	if launchCommand != nil {
-		nlc = normalize(*launchCommand)
-	}
-
-	for _, sa := range sas {
-		if title != nil && sa.Title != nil {
-			nc := normalize(*sa.Title)
-			distance := levenshtein.ComputeDistance(nt, nc)
-			matchRatio := 1 - (float64(distance) / math.Max(float64(len(nt)), float64(len(nc))))
-
-			if matchRatio > minimumMatch {
-				sa.TitleRatio = matchRatio
-				byTitle = append(byTitle, sa)
-			}
-		}
-		if launchCommand != nil && sa.LaunchCommand != nil {
-			nc := normalize(*sa.LaunchCommand)
-			distance := levenshtein.ComputeDistance(nlc, nc)
-			matchRatio := 1 - (float64(distance) / math.Max(float64(len(nlc)), float64(len(nc))))
-
-			if matchRatio > minimumMatch {
-				sa.LaunchCommandRatio = matchRatio
-				byLaunchCommand = append(byLaunchCommand, sa)
-			}
-		}
+	if err := s.ensureSimilarityIndex(dbs); err != nil {
+		return nil, nil, err
 	}
 
-	sort.Slice(byTitle, func(i, j int) bool {
-		return byTitle[i].TitleRatio > byTitle[j].TitleRatio
-	})
-
-	sort.Slice(byLaunchCommand, func(i, j int) bool {
-		return byLaunchCommand[i].LaunchCommandRatio > byLaunchCommand[j].LaunchCommandRatio
-	})
+	// minimum match and normalization for some specific launch commands are given by the similarity rules
+	result := s.similarityIndex.search(title, launchCommand, defaultMinimumMatch)
 
 	duration := time.Since(start)
 	utils.LogCtx(ctx).WithField("duration_ns", duration.Nanoseconds()).Debug("similarity scores calculated")
 
-	return byTitle, byLaunchCommand, nil
+	return result.TitleMatches, result.LaunchCommandMatches, nil
 }
 
 func isFlasfhreezeExtensionValid(filename string) (bool, string) {
@@ -1222,7 +1297,7 @@ func (s *SiteService) GetFlashfreezeRootFile(ctx context.Context, fid int64) (*t
 	return ci, nil
 }
 
-func (s *SiteService) processReceivedResumableSubmission(ctx context.Context, uid int64, sid *int64, resumableParams *types.ResumableParams, tempName string) error {
+func (s *SiteService) processReceivedResumableSubmission(ctx context.Context, uid int64, sid *int64, resumableParams *types.ResumableParams, tempName string) (int64, error) {
 	var destinationFilename *string
 	imageFilePaths := make([]string, 0)
 
@@ -1244,22 +1319,22 @@ func (s *SiteService) processReceivedResumableSubmission(ctx context.Context, ui
 	dbs, err := s.dal.NewSession(ctx)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return 0, dberr(err)
 	}
 	defer dbs.Rollback()
 
 	userRoles, err := s.dal.GetDiscordUserRoles(dbs, uid)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return 0, dberr(err)
 	}
 
 	if constants.IsInAudit(userRoles) && resumableParams.ResumableTotalSize > constants.UserInAuditSubmissionMaxFilesize {
 		msg := "submission filesize limited to 500MB for users in audit"
-		s.SSK.SetFailed(tempName, msg)
-		return perr(msg, http.StatusForbidden)
+		s.SSK.SetFailed(ctx, tempName, msg)
+		return 0, perr(msg, http.StatusForbidden)
 	}
 
 	var submissionLevel string
@@ -1272,29 +1347,58 @@ func (s *SiteService) processReceivedResumableSubmission(ctx context.Context, ui
 		submissionLevel = constants.SubmissionLevelStaff
 	}
 
+	if sid != nil {
+		submissions, _, err := s.dal.SearchSubmissions(dbs, &types.SubmissionsFilter{SubmissionIDs: []int64{*sid}})
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			s.SSK.SetFailed(ctx, tempName, "internal error")
+			return 0, dberr(err)
+		}
+		if len(submissions) == 0 {
+			msg := fmt.Sprintf("submission %d not found", *sid)
+			s.SSK.SetFailed(ctx, tempName, msg)
+			return 0, perr(msg, http.StatusNotFound)
+		}
+		if err := isTransitionValidForSubmission(constants.ActionUpload, submissions[0]); err != nil {
+			s.SSK.SetFailed(ctx, tempName, err.Error())
+			return 0, err
+		}
+	}
+
 	ru := newResumableUpload(uid, resumableParams.ResumableIdentifier, resumableParams.ResumableTotalChunks, s.resumableUploadService)
-	destinationFilename, ifp, submissionID, err := s.processReceivedSubmission(ctx, dbs, ru, resumableParams.ResumableFilename, resumableParams.ResumableTotalSize, sid, submissionLevel, tempName)
+	destinationFilename, ifp, submissionID, fid, err := s.processReceivedSubmission(ctx, dbs, ru, resumableParams.ResumableFilename, resumableParams.ResumableTotalSize, sid, submissionLevel, tempName)
 
 	imageFilePaths = append(imageFilePaths, ifp...)
 
 	if err != nil {
 		cleanup()
-		return err
+		return 0, err
 	}
 
 	if err := dbs.Commit(); err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
+		s.SSK.SetFailed(ctx, tempName, "internal error")
 		cleanup()
-		return dberr(err)
+		return 0, dberr(err)
 	}
 
 	utils.LogCtx(ctx).WithField("amount", 1).Debug("submissions received")
 	s.announceNotification()
 
-	s.SSK.SetSuccess(tempName, submissionID)
+	s.updateSimilarityIndex(ctx, submissionID, fid)
 
-	return nil
+	l := utils.LogCtx(ctx).WithFields(logrus.Fields{"submissionFileID": fid, "destinationFilePath": *destinationFilename})
+	// a file which is not indexed because of a shutdown is picked up by IndexUnindexedSubmissionFiles
+	err = s.goBackground(func(bctx context.Context) {
+		s.indexReceivedSubmissionFile(context.WithValue(bctx, utils.CtxKeys.Log, l), fid, *destinationFilename)
+	})
+	if err != nil {
+		l.WithError(err).Warn("submission file not indexed, shutting down")
+	}
+
+	s.SSK.SetSuccess(ctx, tempName)
+
+	return submissionID, nil
 }
 
 func (s *SiteService) processReceivedResumableFlashfreeze(ctx context.Context, uid int64, resumableParams *types.ResumableParams) (*int64, error) {
@@ -1411,109 +1515,136 @@ func provideArchiveForIndexing(filePath string, baseUrl string) ([]*types.Indexe
 	return ir.Files, ir.IndexingErrors, nil
 }
 
-func (s *SiteService) ingestGivenFlashfreezeItems(l *logrus.Entry, files []fs.FileInfo, rootDir string) {
+// ingestGivenFlashfreezeItems ingests the files and waits until all of them are done,
+// no more files are started once the context is cancelled
+func (s *SiteService) ingestGivenFlashfreezeItems(ctx context.Context, l *logrus.Entry, files []fs.FileInfo, rootDir string) error {
+	op := operationFromContext(ctx)
+	op.setTotal(int64(len(files)))
+
 	guard := make(chan struct{}, 3)
+	wg := sync.WaitGroup{}
 
 	mutex := sync.Mutex{}
+	failedMutex := sync.Mutex{}
+	failed := 0
 
 	for _, fileInfo := range files {
-		guard <- struct{}{}
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case guard <- struct{}{}:
+		}
+		wg.Add(1)
 		fileInfo := fileInfo
 		go func() {
+			defer wg.Done()
+			defer op.advance(1)
 			defer func() { <-guard }()
 			if fileInfo.IsDir() {
 				return
 			}
+			// a file which is already being ingested is finished even when the operation is cancelled
 			ctx := context.WithValue(context.Background(), utils.CtxKeys.Log, l.WithField("filename", fileInfo.Name()))
-			fullFilepath := rootDir + "/" + fileInfo.Name()
-
-			ok, ext := isFlasfhreezeExtensionValid(fileInfo.Name())
-			if !ok {
-				utils.LogCtx(ctx).Warn("unsupported file extension")
-				return
+			if err := s.ingestFlashfreezeItem(ctx, &mutex, fileInfo, rootDir); err != nil {
+				utils.LogCtx(ctx).Error(err)
+				failedMutex.Lock()
+				failed++
+				failedMutex.Unlock()
 			}
+		}()
+	}
 
-			var destinationFilename string
-			var destinationFilePath string
+	wg.Wait()
 
-			for {
-				destinationFilename = s.randomStringProvider.RandomString(64) + ext
-				destinationFilePath = fmt.Sprintf("%s/%s", s.flashfreezeDir, destinationFilename)
-				if !utils.FileExists(destinationFilePath) {
-					break
-				}
-			}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d flashfreeze items failed to be ingested", failed, len(files))
+	}
+	return nil
+}
 
-			md5sum := md5.New()
-			sha256sum := sha256.New()
-			multiWriter := io.MultiWriter(sha256sum, md5sum)
+// ingestFlashfreezeItem moves the file into the flashfreeze directory and stores it, the mutex serializes the database work
+func (s *SiteService) ingestFlashfreezeItem(ctx context.Context, mutex *sync.Mutex, fileInfo fs.FileInfo, rootDir string) error {
+	fullFilepath := rootDir + "/" + fileInfo.Name()
 
-			f, err := os.Open(fullFilepath)
-			if err != nil {
-				utils.LogCtx(ctx).Error(err)
-				return
-			}
+	ok, ext := isFlasfhreezeExtensionValid(fileInfo.Name())
+	if !ok {
+		utils.LogCtx(ctx).Warn("unsupported file extension")
+		return nil
+	}
 
-			utils.LogCtx(ctx).Debug("computing checksums...")
-			nBytes, err := io.Copy(multiWriter, f)
-			if err != nil {
-				utils.LogCtx(ctx).Error(err)
-				return
-			}
-			if nBytes != fileInfo.Size() {
-				err := fmt.Errorf("incorrect number of bytes copied to destination")
-				utils.LogCtx(ctx).Error(err)
-				return
-			}
+	var destinationFilename string
+	var destinationFilePath string
 
-			mutex.Lock()
-			defer mutex.Unlock()
-			dbs, err := s.dal.NewSession(ctx)
-			if err != nil {
-				utils.LogCtx(ctx).Error(err)
-				return
-			}
-			defer dbs.Rollback()
+	for {
+		destinationFilename = s.randomStringProvider.RandomString(64) + ext
+		destinationFilePath = fmt.Sprintf("%s/%s", s.flashfreezeDir, destinationFilename)
+		if !utils.FileExists(destinationFilePath) {
+			break
+		}
+	}
 
-			sf := &types.FlashfreezeFile{
-				UserID:           constants.SystemID,
-				OriginalFilename: fileInfo.Name(),
-				CurrentFilename:  destinationFilename,
-				Size:             fileInfo.Size(),
-				UploadedAt:       s.clock.Now(),
-				MD5Sum:           hex.EncodeToString(md5sum.Sum(nil)),
-				SHA256Sum:        hex.EncodeToString(sha256sum.Sum(nil)),
-			}
+	md5sum := md5.New()
+	sha256sum := sha256.New()
+	multiWriter := io.MultiWriter(sha256sum, md5sum)
 
-			fid, err := s.dal.StoreFlashfreezeRootFile(dbs, sf)
-			if err != nil {
-				me, ok := err.(*mysql.MySQLError)
-				if ok {
-					if me.Number == 1062 {
-						err := fmt.Errorf("file '%s' with checksums md5:%s sha256:%s already present in the DB", fileInfo.Name(), sf.MD5Sum, sf.SHA256Sum)
-						utils.LogCtx(ctx).Error(err)
-						return
-					}
-				}
-				utils.LogCtx(ctx).Error(err)
-				return
-			}
+	f, err := os.Open(fullFilepath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
 
-			if err := os.Rename(fullFilepath, destinationFilePath); err != nil {
-				utils.LogCtx(ctx).Error(err)
-				return
-			}
+	utils.LogCtx(ctx).Debug("computing checksums...")
+	nBytes, err := io.Copy(multiWriter, f)
+	if err != nil {
+		return err
+	}
+	if nBytes != fileInfo.Size() {
+		return fmt.Errorf("incorrect number of bytes copied to destination")
+	}
 
-			if err := dbs.Commit(); err != nil {
-				utils.LogCtx(ctx).Error(err)
-				return
+	mutex.Lock()
+	defer mutex.Unlock()
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer dbs.Rollback()
+
+	sf := &types.FlashfreezeFile{
+		UserID:           constants.SystemID,
+		OriginalFilename: fileInfo.Name(),
+		CurrentFilename:  destinationFilename,
+		Size:             fileInfo.Size(),
+		UploadedAt:       s.clock.Now(),
+		MD5Sum:           hex.EncodeToString(md5sum.Sum(nil)),
+		SHA256Sum:        hex.EncodeToString(sha256sum.Sum(nil)),
+	}
+
+	fid, err := s.dal.StoreFlashfreezeRootFile(dbs, sf)
+	if err != nil {
+		me, ok := err.(*mysql.MySQLError)
+		if ok {
+			if me.Number == 1062 {
+				return fmt.Errorf("file '%s' with checksums md5:%s sha256:%s already present in the DB", fileInfo.Name(), sf.MD5Sum, sf.SHA256Sum)
 			}
+		}
+		return err
+	}
 
-			utils.LogCtx(ctx).WithField("amount", 1).Debug("flashfreeze items received")
-			l := utils.LogCtx(ctx).WithFields(logrus.Fields{"flashfreezeFileID": fid, "destinationFilepath": destinationFilePath})
-			s.indexReceivedFlashfreezeFile(l, fid, destinationFilePath)
-		}()
+	if err := os.Rename(fullFilepath, destinationFilePath); err != nil {
+		return err
 	}
+
+	if err := dbs.Commit(); err != nil {
+		return err
+	}
+
+	utils.LogCtx(ctx).WithField("amount", 1).Debug("flashfreeze items received")
+	l := utils.LogCtx(ctx).WithFields(logrus.Fields{"flashfreezeFileID": fid, "destinationFilepath": destinationFilePath})
+	s.indexReceivedFlashfreezeFile(l, fid, destinationFilePath)
+
+	return nil
 }
 
 func (s *SiteService) IngestFlashfreezeItems(l *logrus.Entry) {
@@ -1525,31 +1656,33 @@ func (s *SiteService) IngestFlashfreezeItems(l *logrus.Entry) {
 		return
 	}
 
-	s.ingestGivenFlashfreezeItems(l, files, s.flashfreezeIngestDir)
+	if err := s.ingestGivenFlashfreezeItems(context.Background(), l, files, s.flashfreezeIngestDir); err != nil {
+		l.Error(err)
+	}
 }
 
-func (s *SiteService) IngestUnknownFlashfreezeItems(l *logrus.Entry) {
-	ctx := context.WithValue(context.Background(), utils.CtxKeys.Log, l)
+func (s *SiteService) IngestUnknownFlashfreezeItems(ctx context.Context) error {
+	l := utils.LogCtx(ctx)
 
 	utils.LogCtx(ctx).WithField("directory", s.flashfreezeDir).Debug("listing directory")
 
 	allFiles, err := ioutil.ReadDir(s.flashfreezeDir)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		return
+		return err
 	}
 
 	dbs, err := s.dal.NewSession(ctx)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		return
+		return err
 	}
 	defer dbs.Rollback()
 
 	ingestedFiles, err := s.dal.GetAllFlashfreezeRootFiles(dbs)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		return
+		return err
 	}
 
 	files := make([]fs.FileInfo, 0, len(allFiles)-len(ingestedFiles))
@@ -1573,30 +1706,43 @@ func (s *SiteService) IngestUnknownFlashfreezeItems(l *logrus.Entry) {
 
 	if len(files) == 0 {
 		utils.LogCtx(ctx).Debug("found no unknown flashfreeze items")
-		return
+		return nil
 	}
 
 	utils.LogCtx(ctx).WithField("unknownFlashfreezeItems", len(files)).Debug("found some unknown flashfreeze items")
-	s.ingestGivenFlashfreezeItems(l, files, s.flashfreezeDir)
+	return s.ingestGivenFlashfreezeItems(ctx, l, files, s.flashfreezeDir)
 }
 
-func (s *SiteService) RecomputeSubmissionCacheAll(ctx context.Context) {
+func (s *SiteService) RecomputeSubmissionCacheAll(ctx context.Context) error {
+	op := operationFromContext(ctx)
 
 	var perPage int64 = 10000
+	var page int64 = 1
 	var count int64 = 1
 	var recomputedCount int64 = 0
+	// ordered by the upload time and then by the ID, so that paging does not skip or repeat any submission
+	orderBy := "uploaded"
+	ascDesc := "asc"
 
 	for recomputedCount < count {
 		var submissions []*types.ExtendedSubmission
 		var err error
-		submissions, count, err = s.SearchSubmissions(ctx, &types.SubmissionsFilter{ResultsPerPage: &perPage, ExcludeLegacy: true})
+		submissions, count, err = s.SearchSubmissions(ctx, &types.SubmissionsFilter{ResultsPerPage: &perPage, Page: &page, OrderBy: &orderBy, AscDesc: &ascDesc, ExcludeLegacy: true})
 		if err != nil {
 			utils.LogCtx(ctx).Error(err)
-			return
+			return err
+		}
+		if len(submissions) == 0 {
+			break
 		}
+		op.setTotal(count)
 		utils.LogCtx(ctx).WithField("perPage", perPage).WithField("recomputedCount", recomputedCount).WithField("totalSubmissions", count).Debug("processing a page of submissions")
 
 		for _, submission := range submissions {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			func() {
 				utils.LogCtx(ctx).WithField("submissionID", submission.SubmissionID).Debug("recomputing cache for submission")
 
@@ -1618,26 +1764,31 @@ func (s *SiteService) RecomputeSubmissionCacheAll(ctx context.Context) {
 					return
 				}
 			}()
+
+			op.advance(1)
 		}
 
-		recomputedCount += count
+		recomputedCount += int64(len(submissions))
+		page++
 	}
+
+	return nil
 }
 
-func (s *SiteService) IndexUnindexedFlashfreezeItems(l *logrus.Entry) {
+func (s *SiteService) IndexUnindexedFlashfreezeItems(l *logrus.Entry) error {
 	ctx := context.WithValue(context.Background(), utils.CtxKeys.Log, l)
 
 	dbs, err := s.dal.NewSession(ctx)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		return
+		return err
 	}
 	defer dbs.Rollback()
 
 	unindexedFiles, err := s.dal.GetAllUnindexedFlashfreezeRootFiles(dbs)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		return
+		return err
 	}
 
 	if len(unindexedFiles) == 0 {
@@ -1650,6 +1801,8 @@ func (s *SiteService) IndexUnindexedFlashfreezeItems(l *logrus.Entry) {
 		destinationFilePath := s.flashfreezeDir + "/" + unindexedFile.CurrentFilename
 		s.indexReceivedFlashfreezeFile(l, unindexedFile.ID, destinationFilePath)
 	}
+
+	return nil
 }
 
 func (s *SiteService) GetFixByID(ctx context.Context, fid int64) (*types.Fix, error) {
@@ -1682,6 +1835,12 @@ func (s *SiteService) DeleteUserSessions(ctx context.Context, uid int64) (int64,
 		return 0, dberr(err)
 	}
 
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionDeleteUserSessions, constants.AuditTargetUser, &uid,
+		nil, map[string]int64{"deleted_sessions": count}); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, dberr(err)
+	}
+
 	if err := dbs.Commit(); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return 0, dberr(err)
@@ -1712,6 +1871,8 @@ func (s *SiteService) GetStatisticsPageData(ctx context.Context) (*types.Statist
 	var fffc int64
 	var tss int64
 	var tffs int64
+	var pfc int64
+	var trs int64
 
 	errs.Go(func() error {
 		dbs, _ := s.dal.NewSession(ectx)
@@ -1819,6 +1980,14 @@ func (s *SiteService) GetStatisticsPageData(ctx context.Context) (*types.Statist
 		return err
 	})
 
+	errs.Go(func() error {
+		dbs, _ := s.dal.NewSession(ectx)
+		defer dbs.Rollback()
+		var err error
+		pfc, trs, err = s.dal.GetFilePurgeTotals(dbs)
+		return err
+	})
+
 	if err := errs.Wait(); err != nil {
 		utils.LogCtx(ctx).Error(err)
 		return nil, err
@@ -1839,6 +2008,8 @@ func (s *SiteService) GetStatisticsPageData(ctx context.Context) (*types.Statist
 		FlashfreezeFileCount:        fffc,
 		TotalSubmissionSize:         tss,
 		TotalFlashfreezeSize:        tffs,
+		PurgedFileCount:             pfc,
+		TotalReclaimedSize:          trs,
 	}
 	return pageData, nil
 }
@@ -7,10 +7,12 @@ import (
 	"github.com/Dri0m/flashpoint-submission-system/types"
 	"github.com/Dri0m/flashpoint-submission-system/utils"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 )
 
-func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int64, formAction, formMessage, formIgnoreDupeActions string) error {
+func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int64, formAction, formMessage, formIgnoreDupeActions string, parentCommentID *int64, images [][]byte) error {
 	dbs, err := s.dal.NewSession(ctx)
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
@@ -18,6 +20,15 @@ func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int
 	}
 	defer dbs.Rollback()
 
+	// the limit applies to assigning by hand as well, not just to the next submission button
+	var assignedTestingCount, workloadCap int64
+	if formAction == constants.ActionAssignTesting {
+		assignedTestingCount, workloadCap, err = s.lockTestingWorkload(ctx, dbs, uid)
+		if err != nil {
+			return err
+		}
+	}
+
 	var message *string
 	if formMessage != "" {
 		message = &formMessage
@@ -62,6 +73,60 @@ func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int
 	if formAction == constants.ActionReject && len(sids) > 1 {
 		return perr("cannot reject multiple submissions at once", http.StatusBadRequest)
 	}
+	if parentCommentID != nil && len(sids) > 1 {
+		return perr("cannot reply to a comment on multiple submissions at once", http.StatusBadRequest)
+	}
+	if len(images) > 0 && len(sids) > 1 {
+		return perr("cannot attach images to comments on multiple submissions at once", http.StatusBadRequest)
+	}
+
+	images, err = convertCommentImages(images)
+	if err != nil {
+		return err
+	}
+
+	var imageFilePaths []string
+	committed := false
+	defer func() {
+		if !committed {
+			removeFiles(ctx, imageFilePaths)
+		}
+	}()
+
+	if parentCommentID != nil {
+		comments, err := s.dal.GetExtendedCommentsBySubmissionID(dbs, sids[0])
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return dberr(err)
+		}
+		found := false
+		for _, c := range comments {
+			if c.CommentID == *parentCommentID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return perr(fmt.Sprintf("comment %d not found on submission %d", *parentCommentID, sids[0]), http.StatusNotFound)
+		}
+	}
+
+	// assigns and unassigns have their messages cleared, so they don't mention anyone
+	var mentionedUserIDs []int64
+	if message != nil &&
+		formAction != constants.ActionAssignTesting &&
+		formAction != constants.ActionUnassignTesting &&
+		formAction != constants.ActionAssignVerification &&
+		formAction != constants.ActionUnassignVerification {
+		users, err := s.dal.GetUserIDsByUsernames(dbs, parseMentions(*message))
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return dberr(err)
+		}
+		for _, userID := range users {
+			mentionedUserIDs = append(mentionedUserIDs, userID)
+		}
+	}
 
 	utils.LogCtx(ctx).Debugf("searching submissions for comment batch")
 	foundSubmissions, _, err := s.dal.SearchSubmissions(dbs, &types.SubmissionsFilter{SubmissionIDs: sids})
@@ -96,13 +161,21 @@ func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int
 			return err
 		}
 
+		if formAction == constants.ActionAssignTesting {
+			if workloadCap > 0 && assignedTestingCount >= workloadCap {
+				return workloadCapReachedError(assignedTestingCount, workloadCap)
+			}
+			assignedTestingCount++
+		}
+
 		// actually store the comment
 		c := &types.Comment{
-			AuthorID:     uid,
-			SubmissionID: sid,
-			Message:      message,
-			Action:       formAction,
-			CreatedAt:    s.clock.Now(),
+			AuthorID:        uid,
+			SubmissionID:    sid,
+			Message:         message,
+			Action:          formAction,
+			CreatedAt:       s.clock.Now(),
+			ParentCommentID: parentCommentID,
 		}
 
 		// clear messages for assigns and unassigns
@@ -121,7 +194,8 @@ func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int
 			formAction == constants.ActionApprove ||
 			formAction == constants.ActionRequestChanges ||
 			formAction == constants.ActionVerify ||
-			formAction == constants.ActionReject {
+			formAction == constants.ActionReject ||
+			formAction == constants.ActionReopen {
 
 			subscribed, err := s.dal.IsUserSubscribedToSubmission(dbs, uid, sid)
 			if err != nil {
@@ -141,6 +215,13 @@ func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int
 			return dberr(err)
 		}
 
+		written, err := s.storeCommentImages(dbs, c.ID, images)
+		imageFilePaths = append(imageFilePaths, written...)
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return dberr(err)
+		}
+
 		// unassign if needed
 		if formAction == constants.ActionApprove {
 			c = &types.Comment{
@@ -170,7 +251,7 @@ func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int
 			}
 		}
 
-		if err := s.createNotification(dbs, uid, sid, formAction); err != nil {
+		if err := s.createNotification(dbs, uid, sid, formAction, mentionedUserIDs); err != nil {
 			utils.LogCtx(ctx).Error(err)
 			return dberr(err)
 		}
@@ -187,6 +268,7 @@ func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int
 		utils.LogCtx(ctx).Error(err)
 		return dberr(err)
 	}
+	committed = true
 
 	utils.LogCtx(ctx).WithField("amount", commentCounter).WithField("commentAction", formAction).Debug("comments received")
 
@@ -194,3 +276,140 @@ func (s *SiteService) ReceiveComments(ctx context.Context, uid int64, sids []int
 
 	return nil
 }
+
+// EditComment changes the message of the user's comment, the previous message is kept as a revision and no notifications are sent
+func (s *SiteService) EditComment(ctx context.Context, uid, sid, cid int64, formMessage string) error {
+	var message *string
+	if formMessage != "" {
+		message = &formMessage
+	}
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	submissions, _, err := s.dal.SearchSubmissions(dbs, &types.SubmissionsFilter{SubmissionIDs: []int64{sid}})
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	if len(submissions) == 0 {
+		return perr(fmt.Sprintf("submission %d not found", sid), http.StatusNotFound)
+	}
+
+	comments, err := s.dal.GetExtendedCommentsBySubmissionID(dbs, sid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	var comment *types.ExtendedComment
+	for _, c := range comments {
+		if c.CommentID == cid {
+			comment = c
+			break
+		}
+	}
+	if comment == nil {
+		return perr(fmt.Sprintf("comment %d not found on submission %d", cid, sid), http.StatusNotFound)
+	}
+
+	if err := isCommentEditable(uid, comment, submissions[0], message); err != nil {
+		return err
+	}
+
+	if err := s.dal.UpdateCommentMessage(dbs, cid, message, s.clock.Now()); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	utils.LogCtx(ctx).WithField("cid", cid).Debug("comment edited")
+
+	return nil
+}
+
+// attachCommentRevisions adds the previous versions of edited comments, along with the changes made by their last edit
+func attachCommentRevisions(comments []*types.ExtendedComment, revisions []*types.CommentRevision) {
+	byComment := make(map[int64][]*types.CommentRevision)
+	for _, r := range revisions {
+		byComment[r.CommentID] = append(byComment[r.CommentID], r)
+	}
+
+	for _, c := range comments {
+		rs, ok := byComment[c.CommentID]
+		if !ok {
+			continue
+		}
+		c.Revisions = rs
+
+		from, to := "", ""
+		if rs[0].Message != nil {
+			from = *rs[0].Message
+		}
+		if c.Message != nil {
+			to = *c.Message
+		}
+		c.EditDiff = diffText(from, to)
+	}
+}
+
+var mentionRegexp = regexp.MustCompile(`(?:^|[^\w@])@([a-zA-Z0-9_.]{2,32})`)
+
+// parseMentions returns the lowercase usernames mentioned in the message as @username
+func parseMentions(message string) []string {
+	result := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range mentionRegexp.FindAllStringSubmatch(message, -1) {
+		username := strings.ToLower(strings.TrimRight(match[1], "."))
+		if username == "" || seen[username] {
+			continue
+		}
+		seen[username] = true
+		result = append(result, username)
+	}
+	return result
+}
+
+// threadComments orders comments so that replies follow their parent comment, comments whose parent is missing start a new thread
+func threadComments(comments []*types.ExtendedComment) []*types.ExtendedComment {
+	byID := make(map[int64]*types.ExtendedComment, len(comments))
+	for _, c := range comments {
+		byID[c.CommentID] = c
+	}
+
+	replies := make(map[int64][]*types.ExtendedComment)
+	roots := make([]*types.ExtendedComment, 0, len(comments))
+	for _, c := range comments {
+		if c.ParentCommentID != nil {
+			if parent, ok := byID[*c.ParentCommentID]; ok {
+				c.ParentUsername = parent.Username
+				replies[parent.CommentID] = append(replies[parent.CommentID], c)
+				continue
+			}
+		}
+		roots = append(roots, c)
+	}
+
+	result := make([]*types.ExtendedComment, 0, len(comments))
+	var walk func(c *types.ExtendedComment, depth int)
+	walk = func(c *types.ExtendedComment, depth int) {
+		c.Depth = depth
+		result = append(result, c)
+		for _, reply := range replies[c.CommentID] {
+			walk(reply, depth+1)
+		}
+	}
+	for _, c := range roots {
+		walk(c, 0)
+	}
+
+	return result
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseMentions(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    []string
+	}{
+		{name: "no mentions", message: "looks good", want: []string{}},
+		{name: "lowercased and deduplicated", message: "hi @Bob and @alice. @bob", want: []string{"bob", "alice"}},
+		{name: "mention at the start", message: "@tester.name please check", want: []string{"tester.name"}},
+		{name: "email is not a mention", message: "mail me at user@example.com", want: []string{}},
+		{name: "too short", message: "@a", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, parseMentions(tt.message))
+		})
+	}
+}
+
+func Test_threadComments(t *testing.T) {
+	id := func(i int64) *int64 { return &i }
+
+	comments := []*types.ExtendedComment{
+		{CommentID: 1, Username: "alice"},
+		{CommentID: 2, Username: "bob"},
+		{CommentID: 3, Username: "carol", ParentCommentID: id(1)},
+		{CommentID: 4, Username: "dave", ParentCommentID: id(3)},
+		{CommentID: 5, Username: "erin", ParentCommentID: id(1)},
+		{CommentID: 6, Username: "frank", ParentCommentID: id(100)},
+	}
+
+	threaded := threadComments(comments)
+
+	ids := make([]int64, 0, len(threaded))
+	depths := make([]int, 0, len(threaded))
+	for _, c := range threaded {
+		ids = append(ids, c.CommentID)
+		depths = append(depths, c.Depth)
+	}
+
+	assert.Equal(t, []int64{1, 3, 4, 5, 2, 6}, ids)
+	assert.Equal(t, []int{0, 1, 2, 1, 0, 0}, depths)
+	assert.Equal(t, "alice", comments[2].ParentUsername)
+	assert.Equal(t, "carol", comments[3].ParentUsername)
+	assert.Equal(t, "", comments[5].ParentUsername)
+}
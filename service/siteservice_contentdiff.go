@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// indexReceivedSubmissionFile replaces the indexed contents of the submission file, so indexing a file again does not duplicate them
+func (s *SiteService) indexReceivedSubmissionFile(ctx context.Context, sfid int64, filePath string) {
+	utils.LogCtx(ctx).Debug("indexing submission file")
+
+	files, indexingErrors, err := provideArchiveForIndexing(filePath, s.archiveIndexerServerURL)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.DeleteSubmissionFileContents(dbs, sfid); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+
+	const batchSize = 1000
+
+	for i := 0; i < len(files); i += batchSize {
+		end := i + batchSize
+		if end > len(files) {
+			end = len(files)
+		}
+		utils.LogCtx(ctx).Debug("inserting submission file contents batch into fpfssdb")
+		if err := s.dal.StoreSubmissionFileContents(dbs, sfid, files[i:end]); err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return
+		}
+	}
+
+	if err := s.dal.UpdateSubmissionFileIndexedState(dbs, sfid, s.clock.Now(), indexingErrors); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+
+	utils.LogCtx(ctx).Debug("submission file indexed")
+}
+
+// IndexUnindexedSubmissionFiles indexes submission files uploaded before submission files were being indexed, or those which failed to index
+func (s *SiteService) IndexUnindexedSubmissionFiles(l *logrus.Entry) {
+	ctx := context.WithValue(context.Background(), utils.CtxKeys.Log, l)
+
+	// the list is read in its own session, indexing a file takes a while and every file gets a session of its own
+	unindexedFiles, err := func() ([]*types.ExtendedSubmissionFile, error) {
+		dbs, err := s.dal.NewSession(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer dbs.Rollback()
+		return s.dal.GetUnindexedSubmissionFiles(dbs)
+	}()
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+
+	utils.LogCtx(ctx).WithField("unindexedSubmissionFiles", len(unindexedFiles)).Debug("found unindexed submission files")
+
+	for _, unindexedFile := range unindexedFiles {
+		destinationFilePath := s.submissionsDir + "/" + unindexedFile.CurrentFilename
+		fctx := context.WithValue(ctx, utils.CtxKeys.Log, l.WithField("submissionFileID", unindexedFile.FileID))
+		s.indexReceivedSubmissionFile(fctx, unindexedFile.FileID, destinationFilePath)
+	}
+}
+
+// GetArchiveContentDiff compares contents of two archives of a submission
+func (s *SiteService) GetArchiveContentDiff(ctx context.Context, sid, fromFID, toFID int64) (*types.ArchiveContentDiff, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	return s.getArchiveContentDiff(dbs, sid, fromFID, toFID)
+}
+
+func (s *SiteService) getArchiveContentDiff(dbs database.DBSession, sid, fromFID, toFID int64) (*types.ArchiveContentDiff, error) {
+	sfs, err := s.dal.GetExtendedSubmissionFilesBySubmissionID(dbs, sid)
+	if err != nil {
+		utils.LogCtx(dbs.Ctx()).Error(err)
+		return nil, dberr(err)
+	}
+
+	getContents := func(sfid int64) ([]*types.IndexedFileEntry, error) {
+		for _, sf := range sfs {
+			if sf.FileID != sfid {
+				continue
+			}
+			if sf.IndexedAt == nil {
+				return nil, perr("submission file is not indexed yet", http.StatusConflict)
+			}
+			contents, err := s.dal.GetSubmissionFileContents(dbs, sfid)
+			if err != nil {
+				utils.LogCtx(dbs.Ctx()).Error(err)
+				return nil, dberr(err)
+			}
+			return contents, nil
+		}
+		return nil, perr("submission file not found", http.StatusNotFound)
+	}
+
+	from, err := getContents(fromFID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := getContents(toFID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := diffArchiveContents(from, to)
+	diff.FromFileID = fromFID
+	diff.ToFileID = toFID
+
+	return diff, nil
+}
+
+// diffArchiveContents compares two archive listings by path and sha256, ignoring the name of the root directory
+func diffArchiveContents(from, to []*types.IndexedFileEntry) *types.ArchiveContentDiff {
+	fromEntries := stripCommonRoot(from)
+	toEntries := stripCommonRoot(to)
+
+	result := &types.ArchiveContentDiff{
+		Added:    make([]*types.ArchiveEntryDiff, 0),
+		Removed:  make([]*types.ArchiveEntryDiff, 0),
+		Modified: make([]*types.ArchiveEntryDiff, 0),
+	}
+
+	for path, t := range toEntries {
+		f, ok := fromEntries[path]
+		if !ok {
+			result.Added = append(result.Added, &types.ArchiveEntryDiff{Path: path, SizeTo: t.SizeUncompressed, SizeDelta: t.SizeUncompressed})
+		} else if f.SHA256 != t.SHA256 {
+			result.Modified = append(result.Modified, &types.ArchiveEntryDiff{Path: path, SizeFrom: f.SizeUncompressed, SizeTo: t.SizeUncompressed, SizeDelta: t.SizeUncompressed - f.SizeUncompressed})
+		}
+	}
+	for path, f := range fromEntries {
+		if _, ok := toEntries[path]; !ok {
+			result.Removed = append(result.Removed, &types.ArchiveEntryDiff{Path: path, SizeFrom: f.SizeUncompressed, SizeDelta: -f.SizeUncompressed})
+		}
+	}
+
+	for _, entries := range [][]*types.ArchiveEntryDiff{result.Added, result.Removed, result.Modified} {
+		entries := entries
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Path < entries[j].Path
+		})
+	}
+
+	return result
+}
+
+// stripCommonRoot maps archive entries by their path, without the root directory if all entries share one,
+// so that renaming the curation folder does not show every file as changed
+func stripCommonRoot(entries []*types.IndexedFileEntry) map[string]*types.IndexedFileEntry {
+	root := ""
+	for i, e := range entries {
+		parts := strings.SplitN(e.Name, "/", 2)
+		if len(parts) < 2 {
+			root = ""
+			break
+		}
+		if i == 0 {
+			root = parts[0] + "/"
+		} else if parts[0]+"/" != root {
+			root = ""
+			break
+		}
+	}
+
+	result := make(map[string]*types.IndexedFileEntry, len(entries))
+	for _, e := range entries {
+		result[strings.TrimPrefix(e.Name, root)] = e
+	}
+
+	return result
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+)
+
+func Test_diffArchiveContents(t *testing.T) {
+	entry := func(name string, size int64, sha256 string) *types.IndexedFileEntry {
+		return &types.IndexedFileEntry{Name: name, SizeUncompressed: size, SHA256: sha256}
+	}
+
+	tests := []struct {
+		name string
+		from []*types.IndexedFileEntry
+		to   []*types.IndexedFileEntry
+		want *types.ArchiveContentDiff
+	}{
+		{
+			name: "no changes",
+			from: []*types.IndexedFileEntry{entry("a/meta.yaml", 10, "aa")},
+			to:   []*types.IndexedFileEntry{entry("a/meta.yaml", 10, "aa")},
+			want: &types.ArchiveContentDiff{
+				Added:    []*types.ArchiveEntryDiff{},
+				Removed:  []*types.ArchiveEntryDiff{},
+				Modified: []*types.ArchiveEntryDiff{},
+			},
+		},
+		{
+			name: "renamed root directory is ignored",
+			from: []*types.IndexedFileEntry{entry("old/meta.yaml", 10, "aa"), entry("old/logo.png", 5, "bb")},
+			to:   []*types.IndexedFileEntry{entry("new/meta.yaml", 10, "aa"), entry("new/logo.png", 5, "bb")},
+			want: &types.ArchiveContentDiff{
+				Added:    []*types.ArchiveEntryDiff{},
+				Removed:  []*types.ArchiveEntryDiff{},
+				Modified: []*types.ArchiveEntryDiff{},
+			},
+		},
+		{
+			name: "added, removed and modified",
+			from: []*types.IndexedFileEntry{
+				entry("c/meta.yaml", 10, "aa"),
+				entry("c/content/old.swf", 100, "cc"),
+				entry("c/logo.png", 5, "bb"),
+			},
+			to: []*types.IndexedFileEntry{
+				entry("c/meta.yaml", 12, "ab"),
+				entry("c/content/new.swf", 200, "dd"),
+				entry("c/content/b.swf", 50, "ee"),
+				entry("c/logo.png", 5, "bb"),
+			},
+			want: &types.ArchiveContentDiff{
+				Added: []*types.ArchiveEntryDiff{
+					{Path: "content/b.swf", SizeTo: 50, SizeDelta: 50},
+					{Path: "content/new.swf", SizeTo: 200, SizeDelta: 200},
+				},
+				Removed: []*types.ArchiveEntryDiff{
+					{Path: "content/old.swf", SizeFrom: 100, SizeDelta: -100},
+				},
+				Modified: []*types.ArchiveEntryDiff{
+					{Path: "meta.yaml", SizeFrom: 10, SizeTo: 12, SizeDelta: 2},
+				},
+			},
+		},
+		{
+			name: "files in the archive root",
+			from: []*types.IndexedFileEntry{entry("meta.yaml", 10, "aa")},
+			to:   []*types.IndexedFileEntry{entry("meta.yaml", 10, "aa"), entry("logo.png", 5, "bb")},
+			want: &types.ArchiveContentDiff{
+				Added:    []*types.ArchiveEntryDiff{{Path: "logo.png", SizeTo: 5, SizeDelta: 5}},
+				Removed:  []*types.ArchiveEntryDiff{},
+				Modified: []*types.ArchiveEntryDiff{},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffArchiveContents(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffArchiveContents() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+// isContentHashCandidate decides if a file inside an archive is specific enough to be compared by hash
+func isContentHashCandidate(f *archiveFile) bool {
+	if f.size < constants.ContentHashMinimumSize {
+		return false
+	}
+	ext := strings.ToLower(path.Ext(f.name))
+	for _, candidate := range constants.ContentHashExtensions {
+		if ext == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// hashArchiveContentFiles computes SHA256 of the game files inside a submission archive
+func hashArchiveContentFiles(filePath string) ([]*types.ArchiveContentHash, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := listArchiveFiles(f, fi.Size(), filepath.Base(filePath))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*types.ArchiveContentHash, 0)
+
+	for _, af := range files {
+		if !isContentHashCandidate(af) {
+			continue
+		}
+
+		sum, err := func() (string, error) {
+			rc, err := af.open()
+			if err != nil {
+				return "", err
+			}
+			defer rc.Close()
+
+			h := sha256.New()
+			if _, err := io.Copy(h, rc); err != nil {
+				return "", err
+			}
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}()
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, &types.ArchiveContentHash{Filename: af.name, SHA256: sum})
+	}
+
+	return result, nil
+}
+
+// findContentHashMatches finds files with the same content as the given archive files, matches with the submission itself are left out
+func (s *SiteService) findContentHashMatches(dbs database.DBSession, sid *int64, contentHashes []*types.ArchiveContentHash) ([]*types.ContentHashMatch, error) {
+	sums := make([]string, 0, len(contentHashes))
+	seen := make(map[string]bool, len(contentHashes))
+	for _, ch := range contentHashes {
+		if seen[ch.SHA256] {
+			continue
+		}
+		seen[ch.SHA256] = true
+		sums = append(sums, ch.SHA256)
+	}
+
+	matches, err := s.dal.GetContentHashMatches(dbs, sums)
+	if err != nil {
+		utils.LogCtx(dbs.Ctx()).Error(err)
+		return nil, dberr(err)
+	}
+
+	return filterContentHashMatches(matches, sid), nil
+}
+
+// filterContentHashMatches removes matches with the submission itself and duplicate matches from multiple versions of the same submission
+func filterContentHashMatches(matches []*types.ContentHashMatch, sid *int64) []*types.ContentHashMatch {
+	result := make([]*types.ContentHashMatch, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+
+	for _, m := range matches {
+		if sid != nil && m.Source == constants.ContentHashMatchSourceSubmission && m.ID == *sid {
+			continue
+		}
+		key := fmt.Sprintf("%s/%d/%s", m.Source, m.ID, m.SHA256)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, m)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Source != result[j].Source {
+			return result[i].Source < result[j].Source
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result
+}
+
+// formatContentHashMatches lists content matches for the similarity comment
+func formatContentHashMatches(matches []*types.ContentHashMatch, contentHashes []*types.ArchiveContentHash) string {
+	if len(matches) == 0 {
+		return ""
+	}
+
+	filenames := make(map[string][]string, len(contentHashes))
+	for _, ch := range contentHashes {
+		filenames[ch.SHA256] = append(filenames[ch.SHA256], ch.Filename)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Curations with identical content files have been found:\n")
+
+	for _, m := range matches {
+		var link string
+		switch m.Source {
+		case constants.ContentHashMatchSourceFlashfreeze:
+			link = fmt.Sprintf("https://fpfss.unstable.life/data/flashfreeze/file/%d", m.ID)
+		case constants.ContentHashMatchSourceSubmission:
+			link = fmt.Sprintf("https://fpfss.unstable.life/web/submission/%d", m.ID)
+		}
+		sb.WriteString(fmt.Sprintf("'%s' is identical to '%s' in %s ID %d - %s\n",
+			strings.Join(filenames[m.SHA256], "', '"), m.MatchFilename, m.Source, m.ID, link))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_hashArchiveContentFiles(t *testing.T) {
+	swf := strings.Repeat("swf", 100)
+	filePath := filepath.Join(t.TempDir(), "foo.zip")
+
+	f, err := os.Create(filePath)
+	require.NoError(t, err)
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"Foo/meta.yaml":              strings.Repeat("meta", 100),
+		"Foo/content/foo.swf":        swf,
+		"Foo/content/redirect.html":  "<html></html>",
+		"Foo/content/assets/bar.png": strings.Repeat("png", 100),
+	} {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	require.NoError(t, f.Close())
+
+	got, err := hashArchiveContentFiles(filePath)
+	require.NoError(t, err)
+
+	sum := sha256.Sum256([]byte(swf))
+	assert.Equal(t, []*types.ArchiveContentHash{{Filename: "Foo/content/foo.swf", SHA256: hex.EncodeToString(sum[:])}}, got)
+}
+
+func Test_filterContentHashMatches(t *testing.T) {
+	sid := int64(5)
+	matches := []*types.ContentHashMatch{
+		{SHA256: "a", Source: constants.ContentHashMatchSourceSubmission, ID: 7, MatchFilename: "x/foo.swf"},
+		{SHA256: "a", Source: constants.ContentHashMatchSourceSubmission, ID: 5, MatchFilename: "x/foo.swf"},
+		{SHA256: "a", Source: constants.ContentHashMatchSourceSubmission, ID: 7, MatchFilename: "y/foo.swf"},
+		{SHA256: "a", Source: constants.ContentHashMatchSourceFlashfreeze, ID: 3, MatchFilename: "foo.swf"},
+	}
+
+	got := filterContentHashMatches(matches, &sid)
+
+	assert.Equal(t, []*types.ContentHashMatch{
+		{SHA256: "a", Source: constants.ContentHashMatchSourceFlashfreeze, ID: 3, MatchFilename: "foo.swf"},
+		{SHA256: "a", Source: constants.ContentHashMatchSourceSubmission, ID: 7, MatchFilename: "x/foo.swf"},
+	}, got)
+
+	assert.Len(t, filterContentHashMatches(matches, nil), 3)
+}
+
+func Test_formatContentHashMatches(t *testing.T) {
+	assert.Equal(t, "", formatContentHashMatches(nil, nil))
+
+	got := formatContentHashMatches(
+		[]*types.ContentHashMatch{{SHA256: "a", Source: constants.ContentHashMatchSourceSubmission, ID: 7, MatchFilename: "Bar/content/bar.swf"}},
+		[]*types.ArchiveContentHash{{Filename: "Foo/content/foo.swf", SHA256: "a"}},
+	)
+
+	assert.Equal(t, "Curations with identical content files have been found:\n"+
+		"'Foo/content/foo.swf' is identical to 'Bar/content/bar.swf' in submission ID 7 - https://fpfss.unstable.life/web/submission/7\n", got)
+}
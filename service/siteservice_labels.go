@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+// GetLabels returns all labels
+func (s *SiteService) GetLabels(ctx context.Context) ([]*types.Label, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	labels, err := s.dal.GetLabels(dbs)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	return labels, nil
+}
+
+// CreateLabel stores a new label
+func (s *SiteService) CreateLabel(ctx context.Context, name string) (*types.Label, error) {
+	name, err := types.NormalizeLabelName(name)
+	if err != nil {
+		return nil, perr(err.Error(), http.StatusBadRequest)
+	}
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	l := &types.Label{
+		Name:      name,
+		UserID:    utils.UserID(ctx),
+		CreatedAt: s.clock.Now(),
+	}
+
+	l.ID, err = s.dal.StoreLabel(dbs, l)
+	if err != nil {
+		if isDuplicateEntry(err) {
+			return nil, perr(fmt.Sprintf("label '%s' already exists", name), http.StatusConflict)
+		}
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	return l, nil
+}
+
+// UpdateLabel renames a label on all submissions it's attached to
+func (s *SiteService) UpdateLabel(ctx context.Context, id int64, name string) error {
+	name, err := types.NormalizeLabelName(name)
+	if err != nil {
+		return perr(err.Error(), http.StatusBadRequest)
+	}
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.UpdateLabel(dbs, id, name); err != nil {
+		if err == sql.ErrNoRows {
+			return perr("label not found", http.StatusNotFound)
+		}
+		if isDuplicateEntry(err) {
+			return perr(fmt.Sprintf("label '%s' already exists", name), http.StatusConflict)
+		}
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	return nil
+}
+
+// DeleteLabel deletes a label and detaches it from all submissions
+func (s *SiteService) DeleteLabel(ctx context.Context, id int64) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.DeleteLabel(dbs, id); err != nil {
+		if err == sql.ErrNoRows {
+			return perr("label not found", http.StatusNotFound)
+		}
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	return nil
+}
+
+// AddSubmissionLabel attaches a label to a submission, the label is created if it does not exist yet
+func (s *SiteService) AddSubmissionLabel(ctx context.Context, sid int64, name string) error {
+	uid := utils.UserID(ctx)
+
+	name, err := types.NormalizeLabelName(name)
+	if err != nil {
+		return perr(err.Error(), http.StatusBadRequest)
+	}
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	submissions, _, err := s.dal.SearchSubmissions(dbs, &types.SubmissionsFilter{SubmissionIDs: []int64{sid}})
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	if len(submissions) == 0 {
+		return perr(fmt.Sprintf("submission %d not found", sid), http.StatusNotFound)
+	}
+
+	var lid int64
+	l, err := s.dal.GetLabelByName(dbs, name)
+	if err == nil {
+		lid = l.ID
+	} else if err == sql.ErrNoRows {
+		lid, err = s.dal.StoreLabel(dbs, &types.Label{Name: name, UserID: uid, CreatedAt: s.clock.Now()})
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return dberr(err)
+		}
+	} else {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := s.dal.StoreSubmissionLabel(dbs, sid, lid, uid, s.clock.Now()); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	return nil
+}
+
+// RemoveSubmissionLabel detaches a label from a submission
+func (s *SiteService) RemoveSubmissionLabel(ctx context.Context, sid int64, name string) error {
+	name, err := types.NormalizeLabelName(name)
+	if err != nil {
+		return perr(err.Error(), http.StatusBadRequest)
+	}
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	l, err := s.dal.GetLabelByName(dbs, name)
+	if err == nil {
+		err = s.dal.DeleteSubmissionLabel(dbs, sid, l.ID)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return perr(fmt.Sprintf("submission %d does not have label '%s'", sid, name), http.StatusNotFound)
+		}
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+// GetCurationMetaDiff compares curation meta of two files of a submission
+func (s *SiteService) GetCurationMetaDiff(ctx context.Context, sid, fromFID, toFID int64) (*types.CurationMetaDiff, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	return s.getCurationMetaDiff(dbs, sid, fromFID, toFID)
+}
+
+// getCurationMetaDiff compares curation meta of two files, files without curation meta, like legacy files
+// or files which failed validation, have nothing to compare and give an empty diff marked as such
+func (s *SiteService) getCurationMetaDiff(dbs database.DBSession, sid, fromFID, toFID int64) (*types.CurationMetaDiff, error) {
+	getMeta := func(sfid int64) (*types.CurationMeta, error) {
+		sfs, err := s.dal.GetSubmissionFiles(dbs, []int64{sfid})
+		if err != nil {
+			utils.LogCtx(dbs.Ctx()).Error(err)
+			return nil, dberr(err)
+		}
+		if len(sfs) == 0 {
+			return nil, perr("submission file not found", http.StatusNotFound)
+		}
+		if sfs[0].SubmissionID != sid {
+			return nil, perr("submission file does not belong to the submission", http.StatusBadRequest)
+		}
+
+		meta, err := s.dal.GetCurationMetaBySubmissionFileID(dbs, sfid)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, nil
+			}
+			utils.LogCtx(dbs.Ctx()).Error(err)
+			return nil, dberr(err)
+		}
+		return meta, nil
+	}
+
+	from, err := getMeta(fromFID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := getMeta(toFID)
+	if err != nil {
+		return nil, err
+	}
+
+	if from == nil || to == nil {
+		return &types.CurationMetaDiff{
+			FromFileID:    fromFID,
+			ToFileID:      toFID,
+			Fields:        make([]*types.CurationMetaFieldDiff, 0),
+			Lists:         make([]*types.CurationMetaListDiff, 0),
+			IsMetaMissing: true,
+		}, nil
+	}
+
+	return diffCurationMeta(from, to), nil
+}
+
+// diffCurationMeta compares two curation metas field by field, tags and alternate titles are compared as sets
+func diffCurationMeta(from, to *types.CurationMeta) *types.CurationMetaDiff {
+	result := &types.CurationMetaDiff{
+		FromFileID: from.SubmissionFileID,
+		ToFileID:   to.SubmissionFileID,
+		Fields:     make([]*types.CurationMetaFieldDiff, 0),
+		Lists:      make([]*types.CurationMetaListDiff, 0),
+	}
+
+	fields := []struct {
+		name     string
+		from, to *string
+	}{
+		{"Title", from.Title, to.Title},
+		{"Library", from.Library, to.Library},
+		{"Series", from.Series, to.Series},
+		{"Developer", from.Developer, to.Developer},
+		{"Publisher", from.Publisher, to.Publisher},
+		{"Play Mode", from.PlayMode, to.PlayMode},
+		{"Status", from.Status, to.Status},
+		{"Version", from.Version, to.Version},
+		{"Release Date", from.ReleaseDate, to.ReleaseDate},
+		{"Languages", from.Languages, to.Languages},
+		{"Source", from.Source, to.Source},
+		{"Platform", from.Platform, to.Platform},
+		{"Application Path", from.ApplicationPath, to.ApplicationPath},
+		{"Launch Command", from.LaunchCommand, to.LaunchCommand},
+		{"Mount Parameters", from.MountParameters, to.MountParameters},
+		{"Game Notes", from.GameNotes, to.GameNotes},
+		{"Original Description", from.OriginalDescription, to.OriginalDescription},
+		{"Curation Notes", from.CurationNotes, to.CurationNotes},
+		{"Extreme", from.Extreme, to.Extreme},
+	}
+
+	for _, f := range fields {
+		if utils.Unpointify(f.from) != utils.Unpointify(f.to) {
+			result.Fields = append(result.Fields, &types.CurationMetaFieldDiff{Field: f.name, From: f.from, To: f.to})
+		}
+	}
+
+	lists := []struct {
+		name     string
+		from, to *string
+	}{
+		{"Tags", from.Tags, to.Tags},
+		{"Tag Categories", from.TagCategories, to.TagCategories},
+		{"Alternate Titles", from.AlternateTitles, to.AlternateTitles},
+	}
+
+	for _, l := range lists {
+		added, removed := diffMetaList(splitMetaList(l.from), splitMetaList(l.to))
+		if len(added) > 0 || len(removed) > 0 {
+			result.Lists = append(result.Lists, &types.CurationMetaListDiff{Field: l.name, Added: added, Removed: removed})
+		}
+	}
+
+	return result
+}
+
+// splitMetaList splits a semicolon or newline separated meta field into trimmed non-empty items
+func splitMetaList(s *string) []string {
+	if s == nil {
+		return []string{}
+	}
+	split := strings.FieldsFunc(*s, func(r rune) bool {
+		return r == ';' || r == '\n'
+	})
+	result := make([]string, 0, len(split))
+	for _, item := range split {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// diffMetaList returns items added and removed between two lists, compared case-insensitively
+func diffMetaList(from, to []string) ([]string, []string) {
+	newSet := func(items []string) map[string]string {
+		m := make(map[string]string, len(items))
+		for _, item := range items {
+			m[strings.ToLower(item)] = item
+		}
+		return m
+	}
+
+	fromSet := newSet(from)
+	toSet := newSet(to)
+
+	added := make([]string, 0)
+	removed := make([]string, 0)
+
+	for key, item := range toSet {
+		if _, ok := fromSet[key]; !ok {
+			added = append(added, item)
+		}
+	}
+	for key, item := range fromSet {
+		if _, ok := toSet[key]; !ok {
+			removed = append(removed, item)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	return added, removed
+}
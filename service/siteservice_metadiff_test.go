@@ -0,0 +1,93 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+func Test_diffMetaList(t *testing.T) {
+	tests := []struct {
+		name        string
+		from        *string
+		to          *string
+		wantAdded   []string
+		wantRemoved []string
+	}{
+		{
+			name:        "no changes",
+			from:        utils.StrPtr("Action; Puzzle"),
+			to:          utils.StrPtr("Action; Puzzle"),
+			wantAdded:   []string{},
+			wantRemoved: []string{},
+		},
+		{
+			name:        "order and case do not matter",
+			from:        utils.StrPtr("Action; Puzzle"),
+			to:          utils.StrPtr("puzzle;action"),
+			wantAdded:   []string{},
+			wantRemoved: []string{},
+		},
+		{
+			name:        "added and removed",
+			from:        utils.StrPtr("Action; Puzzle"),
+			to:          utils.StrPtr("Puzzle; Platformer; Arcade"),
+			wantAdded:   []string{"Arcade", "Platformer"},
+			wantRemoved: []string{"Action"},
+		},
+		{
+			name:        "nil to list",
+			from:        nil,
+			to:          utils.StrPtr("Title One\nTitle Two"),
+			wantAdded:   []string{"Title One", "Title Two"},
+			wantRemoved: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := diffMetaList(splitMetaList(tt.from), splitMetaList(tt.to))
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("diffMetaList() added = %v, want %v", added, tt.wantAdded)
+			}
+			if !reflect.DeepEqual(removed, tt.wantRemoved) {
+				t.Errorf("diffMetaList() removed = %v, want %v", removed, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+func Test_diffCurationMeta(t *testing.T) {
+	from := &types.CurationMeta{
+		SubmissionFileID: 1,
+		Title:            utils.StrPtr("Foo"),
+		Developer:        utils.StrPtr("Bar"),
+		Tags:             utils.StrPtr("Action; Puzzle"),
+	}
+	to := &types.CurationMeta{
+		SubmissionFileID: 2,
+		Title:            utils.StrPtr("Foo 2"),
+		Developer:        utils.StrPtr("Bar"),
+		Publisher:        utils.StrPtr("Baz"),
+		Tags:             utils.StrPtr("Action"),
+	}
+
+	diff := diffCurationMeta(from, to)
+
+	if diff.FromFileID != 1 || diff.ToFileID != 2 {
+		t.Errorf("diffCurationMeta() file ids = %d, %d, want 1, 2", diff.FromFileID, diff.ToFileID)
+	}
+
+	changedFields := make([]string, 0)
+	for _, f := range diff.Fields {
+		changedFields = append(changedFields, f.Field)
+	}
+	if !reflect.DeepEqual(changedFields, []string{"Title", "Publisher"}) {
+		t.Errorf("diffCurationMeta() changed fields = %v, want [Title Publisher]", changedFields)
+	}
+
+	if len(diff.Lists) != 1 || diff.Lists[0].Field != "Tags" || !reflect.DeepEqual(diff.Lists[0].Removed, []string{"Puzzle"}) {
+		t.Errorf("diffCurationMeta() unexpected list diff %v", diff.Lists)
+	}
+}
@@ -138,18 +138,18 @@ func (m *mockDAL) GetCommentByID(_ database.DBSession, cid int64) (*types.Commen
 	return args.Get(0).(*types.Comment), args.Error(1)
 }
 
-func (m *mockDAL) SoftDeleteSubmissionFile(_ database.DBSession, sfid int64, deleteReason string) error {
-	args := m.Called(sfid, deleteReason)
+func (m *mockDAL) SoftDeleteSubmissionFile(_ database.DBSession, sfid int64, deleteReason string, deletedBy int64) error {
+	args := m.Called(sfid, deleteReason, deletedBy)
 	return args.Error(0)
 }
 
-func (m *mockDAL) SoftDeleteSubmission(_ database.DBSession, sid int64, deleteReason string) error {
-	args := m.Called(sid, deleteReason)
+func (m *mockDAL) SoftDeleteSubmission(_ database.DBSession, sid int64, deleteReason string, deletedBy int64, deletedAt time.Time) error {
+	args := m.Called(sid, deleteReason, deletedBy, deletedAt)
 	return args.Error(0)
 }
 
-func (m *mockDAL) SoftDeleteComment(_ database.DBSession, cid int64, deleteReason string) error {
-	args := m.Called(cid, deleteReason)
+func (m *mockDAL) SoftDeleteComment(_ database.DBSession, cid int64, deleteReason string, deletedBy int64) error {
+	args := m.Called(cid, deleteReason, deletedBy)
 	return args.Error(0)
 }
 
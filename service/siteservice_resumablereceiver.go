@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"time"
@@ -40,37 +41,55 @@ func (s *SiteService) ReceiveSubmissionChunk(ctx context.Context, sid *int64, re
 	}
 
 	if isComplete {
-		// tempName is used as the ID of the submission while the submission is being processed, used by the client to poll for status
-		tempName := s.randomStringProvider.RandomString(32)
-		s.SSK.SetReceived(tempName)
+		utils.LogCtx(ctx).Debug("submission resumable upload finished")
 
-		go func() {
-			ctx := utils.ValueOnlyContext{ctx}
-			utils.LogCtx(ctx).Debug("submission resumable upload finished")
+		dbs, err := s.dal.NewSession(ctx)
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return nil, dberr(err)
+		}
+		defer dbs.Rollback()
 
-			processReceivedResumableSubmission := func() (interface{}, error) {
-				return nil, s.processReceivedResumableSubmission(ctx, uid, sid, resumableParams, tempName)
-			}
-			processReceivedResumableSubmissionKey := fmt.Sprintf("%d-%s", uid, resumableParams.ResumableIdentifier)
+		// the last chunk can be received more than once, even at the same time, do not queue the same upload twice
+		if err := s.dal.LockUser(dbs, uid); err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return nil, dberr(err)
+		}
 
-			_, err, cached := resumableMemoizer.Memoize(processReceivedResumableSubmissionKey, processReceivedResumableSubmission)
-			utils.LogCtx(ctx).WithField("cached", utils.BoolToString(cached)).Debug("processed resumable submission upload")
+		j, err := s.dal.GetUnfinishedSubmissionJob(dbs, uid, resumableParams.ResumableIdentifier)
+		if err == nil {
+			return &j.TempName, nil
+		} else if err != sql.ErrNoRows {
+			utils.LogCtx(ctx).Error(err)
+			return nil, dberr(err)
+		}
 
-			if err != nil {
-				resumableMemoizer.Storage.Delete(processReceivedResumableSubmissionKey)
-				utils.LogCtx(ctx).Error(err)
-			}
+		// tempName is used as the ID of the submission while the submission is being processed, used by the client to poll for status
+		tempName := s.randomStringProvider.RandomString(32)
 
-			if !cached {
-				utils.LogCtx(ctx).Debug("deleting the resumable file chunks")
-				if err := s.resumableUploadService.DeleteFile(uid, resumableParams.ResumableIdentifier, resumableParams.ResumableTotalChunks); err != nil {
-					utils.LogCtx(ctx).Error(err)
-				}
+		j = &types.SubmissionJob{
+			TempName:            tempName,
+			UserID:              uid,
+			SubmissionID:        sid,
+			ResumableIdentifier: resumableParams.ResumableIdentifier,
+			Filename:            resumableParams.ResumableFilename,
+			Size:                resumableParams.ResumableTotalSize,
+			ChunkCount:          resumableParams.ResumableTotalChunks,
+		}
 
-				utils.LogCtx(ctx).WithField("memoizerKey", processReceivedResumableSubmissionKey).Debug("deleting the memoized call")
-				resumableMemoizer.Storage.Delete(processReceivedResumableSubmissionKey)
-			}
-		}()
+		if _, err := s.dal.StoreSubmissionJob(dbs, j); err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return nil, dberr(err)
+		}
+
+		if err := dbs.Commit(); err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return nil, dberr(err)
+		}
+
+		utils.LogCtx(ctx).WithField("tempName", tempName).Debug("submission job queued")
+		s.SSK.SetReceived(ctx, tempName)
+		s.announceSubmissionJob()
 
 		return &tempName, nil
 	}
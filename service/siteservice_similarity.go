@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+// loadSimilarityRules loads similarity rules from the database, rules which could not be applied are left out
+func (s *SiteService) loadSimilarityRules(dbs database.DBSession) (*similarityRuleSet, error) {
+	rules, err := s.dal.GetSimilarityRules(dbs)
+	if err != nil {
+		utils.LogCtx(dbs.Ctx()).Error(err)
+		return nil, dberr(err)
+	}
+
+	validRules := make([]*types.SimilarityRule, 0, len(rules))
+	for _, rule := range rules {
+		if err := checkSimilarityRule(rule); err != nil {
+			utils.LogCtx(dbs.Ctx()).WithField("similarityRuleID", rule.ID).Error(err)
+			continue
+		}
+		validRules = append(validRules, rule)
+	}
+
+	return newSimilarityRuleSet(validRules)
+}
+
+// GetSimilarityRulesPageData returns similarity rules and, if requested, shows what the similarity scorer finds for a title and launch command
+func (s *SiteService) GetSimilarityRulesPageData(ctx context.Context, req *types.SimilarityTestRequest) (*types.SimilarityRulesPageData, error) {
+	bpd, err := s.GetBasePageData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	rules, err := s.dal.GetSimilarityRules(dbs)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	pageData := &types.SimilarityRulesPageData{
+		BasePageData: *bpd,
+		Rules:        rules,
+	}
+
+	if req.Title != "" || req.LaunchCommand != "" {
+		if err := s.ensureSimilarityIndex(dbs); err != nil {
+			return nil, err
+		}
+
+		var title, launchCommand *string
+		if req.Title != "" {
+			title = &req.Title
+		}
+		if req.LaunchCommand != "" {
+			launchCommand = &req.LaunchCommand
+		}
+
+		pageData.Test = s.similarityIndex.search(title, launchCommand, 0.9)
+		pageData.Test.SimilarityTestRequest = *req
+	}
+
+	return pageData, nil
+}
+
+// CreateSimilarityRule stores a new similarity rule and applies it to the similarity index
+func (s *SiteService) CreateSimilarityRule(ctx context.Context, rule *types.SimilarityRule) error {
+	if err := checkSimilarityRule(rule); err != nil {
+		return perr(err.Error(), http.StatusBadRequest)
+	}
+
+	rule.UserID = utils.UserID(ctx)
+	rule.UpdatedAt = s.clock.Now()
+
+	return s.changeSimilarityRules(ctx, func(dbs database.DBSession) error {
+		id, err := s.dal.StoreSimilarityRule(dbs, rule)
+		if err != nil {
+			return err
+		}
+		return s.storeAuditLogEntry(dbs, constants.AuditActionCreateSimilarityRule, constants.AuditTargetSimilarityRule, &id, nil, rule)
+	})
+}
+
+// UpdateSimilarityRule updates a similarity rule and applies it to the similarity index
+func (s *SiteService) UpdateSimilarityRule(ctx context.Context, rule *types.SimilarityRule) error {
+	if err := checkSimilarityRule(rule); err != nil {
+		return perr(err.Error(), http.StatusBadRequest)
+	}
+
+	rule.UserID = utils.UserID(ctx)
+	rule.UpdatedAt = s.clock.Now()
+
+	return s.changeSimilarityRules(ctx, func(dbs database.DBSession) error {
+		rules, err := s.dal.GetSimilarityRules(dbs)
+		if err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if r.ID == rule.ID {
+				if err := s.dal.UpdateSimilarityRule(dbs, rule); err != nil {
+					return err
+				}
+				return s.storeAuditLogEntry(dbs, constants.AuditActionUpdateSimilarityRule, constants.AuditTargetSimilarityRule, &rule.ID, r, rule)
+			}
+		}
+		return sql.ErrNoRows
+	})
+}
+
+// DeleteSimilarityRule deletes a similarity rule and removes it from the similarity index
+func (s *SiteService) DeleteSimilarityRule(ctx context.Context, id int64) error {
+	return s.changeSimilarityRules(ctx, func(dbs database.DBSession) error {
+		rules, err := s.dal.GetSimilarityRules(dbs)
+		if err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if r.ID == id {
+				if err := s.dal.DeleteSimilarityRule(dbs, id); err != nil {
+					return err
+				}
+				return s.storeAuditLogEntry(dbs, constants.AuditActionDeleteSimilarityRule, constants.AuditTargetSimilarityRule, &id, r, nil)
+			}
+		}
+		return sql.ErrNoRows
+	})
+}
+
+// changeSimilarityRules runs the change and reloads the rules of the similarity index once it's committed
+func (s *SiteService) changeSimilarityRules(ctx context.Context, change func(dbs database.DBSession) error) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	if err := change(dbs); err != nil {
+		if err == sql.ErrNoRows {
+			return perr("similarity rule not found", http.StatusNotFound)
+		}
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	rules, err := s.loadSimilarityRules(dbs)
+	if err != nil {
+		return err
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	s.similarityIndex.setRules(rules)
+
+	return nil
+}
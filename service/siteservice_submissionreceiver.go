@@ -25,10 +25,10 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs database.DBSession, fileReadCloserProvider resumableuploadservice.ReadCloserInformerProvider, filename string, filesize int64, sid *int64, submissionLevel string, tempName string) (*string, []string, int64, error) {
+func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs database.DBSession, fileReadCloserProvider resumableuploadservice.ReadCloserInformerProvider, filename string, filesize int64, sid *int64, submissionLevel string, tempName string) (*string, []string, int64, int64, error) {
 	uid := utils.UserID(ctx)
 	if uid == 0 {
-		s.SSK.SetFailed(tempName, "internal error")
+		s.SSK.SetFailed(ctx, tempName, "internal error")
 		utils.LogCtx(ctx).Panic("no user associated with request")
 	}
 
@@ -37,20 +37,20 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 	utils.LogCtx(ctx).Debugf("received a file '%s' - %d bytes", filename, filesize)
 
 	if err := os.MkdirAll(s.submissionsDir, os.ModeDir); err != nil {
-		s.SSK.SetFailed(tempName, "internal error")
-		return nil, nil, 0, err
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return nil, nil, 0, 0, err
 	}
 	if err := os.MkdirAll(s.submissionImagesDir, os.ModeDir); err != nil {
-		s.SSK.SetFailed(tempName, "internal error")
-		return nil, nil, 0, err
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return nil, nil, 0, 0, err
 	}
 
 	ext := filepath.Ext(filename)
 
 	if ext != ".7z" && ext != ".zip" {
 		msg := "unsupported file extension"
-		s.SSK.SetFailed(tempName, msg)
-		return nil, nil, 0, perr(msg, http.StatusUnsupportedMediaType)
+		s.SSK.SetFailed(ctx, tempName, msg)
+		return nil, nil, 0, 0, perr(msg, http.StatusUnsupportedMediaType)
 	}
 
 	var destinationFilename string
@@ -100,7 +100,7 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 					return
 				case <-bucket:
 					progress := readCloserInformer.GetFractionRead()
-					s.SSK.SetCopying(tempName, fmt.Sprintf("Copying chunks: %0.2f%%", progress*100))
+					s.SSK.SetCopying(ctx, tempName, fmt.Sprintf("Copying chunks: %0.2f%%", progress*100))
 				}
 			}
 		}()
@@ -122,13 +122,14 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "processing failed")
-		return &destinationFilePath, nil, 0, err
+		s.SSK.SetFailed(ctx, tempName, "processing failed")
+		return &destinationFilePath, nil, 0, 0, err
 	}
 
 	// validation
-	s.SSK.SetValidating(tempName)
+	s.SSK.SetValidating(ctx, tempName)
 	var vr *types.ValidatorResponse
+	var ruleResults []*types.ValidationRuleResult
 	var msg *string
 
 	err = func() error {
@@ -148,8 +149,16 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 			return perr(fmt.Sprintf("validator bot: %s", err.Error()), http.StatusInternalServerError)
 		}
 
+		ruleResults = s.validationRuleEngine.Evaluate(vr)
+
+		// the content is compared only to find duplicates, failing to read it should not stop the upload
+		contentHashes, err := hashArchiveContentFiles(destinationFilePath)
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+		}
+
 		utils.LogCtx(ctx).Debug("computing similarity in goroutine...")
-		msg, err = s.computeSimilarityComment(dbs, sid, &vr.Meta)
+		msg, err = s.computeSimilarityComment(dbs, sid, &vr.Meta, contentHashes)
 		if err != nil {
 			utils.LogCtx(ctx).Error(err)
 			return err
@@ -160,11 +169,11 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 
 	if err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "validation failed")
-		return &destinationFilePath, nil, 0, err
+		s.SSK.SetFailed(ctx, tempName, "validation failed")
+		return &destinationFilePath, nil, 0, 0, err
 	}
 
-	s.SSK.SetFinalizing(tempName)
+	s.SSK.SetFinalizing(ctx, tempName)
 
 	// FIXME remove this lazy solution to prevent database deadlocks and fix it properly
 	s.submissionReceiverMutex.Lock()
@@ -178,8 +187,8 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 		submissionID, err = s.dal.StoreSubmission(dbs, submissionLevel)
 		if err != nil {
 			utils.LogCtx(ctx).Error(err)
-			s.SSK.SetFailed(tempName, "internal error")
-			return &destinationFilePath, nil, 0, dberr(err)
+			s.SSK.SetFailed(ctx, tempName, "internal error")
+			return &destinationFilePath, nil, 0, 0, dberr(err)
 		}
 	} else {
 		submissionID = *sid
@@ -188,17 +197,17 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 
 	// send notification about new file uploaded
 	if !isSubmissionNew {
-		if err := s.createNotification(dbs, uid, submissionID, constants.ActionUpload); err != nil {
-			s.SSK.SetFailed(tempName, "internal error")
-			return &destinationFilePath, nil, 0, err
+		if err := s.createNotification(dbs, uid, submissionID, constants.ActionUpload, nil); err != nil {
+			s.SSK.SetFailed(ctx, tempName, "internal error")
+			return &destinationFilePath, nil, 0, 0, err
 		}
 	}
 
 	// subscribe the author
 	if err := s.dal.SubscribeUserToSubmission(dbs, uid, submissionID); err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return &destinationFilePath, nil, 0, dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, nil, 0, 0, dberr(err)
 	}
 
 	isAudition := submissionLevel == constants.SubmissionLevelAudition
@@ -208,15 +217,15 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 		auditionSubscribeUserIDs, err := s.dal.GetUsersForUniversalNotification(dbs, uid, constants.ActionAuditionSubscribe)
 		if err != nil {
 			utils.LogCtx(dbs.Ctx()).Error(err)
-			s.SSK.SetFailed(tempName, "internal error")
-			return &destinationFilePath, nil, 0, dberr(err)
+			s.SSK.SetFailed(ctx, tempName, "internal error")
+			return &destinationFilePath, nil, 0, 0, dberr(err)
 		}
 
 		for _, subUID := range auditionSubscribeUserIDs {
 			if err := s.dal.SubscribeUserToSubmission(dbs, subUID, submissionID); err != nil {
 				utils.LogCtx(ctx).Error(err)
-				s.SSK.SetFailed(tempName, "internal error")
-				return &destinationFilePath, nil, 0, dberr(err)
+				s.SSK.SetFailed(ctx, tempName, "internal error")
+				return &destinationFilePath, nil, 0, 0, dberr(err)
 			}
 		}
 	}
@@ -238,13 +247,29 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 		if ok {
 			if me.Number == 1062 {
 				msg := fmt.Sprintf("file '%s' with checksums md5:%s sha256:%s already present in the DB", filename, sf.MD5Sum, sf.SHA256Sum)
-				s.SSK.SetFailed(tempName, msg)
-				return &destinationFilePath, nil, 0, perr(msg, http.StatusConflict)
+				s.SSK.SetFailed(ctx, tempName, msg)
+				return &destinationFilePath, nil, 0, 0, perr(msg, http.StatusConflict)
 			}
 		}
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return &destinationFilePath, nil, 0, dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, nil, 0, 0, dberr(err)
+	}
+
+	for _, r := range ruleResults {
+		r.SubmissionFileID = fid
+		r.CreatedAt = s.clock.Now()
+	}
+
+	if err := s.dal.StoreValidationRuleResults(dbs, fid, ruleResults); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, nil, 0, 0, dberr(err)
+	}
+
+	if err := s.storeSubmissionFileValidation(dbs, fid, vr); err != nil {
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, nil, 0, 0, err
 	}
 
 	utils.LogCtx(ctx).Debug("storing submission comment...")
@@ -259,8 +284,8 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 
 	if err := s.dal.StoreComment(dbs, c); err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return &destinationFilePath, nil, 0, dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, nil, 0, 0, dberr(err)
 	}
 
 	utils.LogCtx(ctx).Debug("processing curation meta...")
@@ -278,15 +303,16 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 
 	if err := s.dal.StoreCurationMeta(dbs, &vr.Meta); err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return &destinationFilePath, nil, 0, dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, nil, 0, 0, dberr(err)
 	}
 
 	// feed the curation feed
-	isCurationValid := len(vr.CurationErrors) == 0 && len(vr.CurationWarnings) == 0
+	ruleErrorCount, ruleWarningCount := countValidationRuleResults(ruleResults)
+	isCurationValid := len(vr.CurationErrors) == 0 && len(vr.CurationWarnings) == 0 && ruleErrorCount == 0 && ruleWarningCount == 0
 	if err := s.createCurationFeedMessage(dbs, uid, submissionID, isSubmissionNew, isCurationValid, &vr.Meta, isAudition); err != nil {
-		s.SSK.SetFailed(tempName, "internal error")
-		return &destinationFilePath, nil, 0, dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, nil, 0, 0, dberr(err)
 	}
 
 	errs, ectx := errgroup.WithContext(ctx)
@@ -333,15 +359,15 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 
 	if err := errs.Wait(); err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return &destinationFilePath, imageFilePaths, 0, err
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, imageFilePaths, 0, 0, err
 	}
 
 	for _, ci := range cis {
 		if _, err := s.dal.StoreCurationImage(dbs, ci); err != nil {
 			utils.LogCtx(ctx).Error(err)
-			s.SSK.SetFailed(tempName, "internal error")
-			return &destinationFilePath, imageFilePaths, 0, dberr(err)
+			s.SSK.SetFailed(ctx, tempName, "internal error")
+			return &destinationFilePath, imageFilePaths, 0, 0, dberr(err)
 		}
 	}
 
@@ -362,31 +388,32 @@ func (s *SiteService) processReceivedSubmission(ctx context.Context, dbs databas
 	if sc != nil {
 		if err := s.dal.StoreComment(dbs, sc); err != nil {
 			utils.LogCtx(ectx).Error(err)
-			s.SSK.SetFailed(tempName, "internal error")
-			return &destinationFilePath, imageFilePaths, 0, dberr(err)
+			s.SSK.SetFailed(ctx, tempName, "internal error")
+			return &destinationFilePath, imageFilePaths, 0, 0, dberr(err)
 		}
 	}
 
 	utils.LogCtx(ctx).Debug("processing bot event...")
 
-	bc := s.convertValidatorResponseToComment(vr)
+	bc := s.convertValidatorResponseToComment(vr, ruleResults)
 	if err := s.dal.StoreComment(dbs, bc); err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return &destinationFilePath, imageFilePaths, 0, dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, imageFilePaths, 0, 0, dberr(err)
 	}
 
 	if err := s.dal.UpdateSubmissionCacheTable(dbs, submissionID); err != nil {
 		utils.LogCtx(ctx).Error(err)
-		s.SSK.SetFailed(tempName, "internal error")
-		return &destinationFilePath, imageFilePaths, 0, dberr(err)
+		s.SSK.SetFailed(ctx, tempName, "internal error")
+		return &destinationFilePath, imageFilePaths, 0, 0, dberr(err)
 	}
 
-	return &destinationFilePath, imageFilePaths, submissionID, nil
+	return &destinationFilePath, imageFilePaths, submissionID, fid, nil
 }
 
-// convertValidatorResponseToComment produces appropriate comment based on validator response
-func (s *SiteService) convertValidatorResponseToComment(vr *types.ValidatorResponse) *types.Comment {
+// convertValidatorResponseToComment produces appropriate comment based on validator response and results of validation rules,
+// the rule results themselves are not part of the comment, they are shown separately
+func (s *SiteService) convertValidatorResponseToComment(vr *types.ValidatorResponse, ruleResults []*types.ValidationRuleResult) *types.Comment {
 	c := &types.Comment{
 		AuthorID:     constants.ValidatorID,
 		SubmissionID: vr.Meta.SubmissionID,
@@ -396,10 +423,14 @@ func (s *SiteService) convertValidatorResponseToComment(vr *types.ValidatorRespo
 	approvalMessage := "Looks good to me! 🤖"
 	message := ""
 
-	if len(vr.CurationErrors) > 0 {
+	ruleErrorCount, ruleWarningCount := countValidationRuleResults(ruleResults)
+	hasErrors := len(vr.CurationErrors) > 0 || ruleErrorCount > 0
+	hasWarnings := len(vr.CurationWarnings) > 0 || ruleWarningCount > 0
+
+	if hasErrors {
 		message += "Your curation is invalid:\n"
 	}
-	if len(vr.CurationErrors) == 0 && len(vr.CurationWarnings) > 0 {
+	if !hasErrors && hasWarnings {
 		message += "Your curation might have some problems:\n"
 	}
 
@@ -407,29 +438,57 @@ func (s *SiteService) convertValidatorResponseToComment(vr *types.ValidatorRespo
 		message += fmt.Sprintf("🚫 %s\n", e)
 	}
 	for _, w := range vr.CurationWarnings {
-		message += fmt.Sprintf("🚫 %s\n", w)
+		message += fmt.Sprintf("⚠️ %s\n", w)
+	}
+	if ruleErrorCount > 0 || ruleWarningCount > 0 {
+		message += fmt.Sprintf("📋 %d error(s) and %d warning(s) found by validation rules, see the validation results.\n", ruleErrorCount, ruleWarningCount)
 	}
 
 	c.Message = &message
 
 	c.Action = constants.ActionRequestChanges
-	if len(vr.CurationErrors) == 0 && len(vr.CurationWarnings) == 0 {
+	if !hasErrors && !hasWarnings {
 		c.Action = constants.ActionApprove
 		c.Message = &approvalMessage
 	}
 
+	// content of the curation was not checked, so a degraded result never approves the submission
+	if vr.IsDegraded {
+		degradedMessage := "ℹ️ The validator server was not available, only the curation metadata, logo and screenshot were checked. " +
+			"Full validation is pending, the file will be revalidated automatically once the validator server is back."
+		if c.Action == constants.ActionApprove {
+			c.Action = constants.ActionComment
+		} else {
+			degradedMessage = *c.Message + "\n\n" + degradedMessage
+		}
+		c.Message = &degradedMessage
+	}
+
 	return c
 }
 
-func (s *SiteService) computeSimilarityComment(dbs database.DBSession, sid *int64, meta *types.CurationMeta) (*string, error) {
+func (s *SiteService) computeSimilarityComment(dbs database.DBSession, sid *int64, meta *types.CurationMeta, contentHashes []*types.ArchiveContentHash) (*string, error) {
 	titleSimilarities, launchCommandSimilarities, err := s.getSimilarityScores(dbs, 0.9, meta.Title, meta.LaunchCommand)
 	if err != nil {
 		utils.LogCtx(dbs.Ctx()).Error(err)
 		return nil, dberr(err)
 	}
 
+	contentMatches, err := s.findContentHashMatches(dbs, sid, contentHashes)
+	if err != nil {
+		return nil, err
+	}
+
 	var sb strings.Builder
 
+	// identical content is a much stronger signal than similar titles, so it goes first
+	if len(contentMatches) > 0 {
+		sb.Write([]byte(formatContentHashMatches(contentMatches, contentHashes)))
+		if len(titleSimilarities) > 1 || len(launchCommandSimilarities) > 1 {
+			sb.Write([]byte("\n"))
+		}
+	}
+
 	if len(titleSimilarities) > 1 || len(launchCommandSimilarities) > 1 {
 
 		strID := ""
@@ -459,7 +518,9 @@ func (s *SiteService) computeSimilarityComment(dbs database.DBSession, sid *int6
 				sb.Write([]byte(fmt.Sprintf("(%.1f%%) ID %s - Launch Command - '%s'\n", ts.LaunchCommandRatio*100, ts.ID, *ts.LaunchCommand)))
 			}
 		}
+	}
 
+	if sb.Len() > 0 {
 		sb.Write([]byte("\n"))
 		sb.Write([]byte("This could mean that your submission is a duplicate."))
 	}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+)
+
+// trashPageSize is the number of the most recently deleted items shown in the trash
+const trashPageSize = 500
+
+// restoreErr converts errors of the restore DAL methods
+func restoreErr(err error, what string) error {
+	if err == sql.ErrNoRows {
+		return perr(fmt.Sprintf("deleted %s not found", what), http.StatusNotFound)
+	}
+	if err.Error() == constants.ErrorCannotRestoreInDeletedSubmission || err.Error() == constants.ErrorCannotRestorePurgedFile {
+		return perr(err.Error(), http.StatusConflict)
+	}
+	return dberr(err)
+}
+
+// RestoreSubmission restores a deleted submission with the files and comments which were deleted together with it
+func (s *SiteService) RestoreSubmission(ctx context.Context, sid int64) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.RestoreSubmission(dbs, sid); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return restoreErr(err, "submission")
+	}
+
+	submissions, _, err := s.dal.SearchSubmissions(dbs, &types.SubmissionsFilter{SubmissionIDs: []int64{sid}})
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	if len(submissions) == 0 {
+		return perr(fmt.Sprintf("submission %d not found", sid), http.StatusNotFound)
+	}
+
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionRestoreSubmission, constants.AuditTargetSubmission, &sid, nil, submissions[0]); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	s.updateSimilarityIndex(ctx, sid, submissions[0].FileID)
+
+	return nil
+}
+
+// RestoreSubmissionFile restores a submission file deleted on its own
+func (s *SiteService) RestoreSubmissionFile(ctx context.Context, sfid int64) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.RestoreSubmissionFile(dbs, sfid); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return restoreErr(err, "submission file")
+	}
+
+	sfs, err := s.dal.GetSubmissionFiles(dbs, []int64{sfid})
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionRestoreSubmissionFile, constants.AuditTargetSubmissionFile, &sfid, nil, sfs[0]); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	submissions, _, err := s.dal.SearchSubmissions(dbs, &types.SubmissionsFilter{SubmissionIDs: []int64{sfs[0].SubmissionID}})
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	// the restored file may be the newest one again
+	if len(submissions) > 0 {
+		s.updateSimilarityIndex(ctx, submissions[0].SubmissionID, submissions[0].FileID)
+	}
+
+	return nil
+}
+
+// RestoreComment restores a comment deleted on its own
+func (s *SiteService) RestoreComment(ctx context.Context, cid int64) error {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+	defer dbs.Rollback()
+
+	if err := s.dal.RestoreComment(dbs, cid); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return restoreErr(err, "comment")
+	}
+
+	c, err := s.dal.GetCommentByID(dbs, cid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := s.storeAuditLogEntry(dbs, constants.AuditActionRestoreComment, constants.AuditTargetComment, &cid, nil, c); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	if err := dbs.Commit(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return dberr(err)
+	}
+
+	return nil
+}
+
+func (s *SiteService) GetTrashPageData(ctx context.Context) (*types.TrashPageData, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	bpd, err := s.GetBasePageData(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.dal.GetDeletedItems(dbs, trashPageSize)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	pageData := &types.TrashPageData{
+		BasePageData: *bpd,
+		Items:        items,
+	}
+
+	return pageData, nil
+}
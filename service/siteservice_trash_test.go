@@ -0,0 +1,47 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_restoreErr(t *testing.T) {
+	dbErr := fmt.Errorf("connection lost")
+
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{
+			name: "not deleted",
+			err:  sql.ErrNoRows,
+			want: perr("deleted comment not found", http.StatusNotFound),
+		},
+		{
+			name: "parent submission deleted",
+			err:  fmt.Errorf(constants.ErrorCannotRestoreInDeletedSubmission),
+			want: perr(constants.ErrorCannotRestoreInDeletedSubmission, http.StatusConflict),
+		},
+		{
+			name: "file purged",
+			err:  fmt.Errorf(constants.ErrorCannotRestorePurgedFile),
+			want: perr(constants.ErrorCannotRestorePurgedFile, http.StatusConflict),
+		},
+		{
+			name: "database error",
+			err:  dbErr,
+			want: dberr(dbErr),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, restoreErr(tt.err, "comment"))
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/database"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// storeSubmissionFileValidation stores the validator response of a submission file, image data is left out as the images are stored separately
+func (s *SiteService) storeSubmissionFileValidation(dbs database.DBSession, sfid int64, vr *types.ValidatorResponse) error {
+	stored := *vr
+	stored.Images = make([]types.ValidatorResponseImage, 0, len(vr.Images))
+	for _, image := range vr.Images {
+		stored.Images = append(stored.Images, types.ValidatorResponseImage{Type: image.Type})
+	}
+
+	if err := s.dal.StoreSubmissionFileValidation(dbs, sfid, &stored, buildValidationMessages(vr), s.clock.Now()); err != nil {
+		utils.LogCtx(dbs.Ctx()).Error(err)
+		return dberr(err)
+	}
+
+	return nil
+}
+
+// GetSubmissionFileValidation returns the stored validator response and validation rule results of a submission file
+func (s *SiteService) GetSubmissionFileValidation(ctx context.Context, sid, sfid int64) (*types.SubmissionFileValidation, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	sfs, err := s.dal.GetExtendedSubmissionFilesBySubmissionID(dbs, sid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	found := false
+	for _, sf := range sfs {
+		if sf.FileID == sfid {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, perr("submission file not found", http.StatusNotFound)
+	}
+
+	sfv, err := s.dal.GetSubmissionFileValidation(dbs, sfid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, perr("validation results of the submission file are not available", http.StatusNotFound)
+		}
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	sfv.RuleResults, err = s.dal.GetValidationRuleResultsBySubmissionFileID(dbs, sfid)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	return sfv, nil
+}
+
+// RevalidateSubmissionFiles validates given submission files again, or newest files of all submissions which were not added yet.
+// A new bot comment is posted only if the outcome differs from the latest bot comment of the submission.
+func (s *SiteService) RevalidateSubmissionFiles(l *logrus.Entry, sfids []int64) {
+	ctx := context.WithValue(context.Background(), utils.CtxKeys.Log, l)
+
+	targets, err := func() ([]*types.SubmissionFileValidationTarget, error) {
+		dbs, err := s.dal.NewSession(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer dbs.Rollback()
+
+		return s.dal.GetSubmissionFileValidationTargets(dbs, sfids)
+	}()
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return
+	}
+
+	s.revalidateSubmissionFileTargets(ctx, targets)
+}
+
+// RevalidateDegradedSubmissionFiles validates again the newest files of submissions which were not added yet,
+// if they were validated only by the local validator while the validator server was not available.
+// Returns the amount of files revalidated and the amount of files which failed, e.g. because the validator server is still not available.
+func (s *SiteService) RevalidateDegradedSubmissionFiles(ctx context.Context) (int, int, error) {
+	targets, err := func() ([]*types.SubmissionFileValidationTarget, error) {
+		dbs, err := s.dal.NewSession(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer dbs.Rollback()
+
+		return s.dal.GetDegradedSubmissionFileValidationTargets(dbs)
+	}()
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return 0, 0, dberr(err)
+	}
+
+	failedCount, err := s.revalidateSubmissionFileTargets(ctx, targets)
+	return len(targets) - failedCount, failedCount, err
+}
+
+// revalidateSubmissionFileTargets revalidates the targets one by one, a failed target does not stop the rest.
+// Returns the amount of failed targets, or an error if the context is done before all targets are revalidated.
+func (s *SiteService) revalidateSubmissionFileTargets(ctx context.Context, targets []*types.SubmissionFileValidationTarget) (int, error) {
+	l := utils.LogCtx(ctx)
+	l.WithField("amount", len(targets)).Info("revalidating submission files")
+
+	changedCount := 0
+	failedCount := 0
+
+	for _, t := range targets {
+		if err := ctx.Err(); err != nil {
+			return failedCount, err
+		}
+		tl := l.WithFields(logrus.Fields{"submissionID": t.SubmissionID, "submissionFileID": t.FileID})
+		isChanged, err := s.revalidateSubmissionFile(tl, t)
+		if err != nil {
+			tl.Error(err)
+			failedCount++
+			continue
+		}
+		if isChanged {
+			changedCount++
+		}
+	}
+
+	l.WithFields(logrus.Fields{"amount": len(targets), "changed": changedCount, "failed": failedCount}).Info("submission files revalidated")
+	return failedCount, nil
+}
+
+// revalidateSubmissionFile replaces stored validation results of the submission file, returns true if a new bot comment was posted
+func (s *SiteService) revalidateSubmissionFile(l *logrus.Entry, t *types.SubmissionFileValidationTarget) (bool, error) {
+	ctx := context.WithValue(context.Background(), utils.CtxKeys.Log, l)
+
+	vr, err := s.validator.ProvideArchiveForValidation(s.submissionsDir + "/" + t.CurrentFilename)
+	if err != nil {
+		return false, err
+	}
+	// metadata-only results would override the full ones from the time of the upload
+	if vr.IsDegraded {
+		return false, fmt.Errorf("validator server is not available, not revalidating with the local validator")
+	}
+
+	vr.Meta.SubmissionID = t.SubmissionID
+	vr.Meta.SubmissionFileID = t.FileID
+	ruleResults := s.validationRuleEngine.Evaluate(vr)
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer dbs.Rollback()
+
+	for _, r := range ruleResults {
+		r.SubmissionFileID = t.FileID
+		r.CreatedAt = s.clock.Now()
+	}
+
+	if err := s.dal.DeleteValidationRuleResults(dbs, t.FileID); err != nil {
+		return false, err
+	}
+	if err := s.dal.StoreValidationRuleResults(dbs, t.FileID, ruleResults); err != nil {
+		return false, err
+	}
+	if err := s.storeSubmissionFileValidation(dbs, t.FileID, vr); err != nil {
+		return false, err
+	}
+
+	// the bot action of the submission is decided by the newest file, older files only get their results updated
+	isChanged := false
+	if t.IsNewest {
+		comments, err := s.dal.GetExtendedCommentsBySubmissionID(dbs, t.SubmissionID)
+		if err != nil {
+			return false, err
+		}
+
+		bc := s.convertValidatorResponseToComment(vr, ruleResults)
+		if isValidatorOutcomeChanged(comments, bc) {
+			if err := s.dal.StoreComment(dbs, bc); err != nil {
+				return false, err
+			}
+			if err := s.dal.UpdateSubmissionCacheTable(dbs, t.SubmissionID); err != nil {
+				return false, err
+			}
+			isChanged = true
+		}
+	}
+
+	if err := dbs.Commit(); err != nil {
+		return false, err
+	}
+
+	l.WithField("isChanged", isChanged).Debug("submission file revalidated")
+	return isChanged, nil
+}
+
+// isValidatorOutcomeChanged compares a new bot comment with the latest bot comment of the submission
+func isValidatorOutcomeChanged(comments []*types.ExtendedComment, bc *types.Comment) bool {
+	var latest *types.ExtendedComment
+	for _, c := range comments {
+		if c.AuthorID == constants.ValidatorID {
+			latest = c
+		}
+	}
+
+	if latest == nil {
+		return true
+	}
+
+	return latest.Action != bc.Action || utils.Unpointify(latest.Message) != utils.Unpointify(bc.Message)
+}
+
+// buildValidationMessages converts errors and warnings of the validator response to coded messages
+func buildValidationMessages(vr *types.ValidatorResponse) []*types.ValidationMessage {
+	result := make([]*types.ValidationMessage, 0, len(vr.CurationErrors)+len(vr.CurationWarnings))
+	for _, e := range vr.CurationErrors {
+		result = append(result, &types.ValidationMessage{Kind: constants.ValidationSeverityError, Code: validationMessageCode(e), Message: e})
+	}
+	for _, w := range vr.CurationWarnings {
+		result = append(result, &types.ValidationMessage{Kind: constants.ValidationSeverityWarning, Code: validationMessageCode(w), Message: w})
+	}
+	return result
+}
+
+// validationMessageCodes maps validator messages to stable codes, the validator does not provide codes on its own.
+// The codes are stored and used in filters, so a code must not change once it's here, only new patterns are added.
+var validationMessageCodes = []struct {
+	code    string
+	pattern *regexp.Regexp
+}{
+	{"meta-missing", regexp.MustCompile(`(?i)^meta file is missing`)},
+	{"meta-invalid", regexp.MustCompile(`(?i)^failed to parse the meta file`)},
+	{"logo-missing", regexp.MustCompile(`(?i)^logo file is missing`)},
+	{"screenshot-missing", regexp.MustCompile(`(?i)^screenshot file is missing`)},
+	{"title-missing", regexp.MustCompile(`(?i)^title is missing`)},
+	{"tags-missing", regexp.MustCompile(`(?i)^tags (is|are) missing`)},
+	{"source-missing", regexp.MustCompile(`(?i)^source is missing`)},
+	{"platform-missing", regexp.MustCompile(`(?i)^platform is missing`)},
+	{"application-path-missing", regexp.MustCompile(`(?i)^application path is missing`)},
+	{"launch-command-missing", regexp.MustCompile(`(?i)^launch command is missing`)},
+	{"library-invalid", regexp.MustCompile(`(?i)^library .* is not valid`)},
+	{"extreme-invalid", regexp.MustCompile(`(?i)^extreme .* is not valid`)},
+	{"tag-unknown", regexp.MustCompile(`(?i)^tag .* is not a known tag`)},
+	{"tags-unverified", regexp.MustCompile(`(?i)^tags could not be verified`)},
+	{"platform-discouraged", regexp.MustCompile(`(?i)^don't use .* as the platform`)},
+}
+
+// validationMessageCode returns the code of a validator message, or the unknown code if the message is not in the code table
+func validationMessageCode(msg string) string {
+	msg = strings.TrimSpace(msg)
+	for _, c := range validationMessageCodes {
+		if c.pattern.MatchString(msg) {
+			return c.code
+		}
+	}
+	return constants.ValidationMessageCodeUnknown
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validationMessageCode(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want string
+	}{
+		{"Logo file is missing.", "logo-missing"},
+		{"Tag 'Puzzel' is not a known tag, please verify (did you write it correctly?).", "tag-unknown"},
+		{"Tag 'Action RPG' is not a known tag, please verify (did you write it correctly?).", "tag-unknown"},
+		{"Tags is missing.", "tags-missing"},
+		{"Tags could not be verified, the tag list is not available.", "tags-unverified"},
+		{"Launch Command is missing.", "launch-command-missing"},
+		{"Library 'games' is not valid, use 'arcade' or 'theatre'.", "library-invalid"},
+		{"Don't use \"Flash\" as the platform.", "platform-discouraged"},
+		{"Failed to parse the meta file: yaml: line 3: mapping values are not allowed in this context", "meta-invalid"},
+		{"File `content/foo.swf` is 12 MB large.", constants.ValidationMessageCodeUnknown},
+		{"'foo'", constants.ValidationMessageCodeUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.msg, func(t *testing.T) {
+			assert.Equal(t, tt.want, validationMessageCode(tt.msg))
+		})
+	}
+}
+
+func Test_buildValidationMessages(t *testing.T) {
+	vr := &types.ValidatorResponse{
+		CurationErrors:   []string{"Logo file is missing."},
+		CurationWarnings: []string{"Tags could not be verified, the tag list is not available."},
+	}
+
+	want := []*types.ValidationMessage{
+		{Kind: "error", Code: "logo-missing", Message: "Logo file is missing."},
+		{Kind: "warning", Code: "tags-unverified", Message: "Tags could not be verified, the tag list is not available."},
+	}
+
+	assert.Equal(t, want, buildValidationMessages(vr))
+}
+
+func Test_isValidatorOutcomeChanged(t *testing.T) {
+	approved := "Looks good to me! 🤖"
+	refused := "There seems to be a problem with your submission"
+	bc := &types.Comment{AuthorID: constants.ValidatorID, Action: constants.ActionApprove, Message: &approved}
+
+	tests := []struct {
+		name     string
+		comments []*types.ExtendedComment
+		want     bool
+	}{
+		{"no bot comment", []*types.ExtendedComment{{AuthorID: 1, Action: constants.ActionComment}}, true},
+		{"same outcome", []*types.ExtendedComment{{AuthorID: constants.ValidatorID, Action: constants.ActionApprove, Message: &approved}}, false},
+		{"different action", []*types.ExtendedComment{{AuthorID: constants.ValidatorID, Action: constants.ActionRequestChanges, Message: &approved}}, true},
+		{"different message", []*types.ExtendedComment{{AuthorID: constants.ValidatorID, Action: constants.ActionApprove, Message: &refused}}, true},
+		{"latest bot comment decides", []*types.ExtendedComment{
+			{AuthorID: constants.ValidatorID, Action: constants.ActionApprove, Message: &approved},
+			{AuthorID: 1, Action: constants.ActionComment},
+			{AuthorID: constants.ValidatorID, Action: constants.ActionRequestChanges, Message: &refused},
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isValidatorOutcomeChanged(tt.comments, bc))
+		})
+	}
+}
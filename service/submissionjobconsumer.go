@@ -0,0 +1,318 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// submissionJobStaleTimeout is the time after which a running job without a refreshed lock is considered abandoned,
+// which happens when the instance processing it gets restarted or dies
+const submissionJobStaleTimeout = time.Minute * 5
+
+// RunSubmissionJobConsumer processes queued submission jobs, multiple consumers can run in parallel
+func (s *SiteService) RunSubmissionJobConsumer(logger *logrus.Entry, ctx context.Context, wg *sync.WaitGroup, consumerID int) {
+	defer wg.Done()
+	l := logger.WithField("serviceName", "submissionJobConsumer").WithField("consumerID", consumerID)
+	defer l.Info("consumer stopped")
+
+	const errorSleepTime = time.Second * 60
+
+	// jobs waiting for a retry, abandoned jobs or jobs stored by another instance are not announced, so poll for them as well
+	ticker := time.NewTicker(time.Second * 30)
+	defer ticker.Stop()
+
+	for {
+		for {
+			processed, err := s.consumeSubmissionJob(l, ctx)
+			if err != nil {
+				if err == context.Canceled {
+					break
+				}
+				l.Error(err)
+				l.Debugf("sleeping for %f seconds", errorSleepTime.Seconds())
+				select {
+				case <-ctx.Done():
+				case <-time.After(errorSleepTime):
+				}
+				break
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			l.Info("context cancelled, stopping submission job consumer")
+			return
+		case <-s.submissionJobQueueNotEmpty:
+		case <-ticker.C:
+		}
+	}
+}
+
+// consumeSubmissionJob claims and processes a single job, returns false if there was no job to process
+func (s *SiteService) consumeSubmissionJob(l *logrus.Entry, ctx context.Context) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer dbs.Rollback()
+
+	j, err := s.dal.ClaimNextSubmissionJob(dbs, s.clock.Now().Add(-submissionJobStaleTimeout))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			l.Debug("submission job queue is empty")
+			return false, nil
+		}
+		return false, err
+	}
+
+	if err := dbs.Commit(); err != nil {
+		return false, err
+	}
+
+	// there might be more jobs in the queue, let another consumer pick them up
+	s.announceSubmissionJob()
+
+	s.processSubmissionJob(l, ctx, j)
+
+	return true, nil
+}
+
+// processSubmissionJob runs the submission processing pipeline and stores the outcome of the job
+func (s *SiteService) processSubmissionJob(l *logrus.Entry, ctx context.Context, j *types.SubmissionJob) {
+	jl := l.WithFields(logrus.Fields{"jobID": j.ID, "tempName": j.TempName, "uid": j.UserID, "attempt": j.Attempts})
+	jl.Debug("processing submission job")
+
+	// let the job finish during shutdown instead of leaving half-processed files around
+	jctx := context.WithValue(utils.ValueOnlyContext{Context: ctx}, utils.CtxKeys.Log, jl)
+	jctx = context.WithValue(jctx, utils.CtxKeys.UserID, j.UserID)
+
+	tctx, cancel := context.WithCancel(jctx)
+	go s.keepSubmissionJobLocked(tctx, j.ID)
+
+	resumableParams := &types.ResumableParams{
+		ResumableIdentifier:  j.ResumableIdentifier,
+		ResumableFilename:    j.Filename,
+		ResumableTotalSize:   j.Size,
+		ResumableTotalChunks: j.ChunkCount,
+	}
+
+	sid, processErr := s.processReceivedResumableSubmission(jctx, j.UserID, j.SubmissionID, resumableParams, j.TempName)
+	cancel()
+
+	isFinished := true
+
+	err := func() error {
+		dbs, err := s.dal.NewSession(jctx)
+		if err != nil {
+			return err
+		}
+		defer dbs.Rollback()
+
+		if processErr == nil {
+			if err := s.dal.MarkSubmissionJobAsDone(dbs, j.ID, sid); err != nil {
+				return err
+			}
+			return dbs.Commit()
+		}
+
+		jl.Error(processErr)
+
+		// the status message is what the user has been told about the failure
+		msg := "internal error"
+		fj, err := s.dal.GetSubmissionJobByTempName(dbs, j.TempName)
+		if err != nil {
+			return err
+		}
+		if fj.StatusMessage != nil {
+			msg = *fj.StatusMessage
+		}
+
+		if isSubmissionJobErrorRetryable(processErr) && j.Attempts < constants.SubmissionJobMaxAttempts {
+			isFinished = false
+			nextAttemptAt := s.clock.Now().Add(time.Minute * time.Duration(j.Attempts))
+			jl.WithField("nextAttemptAt", nextAttemptAt.Unix()).Debug("requeueing submission job")
+			if err := s.dal.RequeueSubmissionJob(dbs, j.ID, msg, nextAttemptAt); err != nil {
+				return err
+			}
+			return dbs.Commit()
+		}
+
+		if err := s.dal.MarkSubmissionJobAsFailed(dbs, j.ID, msg); err != nil {
+			return err
+		}
+		return dbs.Commit()
+	}()
+	if err != nil {
+		// the job stays locked and will be picked up again once the lock goes stale
+		jl.Error(err)
+		return
+	}
+
+	if isFinished {
+		jl.Debug("deleting the resumable file chunks")
+		if err := s.resumableUploadService.DeleteFile(j.UserID, j.ResumableIdentifier, j.ChunkCount); err != nil {
+			jl.Error(err)
+		}
+	}
+}
+
+// keepSubmissionJobLocked periodically refreshes the job lock until the context is cancelled
+func (s *SiteService) keepSubmissionJobLocked(ctx context.Context, jid int64) {
+	ticker := time.NewTicker(submissionJobStaleTimeout / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := func() error {
+				dbs, err := s.dal.NewSession(ctx)
+				if err != nil {
+					return err
+				}
+				defer dbs.Rollback()
+
+				if err := s.dal.RefreshSubmissionJobLock(dbs, jid); err != nil {
+					return err
+				}
+				return dbs.Commit()
+			}()
+			if err != nil && err != context.Canceled {
+				utils.LogCtx(ctx).Error(err)
+			}
+		}
+	}
+}
+
+// isSubmissionJobErrorRetryable returns false for errors caused by the submission itself, those would fail again
+func isSubmissionJobErrorRetryable(err error) bool {
+	if pe, ok := err.(constants.PublicError); ok {
+		return pe.Status >= http.StatusInternalServerError
+	}
+	return true
+}
+
+func (s *SiteService) announceSubmissionJob() {
+	select {
+	// non-blocking announce that something is in the queue
+	case s.submissionJobQueueNotEmpty <- true:
+	default:
+	}
+}
+
+// GetUploadStatus returns the status of a submission job, or nil if there's no job with the given temp name
+func (s *SiteService) GetUploadStatus(ctx context.Context, tempName string) (*types.SubmissionStatus, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	j, err := s.dal.GetSubmissionJobByTempName(dbs, tempName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	switch j.State {
+	case constants.SubmissionJobStateQueued:
+		msg := "waiting in the queue"
+		if j.LastError != nil {
+			msg = fmt.Sprintf("attempt %d failed (%s), waiting for a retry", j.Attempts, *j.LastError)
+		}
+		return &types.SubmissionStatus{Status: constants.SubmissionStatusReceived, Message: &msg}, nil
+	case constants.SubmissionJobStateRunning:
+		// a failed attempt might still be retried, the job state is what decides whether the job failed
+		if j.Status == nil || *j.Status == constants.SubmissionStatusFailed {
+			return &types.SubmissionStatus{Status: constants.SubmissionStatusReceived, Message: j.StatusMessage}, nil
+		}
+		return &types.SubmissionStatus{Status: *j.Status, Message: j.StatusMessage}, nil
+	case constants.SubmissionJobStateDone:
+		return &types.SubmissionStatus{Status: constants.SubmissionStatusSuccess, SubmissionID: j.ResultSubmissionID}, nil
+	default:
+		return &types.SubmissionStatus{Status: constants.SubmissionStatusFailed, Message: j.LastError}, nil
+	}
+}
+
+// GetUploadStatusTimeline returns all stages a submission job went through, with timestamps
+func (s *SiteService) GetUploadStatusTimeline(ctx context.Context, tempName string) ([]*types.SubmissionStatusHistoryEntry, error) {
+	dbs, err := s.dal.NewSession(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+	defer dbs.Rollback()
+
+	timeline, err := s.dal.GetSubmissionJobStatusHistory(dbs, tempName)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, dberr(err)
+	}
+
+	return timeline, nil
+}
+
+// RunSubmissionJobCleaner periodically deletes finished submission jobs, together with their status timeline
+func (s *SiteService) RunSubmissionJobCleaner(logger *logrus.Entry, ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	l := logger.WithField("serviceName", "submissionJobCleaner")
+	defer l.Info("cleaner stopped")
+
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		err := func() error {
+			dbs, err := s.dal.NewSession(ctx)
+			if err != nil {
+				return err
+			}
+			defer dbs.Rollback()
+
+			count, err := s.dal.DeleteFinishedSubmissionJobs(dbs, s.clock.Now().Add(-constants.SubmissionJobTTL))
+			if err != nil {
+				return err
+			}
+
+			if err := dbs.Commit(); err != nil {
+				return err
+			}
+
+			l.WithField("amount", count).Debug("finished submission jobs deleted")
+			return nil
+		}()
+		if err != nil && err != context.Canceled {
+			l.Error(err)
+		}
+
+		s.SSK.prune(s.clock.Now().Add(-constants.SubmissionJobTTL))
+
+		select {
+		case <-ctx.Done():
+			l.Info("context cancelled, stopping submission job cleaner")
+			return
+		case <-ticker.C:
+		}
+	}
+}
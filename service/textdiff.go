@@ -0,0 +1,77 @@
+package service
+
+import (
+	"regexp"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+)
+
+var textDiffTokenRegexp = regexp.MustCompile(`\s+|\S+`)
+
+// textDiffMaxCells limits the size of the LCS table, bigger texts are diffed as a whole
+const textDiffMaxCells = 4_000_000
+
+// diffText compares two texts word by word, whitespace is kept so that the chunks can be joined back into the texts
+func diffText(from, to string) []*types.TextDiffChunk {
+	a := textDiffTokenRegexp.FindAllString(from, -1)
+	b := textDiffTokenRegexp.FindAllString(to, -1)
+
+	result := make([]*types.TextDiffChunk, 0)
+	add := func(kind, text string) {
+		if len(result) > 0 && result[len(result)-1].Kind == kind {
+			result[len(result)-1].Text += text
+			return
+		}
+		result = append(result, &types.TextDiffChunk{Kind: kind, Text: text})
+	}
+
+	if (len(a)+1)*(len(b)+1) > textDiffMaxCells {
+		if from != "" {
+			add("removed", from)
+		}
+		if to != "" {
+			add("added", to)
+		}
+		return result
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			add("same", a[i])
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			add("removed", a[i])
+			i++
+		} else {
+			add("added", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add("removed", a[i])
+	}
+	for ; j < len(b); j++ {
+		add("added", b[j])
+	}
+
+	return result
+}
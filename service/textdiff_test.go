@@ -0,0 +1,55 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func joinTextDiff(chunks []*types.TextDiffChunk, skip string) string {
+	var sb strings.Builder
+	for _, c := range chunks {
+		if c.Kind != skip {
+			sb.WriteString(c.Text)
+		}
+	}
+	return sb.String()
+}
+
+func Test_diffText(t *testing.T) {
+	tests := []struct {
+		name string
+		from string
+		to   string
+		want []*types.TextDiffChunk
+	}{
+		{name: "unchanged", from: "same text", to: "same text", want: []*types.TextDiffChunk{{Kind: "same", Text: "same text"}}},
+		{name: "fixed typo", from: "the gmae crashes", to: "the game crashes", want: []*types.TextDiffChunk{
+			{Kind: "same", Text: "the "}, {Kind: "removed", Text: "gmae"}, {Kind: "added", Text: "game"}, {Kind: "same", Text: " crashes"},
+		}},
+		{name: "appended line", from: "broken audio", to: "broken audio\nalso missing images", want: []*types.TextDiffChunk{
+			{Kind: "same", Text: "broken audio"}, {Kind: "added", Text: "\nalso missing images"},
+		}},
+		{name: "from empty", from: "", to: "new", want: []*types.TextDiffChunk{{Kind: "added", Text: "new"}}},
+		{name: "to empty", from: "old", to: "", want: []*types.TextDiffChunk{{Kind: "removed", Text: "old"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffText(tt.from, tt.to)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.from, joinTextDiff(got, "added"))
+			assert.Equal(t, tt.to, joinTextDiff(got, "removed"))
+		})
+	}
+}
+
+func Test_diffText_large(t *testing.T) {
+	from := strings.Repeat("a ", 3000)
+	to := strings.Repeat("b ", 3000)
+
+	got := diffText(from, to)
+	assert.Equal(t, from, joinTextDiff(got, "added"))
+	assert.Equal(t, to, joinTextDiff(got, "removed"))
+}
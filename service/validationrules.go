@@ -0,0 +1,249 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// validationCheck returns a message for every violation of a rule found in the validator response
+type validationCheck func(vr *types.ValidatorResponse) []string
+
+// validationRuleKinds maps kinds of rules to constructors of their checks, new kinds of rules are registered here
+var validationRuleKinds = map[string]func(params json.RawMessage) (validationCheck, error){
+	"launch_command_pattern":  newLaunchCommandPatternCheck,
+	"library_extreme":         newLibraryExtremeCheck,
+	"forbidden_source_domain": newForbiddenSourceDomainCheck,
+}
+
+// ValidationRule is a single configured rule of the validation rule engine
+type ValidationRule struct {
+	ID             string          `json:"id"`
+	Kind           string          `json:"kind"`
+	Severity       string          `json:"severity"`
+	ExplanationURL *string         `json:"explanation_url"`
+	Params         json.RawMessage `json:"params"`
+	check          validationCheck
+}
+
+// ValidationRuleEngine post-processes validator responses with server-side rules
+type ValidationRuleEngine struct {
+	rules []*ValidationRule
+}
+
+func NewValidationRuleEngine(rules []*ValidationRule) *ValidationRuleEngine {
+	return &ValidationRuleEngine{
+		rules: rules,
+	}
+}
+
+// LoadValidationRules loads rules from a JSON file
+func LoadValidationRules(filePath string) ([]*ValidationRule, error) {
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	return parseValidationRules(b)
+}
+
+// LoadOptionalValidationRules loads rules from a JSON file, the rules are optional so an unset path or a missing file gives no rules.
+// A file which exists but is invalid is still an error.
+func LoadOptionalValidationRules(l *logrus.Entry, filePath string) ([]*ValidationRule, error) {
+	if filePath == "" {
+		l.Warn("validation rules file is not set, no validation rules are used")
+		return []*ValidationRule{}, nil
+	}
+
+	rules, err := LoadValidationRules(filePath)
+	if os.IsNotExist(err) {
+		l.WithField("filePath", filePath).Warn("validation rules file does not exist, no validation rules are used")
+		return []*ValidationRule{}, nil
+	}
+
+	return rules, err
+}
+
+// parseValidationRules parses a JSON array of rules and builds their checks
+func parseValidationRules(b []byte) ([]*ValidationRule, error) {
+	var rules []*ValidationRule
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]bool, len(rules))
+
+	for _, rule := range rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("validation rule of kind '%s' has no id", rule.Kind)
+		}
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("duplicate validation rule id '%s'", rule.ID)
+		}
+		ids[rule.ID] = true
+
+		switch rule.Severity {
+		case constants.ValidationSeverityError, constants.ValidationSeverityWarning, constants.ValidationSeverityInfo:
+		default:
+			return nil, fmt.Errorf("validation rule '%s' has invalid severity '%s'", rule.ID, rule.Severity)
+		}
+
+		newCheck, ok := validationRuleKinds[rule.Kind]
+		if !ok {
+			return nil, fmt.Errorf("validation rule '%s' has unknown kind '%s'", rule.ID, rule.Kind)
+		}
+		check, err := newCheck(rule.Params)
+		if err != nil {
+			return nil, fmt.Errorf("validation rule '%s' has invalid params: %w", rule.ID, err)
+		}
+		rule.check = check
+	}
+
+	return rules, nil
+}
+
+// Evaluate runs all rules against the validator response, the results are not bound to a submission file yet
+func (e *ValidationRuleEngine) Evaluate(vr *types.ValidatorResponse) []*types.ValidationRuleResult {
+	result := make([]*types.ValidationRuleResult, 0)
+	if e == nil {
+		return result
+	}
+
+	for _, rule := range e.rules {
+		for _, msg := range rule.check(vr) {
+			result = append(result, &types.ValidationRuleResult{
+				RuleID:         rule.ID,
+				Severity:       rule.Severity,
+				Message:        msg,
+				ExplanationURL: rule.ExplanationURL,
+			})
+		}
+	}
+
+	return result
+}
+
+// countValidationRuleResults counts results which should prevent the curation from being approved by the bot
+func countValidationRuleResults(results []*types.ValidationRuleResult) (int, int) {
+	errorCount := 0
+	warningCount := 0
+	for _, r := range results {
+		switch r.Severity {
+		case constants.ValidationSeverityError:
+			errorCount++
+		case constants.ValidationSeverityWarning:
+			warningCount++
+		}
+	}
+	return errorCount, warningCount
+}
+
+// newLaunchCommandPatternCheck requires launch commands of a platform to match a regular expression
+func newLaunchCommandPatternCheck(params json.RawMessage) (validationCheck, error) {
+	var p struct {
+		Platform string `json:"platform"`
+		Pattern  string `json:"pattern"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Platform == "" || p.Pattern == "" {
+		return nil, fmt.Errorf("platform and pattern are required")
+	}
+	re, err := regexp.Compile(p.Pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(vr *types.ValidatorResponse) []string {
+		if !strings.EqualFold(strings.TrimSpace(utils.Unpointify(vr.Meta.Platform)), p.Platform) {
+			return nil
+		}
+		launchCommand := strings.TrimSpace(utils.Unpointify(vr.Meta.LaunchCommand))
+		if launchCommand == "" || re.MatchString(launchCommand) {
+			return nil
+		}
+		return []string{fmt.Sprintf("Launch command '%s' does not look like a valid %s launch command.", launchCommand, p.Platform)}
+	}, nil
+}
+
+// newLibraryExtremeCheck requires curations in a library to have the given extreme flag
+func newLibraryExtremeCheck(params json.RawMessage) (validationCheck, error) {
+	var p struct {
+		Library string `json:"library"`
+		Extreme string `json:"extreme"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Library == "" || (p.Extreme != "Yes" && p.Extreme != "No") {
+		return nil, fmt.Errorf("library and extreme (Yes or No) are required")
+	}
+
+	return func(vr *types.ValidatorResponse) []string {
+		if !strings.EqualFold(strings.TrimSpace(utils.Unpointify(vr.Meta.Library)), p.Library) {
+			return nil
+		}
+		isExtreme := "No"
+		if vr.IsExtreme {
+			isExtreme = "Yes"
+		}
+		if isExtreme == p.Extreme {
+			return nil
+		}
+		return []string{fmt.Sprintf("Curations in the %s library must have Extreme set to '%s'.", p.Library, p.Extreme)}
+	}, nil
+}
+
+// newForbiddenSourceDomainCheck forbids sources pointing to the given domains or their subdomains
+func newForbiddenSourceDomainCheck(params json.RawMessage) (validationCheck, error) {
+	var p struct {
+		Domains []string `json:"domains"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Domains) == 0 {
+		return nil, fmt.Errorf("domains are required")
+	}
+
+	return func(vr *types.ValidatorResponse) []string {
+		host := sourceHost(utils.Unpointify(vr.Meta.Source))
+		if host == "" {
+			return nil
+		}
+		for _, domain := range p.Domains {
+			domain = strings.ToLower(domain)
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return []string{fmt.Sprintf("Source '%s' is not allowed, find the original source of the game.", host)}
+			}
+		}
+		return nil
+	}, nil
+}
+
+var urlSchemeRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+
+// sourceHost returns the lowercase host of the source if it's a URL, sources without scheme are accepted as well
+func sourceHost(source string) string {
+	source = strings.TrimSpace(source)
+	if source == "" || strings.ContainsAny(source, " \t\n") {
+		return ""
+	}
+	if !urlSchemeRegexp.MatchString(source) {
+		source = "http://" + source
+	}
+	u, err := url.Parse(source)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
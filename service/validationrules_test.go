@@ -0,0 +1,167 @@
+package service
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseValidationRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			json: `[{"id": "A", "kind": "forbidden_source_domain", "severity": "error", "params": {"domains": ["example.com"]}}]`,
+		},
+		{
+			name:    "unknown kind",
+			json:    `[{"id": "A", "kind": "foo", "severity": "error", "params": {}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid severity",
+			json:    `[{"id": "A", "kind": "forbidden_source_domain", "severity": "fatal", "params": {"domains": ["example.com"]}}]`,
+			wantErr: true,
+		},
+		{
+			name: "duplicate id",
+			json: `[{"id": "A", "kind": "forbidden_source_domain", "severity": "error", "params": {"domains": ["example.com"]}},
+			        {"id": "A", "kind": "forbidden_source_domain", "severity": "error", "params": {"domains": ["example.org"]}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid pattern",
+			json:    `[{"id": "A", "kind": "launch_command_pattern", "severity": "error", "params": {"platform": "Flash", "pattern": "("}}]`,
+			wantErr: true,
+		},
+		{
+			name:    "missing params",
+			json:    `[{"id": "A", "kind": "library_extreme", "severity": "error", "params": {"library": "theatre"}}]`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseValidationRules([]byte(tt.json))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseValidationRules() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadValidationRules_example(t *testing.T) {
+	rules, err := LoadValidationRules("../validation-rules.example.json")
+	require.NoError(t, err)
+	assert.NotEmpty(t, rules)
+}
+
+func TestLoadOptionalValidationRules(t *testing.T) {
+	l := logrus.New()
+	l.Out = ioutil.Discard
+
+	tests := []struct {
+		name      string
+		filePath  string
+		wantRules bool
+		wantErr   bool
+	}{
+		{name: "unset", filePath: ""},
+		{name: "missing file", filePath: "../validation-rules.missing.json"},
+		{name: "example", filePath: "../validation-rules.example.json", wantRules: true},
+		{name: "invalid file", filePath: "../go.mod", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := LoadOptionalValidationRules(logrus.NewEntry(l), tt.filePath)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRules, len(rules) > 0)
+		})
+	}
+}
+
+func TestValidationRuleEngine_Evaluate(t *testing.T) {
+	rules, err := parseValidationRules([]byte(`[
+		{"id": "LC", "kind": "launch_command_pattern", "severity": "error", "explanation_url": "https://example.com/lc",
+		 "params": {"platform": "Flash", "pattern": "^https?://.+\\.swf$"}},
+		{"id": "LIB", "kind": "library_extreme", "severity": "warning", "params": {"library": "theatre", "extreme": "No"}},
+		{"id": "SRC", "kind": "forbidden_source_domain", "severity": "info", "params": {"domains": ["archive.org"]}}
+	]`))
+	require.NoError(t, err)
+	e := NewValidationRuleEngine(rules)
+
+	tests := []struct {
+		name string
+		vr   *types.ValidatorResponse
+		want []string
+	}{
+		{
+			name: "no violations",
+			vr: &types.ValidatorResponse{Meta: types.CurationMeta{
+				Platform:      utils.StrPtr("Flash"),
+				LaunchCommand: utils.StrPtr("http://example.com/foo.swf"),
+				Library:       utils.StrPtr("arcade"),
+				Source:        utils.StrPtr("https://example.com"),
+			}, IsExtreme: true},
+			want: []string{},
+		},
+		{
+			name: "other platforms are not checked",
+			vr: &types.ValidatorResponse{Meta: types.CurationMeta{
+				Platform:      utils.StrPtr("HTML5"),
+				LaunchCommand: utils.StrPtr("http://example.com/index.html"),
+			}},
+			want: []string{},
+		},
+		{
+			name: "all violated",
+			vr: &types.ValidatorResponse{Meta: types.CurationMeta{
+				Platform:      utils.StrPtr("flash"),
+				LaunchCommand: utils.StrPtr("http://example.com/index.html"),
+				Library:       utils.StrPtr("theatre"),
+				Source:        utils.StrPtr("web.archive.org/web/2005/http://example.com"),
+			}, IsExtreme: true},
+			want: []string{"LC", "LIB", "SRC"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := e.Evaluate(tt.vr)
+			got := make([]string, 0, len(results))
+			for _, r := range results {
+				got = append(got, r.RuleID)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_sourceHost(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"https://Example.com/game", "example.com"},
+		{"www.example.com/game.swf", "www.example.com"},
+		{"Newgrounds", "newgrounds"},
+		{"The author's website", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			assert.Equal(t, tt.want, sourceHost(tt.source))
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -10,9 +12,11 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/Dri0m/flashpoint-submission-system/constants"
 	"github.com/Dri0m/flashpoint-submission-system/types"
 	"github.com/Dri0m/flashpoint-submission-system/utils"
 	"github.com/kofalt/go-memoize"
+	"github.com/sirupsen/logrus"
 )
 
 var cache = memoize.NewMemoizer(10*time.Minute, 60*time.Minute)
@@ -27,22 +31,64 @@ func NewValidator(validatorServerURL string) *curationValidator {
 	}
 }
 
+// newValidatorForMode creates a validator for the given validator mode
+func newValidatorForMode(l *logrus.Entry, mode, validatorServerURL, tagsFilePath string) (Validator, error) {
+	switch mode {
+	case constants.ValidatorModeRemote:
+		return NewValidator(validatorServerURL), nil
+	case constants.ValidatorModeLocal:
+		return NewLocalValidator(tagsFilePath), nil
+	case constants.ValidatorModeFallback:
+		return NewFallbackValidator(l, NewValidator(validatorServerURL), NewLocalValidator(tagsFilePath)), nil
+	}
+	return nil, fmt.Errorf("unknown validator mode '%s'", mode)
+}
+
+// validatorUnavailableError means that the validator server could not be reached or failed on its side,
+// as opposed to responding with an error about the file
+type validatorUnavailableError struct {
+	err error
+}
+
+func (e *validatorUnavailableError) Error() string {
+	return fmt.Sprintf("validator server is not available: %s", e.err.Error())
+}
+
+func (e *validatorUnavailableError) Unwrap() error {
+	return e.err
+}
+
+// isValidatorUnavailable tells if the validator failed in a way worth falling back to the local validator
+func isValidatorUnavailable(err error) bool {
+	var ue *validatorUnavailableError
+	return errors.As(err, &ue)
+}
+
+// validatorRequestError wraps an error of a request to the validator server, transport errors and 5xx responses mean the server is unavailable
+func validatorRequestError(err error) error {
+	var se *utils.HTTPStatusError
+	if errors.As(err, &se) && se.StatusCode < http.StatusInternalServerError {
+		return err
+	}
+	return &validatorUnavailableError{err: err}
+}
+
 func (c *curationValidator) ProvideArchiveForValidation(filePath string) (*types.ValidatorResponse, error) {
 	client := http.Client{Timeout: 86400 * time.Second}
 	resp, err := client.Post(fmt.Sprintf("%s/provide-path?path=%s", c.validatorServerURL, url.QueryEscape(filePath)), "application/json;charset=utf-8", nil)
 	if err != nil {
-		return nil, err
+		return nil, validatorRequestError(err)
 	}
 	defer resp.Body.Close()
 
 	bytes, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, validatorRequestError(err)
 	}
 
 	// Check the response
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("provide to remote error: %s", string(bytes))
+		return nil, validatorRequestError(&utils.HTTPStatusError{StatusCode: resp.StatusCode, Msg: fmt.Sprintf("provide to remote error: %s", string(bytes))})
 	}
 
 	var vr types.ValidatorResponse
@@ -57,7 +103,10 @@ func (c *curationValidator) ProvideArchiveForValidation(filePath string) (*types
 func (c *curationValidator) Validate(ctx context.Context, file io.Reader, filename string) (*types.ValidatorResponse, error) {
 	resp, err := utils.UploadMultipartFile(ctx, c.validatorServerURL+"/upload", file, filename)
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return nil, validatorRequestError(err)
 	}
 
 	var vr types.ValidatorResponse
@@ -100,3 +149,59 @@ func (c *curationValidator) getTags(ctx context.Context) ([]types.Tag, error) {
 
 	return tr.Tags, nil
 }
+
+// fallbackCurationValidator uses the validator server and falls back to the local validator when the server is not available
+type fallbackCurationValidator struct {
+	l      *logrus.Entry
+	remote *curationValidator
+	local  *localCurationValidator
+}
+
+func NewFallbackValidator(l *logrus.Entry, remote *curationValidator, local *localCurationValidator) *fallbackCurationValidator {
+	return &fallbackCurationValidator{
+		l:      l,
+		remote: remote,
+		local:  local,
+	}
+}
+
+func (c *fallbackCurationValidator) ProvideArchiveForValidation(filePath string) (*types.ValidatorResponse, error) {
+	vr, err := c.remote.ProvideArchiveForValidation(filePath)
+	if err == nil || !isValidatorUnavailable(err) {
+		return vr, err
+	}
+
+	c.l.WithError(err).WithField("filePath", filePath).Warn("validator server failed, falling back to the local validator")
+	return c.local.ProvideArchiveForValidation(filePath)
+}
+
+func (c *fallbackCurationValidator) Validate(ctx context.Context, file io.Reader, filename string) (*types.ValidatorResponse, error) {
+	// the reader can be consumed only once, so keep a copy for the fallback
+	b, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	vr, err := c.remote.Validate(ctx, bytes.NewReader(b), filename)
+	if err == nil || !isValidatorUnavailable(err) {
+		return vr, err
+	}
+
+	utils.LogCtx(ctx).WithError(err).Warn("validator server failed, falling back to the local validator")
+	return c.local.Validate(ctx, bytes.NewReader(b), filename)
+}
+
+// GetTags returns tags from the validator server and caches them for the local validator
+func (c *fallbackCurationValidator) GetTags(ctx context.Context) ([]types.Tag, error) {
+	tags, err := c.remote.GetTags(ctx)
+	if err != nil {
+		utils.LogCtx(ctx).WithError(err).Warn("validator server failed, falling back to the cached tag list")
+		return c.local.GetTags(ctx)
+	}
+
+	if err := c.local.cacheTags(tags); err != nil {
+		utils.LogCtx(ctx).Error(err)
+	}
+
+	return tags, nil
+}
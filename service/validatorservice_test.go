@@ -0,0 +1,115 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
+	"github.com/Dri0m/flashpoint-submission-system/types"
+	"github.com/Dri0m/flashpoint-submission-system/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_validatorRequestError(t *testing.T) {
+	tests := []struct {
+		name            string
+		err             error
+		wantUnavailable bool
+	}{
+		{name: "transport error", err: fmt.Errorf("connection refused"), wantUnavailable: true},
+		{name: "server error", err: &utils.HTTPStatusError{StatusCode: http.StatusBadGateway, Msg: "bad gateway"}, wantUnavailable: true},
+		{name: "rejected file", err: &utils.HTTPStatusError{StatusCode: http.StatusBadRequest, Msg: "bad request"}, wantUnavailable: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantUnavailable, isValidatorUnavailable(validatorRequestError(tt.err)))
+		})
+	}
+}
+
+func Test_fallbackCurationValidator_Validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		wantFallback bool
+	}{
+		{name: "server error falls back", status: http.StatusServiceUnavailable, wantFallback: true},
+		{name: "rejected file does not fall back", status: http.StatusBadRequest, wantFallback: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			l := logrus.NewEntry(logrus.New())
+			l.Logger.Out = ioutil.Discard
+			ctx := context.WithValue(context.Background(), utils.CtxKeys.Log, l)
+
+			v := NewFallbackValidator(l, NewValidator(server.URL), NewLocalValidator(""))
+			_, err := v.Validate(ctx, bytes.NewReader([]byte("not an archive")), "foo.txt")
+
+			// the local validator does not support the file, so falling back shows as its error instead of the server's one
+			var se *utils.HTTPStatusError
+			assert.Error(t, err)
+			assert.Equal(t, tt.wantFallback, !errors.As(err, &se))
+		})
+	}
+}
+
+func Test_newValidatorForMode(t *testing.T) {
+	l := logrus.NewEntry(logrus.New())
+
+	_, err := newValidatorForMode(l, constants.ValidatorModeLocal, "", "")
+	assert.NoError(t, err)
+
+	_, err = newValidatorForMode(l, "magic", "", "")
+	assert.Error(t, err)
+}
+
+func Test_convertValidatorResponseToComment(t *testing.T) {
+	tests := []struct {
+		name       string
+		vr         *types.ValidatorResponse
+		wantAction string
+	}{
+		{
+			name:       "valid",
+			vr:         &types.ValidatorResponse{},
+			wantAction: constants.ActionApprove,
+		},
+		{
+			name:       "invalid",
+			vr:         &types.ValidatorResponse{CurationErrors: []string{"Screenshot file is missing."}},
+			wantAction: constants.ActionRequestChanges,
+		},
+		{
+			name:       "degraded is never approved",
+			vr:         &types.ValidatorResponse{IsDegraded: true},
+			wantAction: constants.ActionComment,
+		},
+		{
+			name:       "degraded and invalid",
+			vr:         &types.ValidatorResponse{IsDegraded: true, CurationErrors: []string{"Screenshot file is missing."}},
+			wantAction: constants.ActionRequestChanges,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SiteService{clock: &RealClock{}}
+			c := s.convertValidatorResponseToComment(tt.vr, nil)
+			assert.Equal(t, tt.wantAction, c.Action)
+			if tt.vr.IsDegraded {
+				assert.Contains(t, *c.Message, "Full validation is pending")
+			}
+		})
+	}
+}
@@ -128,7 +128,21 @@ function batchComment(checkboxClassName, attribute, action) {
         }
         url += `/comment?action=${encodeURIComponent(action)}&message=${encodeURIComponent(textArea.value)}&ignore-duplicate-actions=${checked}`
 
-        sendXHR(url, "POST", null, reload,
+        let parentCommentID = document.querySelector("#reply-parent-comment-id")
+        if (parentCommentID !== null && parentCommentID.value !== "") {
+            url += `&parent-comment-id=${parentCommentID.value}`
+        }
+
+        let data = null
+        let imagesInput = document.querySelector("#comment-images")
+        if (imagesInput !== null && imagesInput.files.length > 0) {
+            data = new FormData()
+            for (let i = 0; i < imagesInput.files.length; i++) {
+                data.append("images", imagesInput.files[i])
+            }
+        }
+
+        sendXHR(url, "POST", data, reload,
             `Failed to post comment(s) with action '${action}'.`, successMessage, null)
     }
 
@@ -267,6 +281,42 @@ function overrideBot(sid) {
         null)
 }
 
+function addSubmissionLabel(sid, name) {
+    sendXHR(`/api/submission/${sid}/label?name=${encodeURIComponent(name)}`, "POST", null, true,
+        "Failed to add label.", null, null)
+}
+
+function removeSubmissionLabel(sid, name) {
+    sendXHR(`/api/submission/${sid}/label?name=${encodeURIComponent(name)}`, "DELETE", null, true,
+        "Failed to remove label.", null, null)
+}
+
+function replyToComment(cid, username) {
+    document.querySelector("#reply-parent-comment-id").value = cid
+    let link = document.querySelector("#reply-to-link")
+    link.href = `#comment-${cid}`
+    link.textContent = username
+    document.querySelector("#reply-to").hidden = false
+    let textArea = document.querySelector("#batch-comment-message")
+    textArea.focus()
+}
+
+function cancelReply() {
+    document.querySelector("#reply-parent-comment-id").value = ""
+    document.querySelector("#reply-to").hidden = true
+}
+
+function toggleCommentEdit(cid) {
+    let form = document.getElementById(`comment-edit-${cid}`)
+    form.hidden = !form.hidden
+}
+
+function editComment(sid, cid) {
+    let textArea = document.querySelector(`#comment-edit-message-${cid}`)
+    sendXHR(`/api/submission/${sid}/comment/${cid}?message=${encodeURIComponent(textArea.value)}`, "PUT", null, true,
+        "Failed to edit comment.", null, null)
+}
+
 function deleteComment(sid, cid) {
     sendXHR(`/api/submission/${sid}/comment/${cid}`, "DELETE", null, true,
         "Failed to delete comment.",
@@ -274,6 +324,34 @@ function deleteComment(sid, cid) {
         "Please provide a reason to delete this comment:")
 }
 
+function restoreSubmission(sid) {
+    sendXHR(`/api/submission/${sid}/restore`, "POST", null, true,
+        "Failed to restore submission.",
+        "Submission restored successfully.",
+        null)
+}
+
+function restoreSubmissionFile(sid, sfid) {
+    sendXHR(`/api/submission/${sid}/file/${sfid}/restore`, "POST", null, true,
+        "Failed to restore submission file.",
+        "Submission file restored successfully.",
+        null)
+}
+
+function restoreComment(sid, cid) {
+    sendXHR(`/api/submission/${sid}/comment/${cid}/restore`, "POST", null, true,
+        "Failed to restore comment.",
+        "Comment restored successfully.",
+        null)
+}
+
+function cancelOperation(id) {
+    sendXHR(`/api/internal/operations/${id}/cancel`, "POST", null, true,
+        "Failed to cancel operation.",
+        "Operation cancelled, it stops at its next check.",
+        null)
+}
+
 function resetFilterForm() {
     // default reset doesn't seem to work because i have divs inside the form
     let formSimple = document.getElementById("filter-form-simple")
@@ -178,11 +178,13 @@ function pollUploadStatus(file, tempName) {
         
         let status = null
         try {
-            status = JSON.parse(request.response)["status"]
+            let response = JSON.parse(request.response)
+            status = response["status"]
             file.progressText.innerHTML = `${getFilename(file)}<br>Status: ${status["status"]}`
             if (status["message"] !== null) {
                 file.progressText.innerHTML += `<br>Message: ${status["message"]}`
             }
+            file.progressText.innerHTML += formatUploadTimeline(response["timeline"])
             if (status["submission_id"] !== null) {
                 file.progressText.innerHTML += `<br><a href="/web/submission/${status["submission_id"]}">View</a>`
                 clearInterval(file.intervalID)
@@ -200,4 +202,18 @@ function pollUploadStatus(file, tempName) {
     })
 
     request.send()
-}
\ No newline at end of file
+}
+
+function formatUploadTimeline(timeline) {
+    if (timeline === null || timeline.length === 0) {
+        return ""
+    }
+    let html = "<br>Timeline:"
+    for (let entry of timeline) {
+        html += `<br>${new Date(entry["created_at"]).toLocaleTimeString()} - ${entry["status"]}`
+        if (entry["message"] !== null) {
+            html += ` (${entry["message"]})`
+        }
+    }
+    return html
+}
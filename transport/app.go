@@ -12,9 +12,11 @@ import (
 	"time"
 
 	"github.com/Dri0m/flashpoint-submission-system/config"
+	"github.com/Dri0m/flashpoint-submission-system/constants"
 	"github.com/Dri0m/flashpoint-submission-system/logging"
 	"github.com/Dri0m/flashpoint-submission-system/resumableuploadservice"
 	"github.com/Dri0m/flashpoint-submission-system/service"
+	"github.com/Dri0m/flashpoint-submission-system/types"
 	"github.com/Dri0m/flashpoint-submission-system/utils"
 	"github.com/bwmarrin/discordgo"
 	"github.com/gorilla/mux"
@@ -53,7 +55,16 @@ func InitApp(l *logrus.Entry, conf *config.Config, db *sql.DB, authBotSession, n
 		},
 		Service: service.New(l, db, authBotSession, notificationBotSession, conf.FlashpointServerID,
 			conf.NotificationChannelID, conf.CurationFeedChannelID, conf.ValidatorServerURL, conf.SessionExpirationSeconds,
-			conf.SubmissionsDirFullPath, conf.SubmissionImagesDirFullPath, conf.FlashfreezeDirFullPath, conf.IsDev, rsu, conf.ArchiveIndexerServerURL, conf.FlashfreezeIngestDirFullPath, conf.FixesDirFullPath),
+			conf.SubmissionsDirFullPath, conf.SubmissionImagesDirFullPath, conf.FlashfreezeDirFullPath, conf.IsDev, rsu, conf.ArchiveIndexerServerURL, conf.FlashfreezeIngestDirFullPath, conf.FixesDirFullPath,
+			conf.ValidatorMode, conf.ValidatorTagsFileFullPath, conf.ValidationRulesFileFullPath, conf.ScheduledTasksFileFullPath,
+			types.ApprovalQuorums{
+				constants.SubmissionLevelAudition: conf.ApprovalQuorumAudition,
+				constants.SubmissionLevelTrial:    conf.ApprovalQuorumTrial,
+				constants.SubmissionLevelStaff:    conf.ApprovalQuorumStaff,
+			},
+			newAssignmentExpiry(conf.AssignmentExpiryDays, conf.AssignmentExpiryDaysByRole),
+			types.WorkloadCaps{Default: conf.WorkloadCap, ByRole: conf.WorkloadCapByRole},
+			time.Duration(conf.FileRetentionDays)*time.Hour*24),
 		decoder:             decoder,
 		authMiddlewareCache: memoize.NewMemoizer(5*time.Second, 60*time.Minute),
 	}
@@ -75,6 +86,51 @@ func InitApp(l *logrus.Entry, conf *config.Config, db *sql.DB, authBotSession, n
 		a.Service.RunNotificationConsumer(l, ctx, wg)
 	}()
 
+	l.Infoln("starting the submission job consumers...")
+
+	for i := 0; i < int(conf.SubmissionJobConsumerCount); i++ {
+		wg.Add(1)
+		consumerID := i
+		go func() {
+			a.Service.RunSubmissionJobConsumer(l, ctx, wg, consumerID)
+		}()
+	}
+
+	l.Infoln("starting the submission job cleaner...")
+
+	wg.Add(1)
+	go func() {
+		a.Service.RunSubmissionJobCleaner(l, ctx, wg)
+	}()
+
+	l.Infoln("starting the assignment sweeper...")
+
+	wg.Add(1)
+	go func() {
+		a.Service.RunAssignmentSweeper(l, ctx, wg)
+	}()
+
+	l.Infoln("starting the file purger...")
+
+	wg.Add(1)
+	go func() {
+		a.Service.RunFilePurger(l, ctx, wg)
+	}()
+
+	l.Infoln("starting the scheduler...")
+
+	wg.Add(1)
+	go func() {
+		a.Service.RunScheduler(l, ctx, wg)
+	}()
+
+	l.Infoln("starting the background task keeper...")
+
+	wg.Add(1)
+	go func() {
+		a.Service.RunBackgroundTaskKeeper(l, ctx, wg)
+	}()
+
 	l.Infoln("starting the memstats printer...")
 
 	wg.Add(1)
@@ -121,3 +177,15 @@ func memstatsPrinter(l *logrus.Entry, ctx context.Context, wg *sync.WaitGroup) {
 		}
 	}
 }
+
+// newAssignmentExpiry converts the configured days to durations
+func newAssignmentExpiry(days int64, daysByRole map[string]int64) types.AssignmentExpiry {
+	ae := types.AssignmentExpiry{
+		Default: time.Duration(days) * time.Hour * 24,
+		ByRole:  make(map[string]time.Duration, len(daysByRole)),
+	}
+	for role, d := range daysByRole {
+		ae.ByRole[role] = time.Duration(d) * time.Hour * 24
+	}
+	return ae
+}
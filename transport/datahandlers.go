@@ -119,6 +119,44 @@ func (a *App) HandleDownloadCurationImage(w http.ResponseWriter, r *http.Request
 	http.ServeContent(w, r, ci.Filename, fi.ModTime(), f)
 }
 
+func (a *App) HandleDownloadCommentImage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	submissionID := params[constants.ResourceKeySubmissionID]
+	commentImageID := params[constants.ResourceKeyCommentImageID]
+
+	sid, err := strconv.ParseInt(submissionID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission id", http.StatusBadRequest))
+		return
+	}
+
+	ciid, err := strconv.ParseInt(commentImageID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid comment image id", http.StatusBadRequest))
+		return
+	}
+
+	ci, err := a.Service.GetCommentImage(ctx, sid, ciid)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	f, err := os.Open(fmt.Sprintf("%s/%s", a.Conf.SubmissionImagesDirFullPath, ci.Filename))
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to read file", http.StatusInternalServerError))
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "image/png")
+	http.ServeContent(w, r, ci.Filename, ci.CreatedAt, f)
+}
+
 func (a *App) HandleDownloadFlashfreezeRootFile(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := mux.Vars(r)
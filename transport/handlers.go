@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/kofalt/go-memoize"
+	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -79,11 +80,28 @@ func (a *App) HandleCommentReceiverBatch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	images, err := a.readCommentImages(ctx, w, r)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
 	// TODO use gorilla/schema
 	formAction := r.FormValue("action")
 	formMessage := r.FormValue("message")
 	formIgnoreDupeActions := r.FormValue("ignore-duplicate-actions")
 
+	var parentCommentID *int64
+	if formParentCommentID := r.FormValue("parent-comment-id"); formParentCommentID != "" {
+		pcid, err := strconv.ParseInt(formParentCommentID, 10, 64)
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			writeError(ctx, w, perr("invalid parent comment id", http.StatusBadRequest))
+			return
+		}
+		parentCommentID = &pcid
+	}
+
 	if len([]rune(formMessage)) > 20000 {
 		err := fmt.Errorf("message cannot be longer than 20000 characters")
 		utils.LogCtx(ctx).Error(err)
@@ -91,7 +109,7 @@ func (a *App) HandleCommentReceiverBatch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := a.Service.ReceiveComments(ctx, uid, sids, formAction, formMessage, formIgnoreDupeActions); err != nil {
+	if err := a.Service.ReceiveComments(ctx, uid, sids, formAction, formMessage, formIgnoreDupeActions, parentCommentID, images); err != nil {
 		writeError(ctx, w, err)
 		return
 	}
@@ -99,6 +117,61 @@ func (a *App) HandleCommentReceiverBatch(w http.ResponseWriter, r *http.Request)
 	writeResponse(ctx, w, presp("success", http.StatusOK), http.StatusOK)
 }
 
+// readCommentImages reads images attached to a comment, comments without attachments are not sent as multipart forms
+func (a *App) readCommentImages(ctx context.Context, w http.ResponseWriter, r *http.Request) ([][]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		return nil, nil
+	}
+
+	// a little headroom for the multipart overhead
+	r.Body = http.MaxBytesReader(w, r.Body, (constants.CommentImageMaxCount+1)*constants.CommentImageMaxFilesize)
+	if err := r.ParseMultipartForm(64 * 1000 * 1000); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		return nil, perr("failed to parse form, the attached images may be too large", http.StatusBadRequest)
+	}
+
+	fileHeaders := r.MultipartForm.File["images"]
+	if len(fileHeaders) > constants.CommentImageMaxCount {
+		return nil, perr(fmt.Sprintf("cannot attach more than %d images to a comment", constants.CommentImageMaxCount), http.StatusBadRequest)
+	}
+
+	images := make([][]byte, 0, len(fileHeaders))
+	for _, fileHeader := range fileHeaders {
+		if fileHeader.Size > constants.CommentImageMaxFilesize {
+			return nil, perr(fmt.Sprintf("image '%s' is larger than %s", fileHeader.Filename, utils.SizeToString(constants.CommentImageMaxFilesize)), http.StatusBadRequest)
+		}
+
+		file, err := fileHeader.Open()
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return nil, perr("failed to open received image", http.StatusInternalServerError)
+		}
+		data, err := ioutil.ReadAll(file)
+		file.Close()
+		if err != nil {
+			utils.LogCtx(ctx).Error(err)
+			return nil, perr("failed to read received image", http.StatusInternalServerError)
+		}
+
+		images = append(images, data)
+	}
+
+	return images, nil
+}
+
+func (a *App) HandleAssignNextSubmission(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := utils.UserID(ctx)
+
+	sid, err := a.Service.AssignNextSubmission(ctx, uid)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/web/submission/%d", sid), http.StatusFound)
+}
+
 func (a *App) HandleSoftDeleteSubmissionFile(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := mux.Vars(r)
@@ -192,6 +265,127 @@ func (a *App) HandleSoftDeleteComment(w http.ResponseWriter, r *http.Request) {
 	writeResponse(ctx, w, nil, http.StatusNoContent)
 }
 
+func (a *App) HandleRestoreSubmission(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	submissionID := params[constants.ResourceKeySubmissionID]
+
+	sid, err := strconv.ParseInt(submissionID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission id", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.RestoreSubmission(ctx, sid); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, presp("submission restored", http.StatusOK), http.StatusOK)
+}
+
+func (a *App) HandleRestoreSubmissionFile(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	submissionFileID := params[constants.ResourceKeyFileID]
+
+	sfid, err := strconv.ParseInt(submissionFileID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission file id", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.RestoreSubmissionFile(ctx, sfid); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, presp("submission file restored", http.StatusOK), http.StatusOK)
+}
+
+func (a *App) HandleRestoreComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	commentID := params[constants.ResourceKeyCommentID]
+
+	cid, err := strconv.ParseInt(commentID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid comment id", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.RestoreComment(ctx, cid); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, presp("comment restored", http.StatusOK), http.StatusOK)
+}
+
+func (a *App) HandleTrashPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	pageData, err := a.Service.GetTrashPageData(ctx)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	if utils.RequestType(ctx) != constants.RequestWeb {
+		writeResponse(ctx, w, pageData, http.StatusOK)
+		return
+	}
+
+	a.RenderTemplates(ctx, w, r, pageData, "templates/trash.gohtml")
+}
+
+func (a *App) HandleEditComment(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	uid := utils.UserID(ctx)
+	params := mux.Vars(r)
+	submissionID := params[constants.ResourceKeySubmissionID]
+	commentID := params[constants.ResourceKeyCommentID]
+
+	sid, err := strconv.ParseInt(submissionID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission id", http.StatusBadRequest))
+		return
+	}
+
+	cid, err := strconv.ParseInt(commentID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid comment id", http.StatusBadRequest))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to parse form", http.StatusBadRequest))
+		return
+	}
+
+	formMessage := r.FormValue("message")
+
+	if len([]rune(formMessage)) > 20000 {
+		err := fmt.Errorf("message cannot be longer than 20000 characters")
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, constants.PublicError{Msg: err.Error(), Status: http.StatusBadRequest})
+		return
+	}
+
+	if err := a.Service.EditComment(ctx, uid, sid, cid, formMessage); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, presp("success", http.StatusOK), http.StatusOK)
+}
+
 func (a *App) HandleOverrideBot(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -520,7 +714,14 @@ func (a *App) HandleViewSubmissionFilesPage(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	pageData, err := a.Service.GetSubmissionsFilesPageData(ctx, sid)
+	fromFID, toFID, err := parseMetaDiffParams(r)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, err)
+		return
+	}
+
+	pageData, err := a.Service.GetSubmissionsFilesPageData(ctx, sid, fromFID, toFID)
 	if err != nil {
 		writeError(ctx, w, err)
 		return
@@ -534,6 +735,99 @@ func (a *App) HandleViewSubmissionFilesPage(w http.ResponseWriter, r *http.Reque
 	a.RenderTemplates(ctx, w, r, pageData, "templates/submission-files.gohtml", "templates/submission-files-table.gohtml")
 }
 
+func (a *App) HandleCurationMetaDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	submissionID := params[constants.ResourceKeySubmissionID]
+
+	sid, err := strconv.ParseInt(submissionID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission id", http.StatusBadRequest))
+		return
+	}
+
+	fromFID, toFID, err := parseMetaDiffParams(r)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, err)
+		return
+	}
+	if fromFID == nil || toFID == nil {
+		writeError(ctx, w, perr("both from and to file ids are required", http.StatusBadRequest))
+		return
+	}
+
+	diff, err := a.Service.GetCurationMetaDiff(ctx, sid, *fromFID, *toFID)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, diff, http.StatusOK)
+}
+
+func (a *App) HandleArchiveContentDiff(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	submissionID := params[constants.ResourceKeySubmissionID]
+
+	sid, err := strconv.ParseInt(submissionID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission id", http.StatusBadRequest))
+		return
+	}
+
+	fromFID, toFID, err := parseMetaDiffParams(r)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, err)
+		return
+	}
+	if fromFID == nil || toFID == nil {
+		writeError(ctx, w, perr("both from and to file ids are required", http.StatusBadRequest))
+		return
+	}
+
+	diff, err := a.Service.GetArchiveContentDiff(ctx, sid, *fromFID, *toFID)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, diff, http.StatusOK)
+}
+
+func (a *App) HandleGetSubmissionFileValidation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	submissionID := params[constants.ResourceKeySubmissionID]
+	submissionFileID := params[constants.ResourceKeyFileID]
+
+	sid, err := strconv.ParseInt(submissionID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission id", http.StatusBadRequest))
+		return
+	}
+
+	sfid, err := strconv.ParseInt(submissionFileID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission file id", http.StatusBadRequest))
+		return
+	}
+
+	sfv, err := a.Service.GetSubmissionFileValidation(ctx, sid, sfid)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, sfv, http.StatusOK)
+}
+
 func (a *App) HandleUpdateNotificationSettings(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	uid := utils.UserID(ctx)
@@ -619,9 +913,8 @@ func (a *App) HandleReceiveFixesSubmitGeneric(w http.ResponseWriter, r *http.Req
 func (a *App) HandleInternalPage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	pageData, err := a.Service.GetBasePageData(ctx)
+	pageData, err := a.Service.GetInternalPageData(ctx)
 	if err != nil {
-		utils.LogCtx(ctx).Error(err)
 		writeError(ctx, w, err)
 		return
 	}
@@ -629,39 +922,58 @@ func (a *App) HandleInternalPage(w http.ResponseWriter, r *http.Request) {
 	a.RenderTemplates(ctx, w, r, pageData, "templates/internal.gohtml")
 }
 
-// TODO create a closure function thingy to handle this automatically? already 3+ guards like these hang around the code
-var updateMasterDBGuard = make(chan struct{}, 1)
-
 func (a *App) HandleUpdateMasterDB(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	select {
-	case updateMasterDBGuard <- struct{}{}:
-		utils.LogCtx(ctx).Debug("starting update master db")
-	default:
-		writeResponse(ctx, w, presp("update master db already running", http.StatusForbidden), http.StatusForbidden)
+	op, err := a.Service.StartOperation(ctx, constants.OperationUpdateMasterDB, constants.AuditActionUpdateMasterDB, a.Service.UpdateMasterDB)
+	if err != nil {
+		writeError(ctx, w, err)
 		return
 	}
 
-	go func() {
-		err := a.Service.UpdateMasterDB(context.WithValue(context.Background(), utils.CtxKeys.Log, utils.LogCtx(ctx)))
-		if err != nil {
-			utils.LogCtx(ctx).Error(err)
-		}
-		<-updateMasterDBGuard
-	}()
+	writeResponse(ctx, w, op, http.StatusOK)
+}
 
-	writeResponse(ctx, w, presp("starting update master db", http.StatusOK), http.StatusOK)
+func (a *App) HandleGetOperations(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	writeResponse(ctx, w, a.Service.GetOperations(ctx), http.StatusOK)
 }
 
-func (a *App) HandleHelpPage(w http.ResponseWriter, r *http.Request) {
-	// TODO all auth-free pages should use a middleware to remove all of this user ID handling from the handlers
+func (a *App) HandleGetOperation(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	uid, err := a.GetUserIDFromCookie(r)
+	params := mux.Vars(r)
+	operationID := params[constants.ResourceKeyOperationID]
+
+	op, err := a.Service.GetOperation(ctx, operationID)
 	if err != nil {
-		utils.LogCtx(ctx).Error(err)
-		utils.UnsetCookie(w, utils.Cookies.Login)
-		http.Redirect(w, r, "/web", http.StatusFound)
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, op, http.StatusOK)
+}
+
+func (a *App) HandleCancelOperation(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	operationID := params[constants.ResourceKeyOperationID]
+
+	if err := a.Service.CancelOperation(ctx, operationID); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, presp("operation cancelled", http.StatusOK), http.StatusOK)
+}
+
+func (a *App) HandleHelpPage(w http.ResponseWriter, r *http.Request) {
+	// TODO all auth-free pages should use a middleware to remove all of this user ID handling from the handlers
+	ctx := r.Context()
+	uid, err := a.GetUserIDFromCookie(r)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		utils.UnsetCookie(w, utils.Cookies.Login)
+		http.Redirect(w, r, "/web", http.StatusFound)
 		return
 	}
 	r = r.WithContext(context.WithValue(r.Context(), utils.CtxKeys.UserID, uid))
@@ -780,6 +1092,7 @@ func (a *App) HandleSearchFlashfreezePage(w http.ResponseWriter, r *http.Request
 		"templates/flashfreeze-pagenav.gohtml")
 }
 
+// TODO create a closure function thingy to handle this automatically? already 3+ guards like these hang around the code
 var ingestGuard = make(chan struct{}, 1)
 
 func (a *App) HandleIngestFlashfreeze(w http.ResponseWriter, r *http.Request) {
@@ -793,6 +1106,12 @@ func (a *App) HandleIngestFlashfreeze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := a.Service.RecordAuditLogEntry(ctx, constants.AuditActionIngestFlashfreeze, "", nil, nil, nil); err != nil {
+		<-ingestGuard
+		writeError(ctx, w, err)
+		return
+	}
+
 	go func() {
 		a.Service.IngestFlashfreezeItems(utils.LogCtx(context.WithValue(context.Background(), utils.CtxKeys.Log, utils.LogCtx(ctx))))
 		<-ingestGuard
@@ -801,48 +1120,30 @@ func (a *App) HandleIngestFlashfreeze(w http.ResponseWriter, r *http.Request) {
 	writeResponse(ctx, w, presp("starting flashfreeze ingestion", http.StatusOK), http.StatusOK)
 }
 
-var recomputeSubmissionCacheAllGuard = make(chan struct{}, 1)
-
 func (a *App) HandleRecomputeSubmissionCacheAll(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	select {
-	case recomputeSubmissionCacheAllGuard <- struct{}{}:
-		utils.LogCtx(ctx).Debug("starting recompute submission cache all")
-	default:
-		writeResponse(ctx, w, presp("recompute submission cache all already running", http.StatusForbidden), http.StatusForbidden)
+	op, err := a.Service.StartOperation(ctx, constants.OperationRecomputeSubmissionCacheAll, constants.AuditActionRecomputeSubmissionCacheAll, a.Service.RecomputeSubmissionCacheAll)
+	if err != nil {
+		writeError(ctx, w, err)
 		return
 	}
 
-	go func() {
-		a.Service.RecomputeSubmissionCacheAll(context.WithValue(context.Background(), utils.CtxKeys.Log, utils.LogCtx(ctx)))
-		<-recomputeSubmissionCacheAllGuard
-	}()
-
-	writeResponse(ctx, w, presp("starting recompute submission cache all", http.StatusOK), http.StatusOK)
+	writeResponse(ctx, w, op, http.StatusOK)
 }
 
-var ingestUnknownGuard = make(chan struct{}, 1)
-
 // HandleIngestUnknownFlashfreeze ingests flashfreeze files which are in the flashfreeze directory, but not in the database.
 // This should not be needed and such files are a result of a bug or human error.
 func (a *App) HandleIngestUnknownFlashfreeze(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	select {
-	case ingestUnknownGuard <- struct{}{}:
-		utils.LogCtx(ctx).Debug("starting flashfreeze ingestion of unknown files")
-	default:
-		writeResponse(ctx, w, presp("ingestion already running", http.StatusForbidden), http.StatusForbidden)
+	op, err := a.Service.StartOperation(ctx, constants.OperationIngestUnknownFlashfreeze, constants.AuditActionIngestUnknownFlashfreeze, a.Service.IngestUnknownFlashfreezeItems)
+	if err != nil {
+		writeError(ctx, w, err)
 		return
 	}
 
-	go func() {
-		a.Service.IngestUnknownFlashfreezeItems(utils.LogCtx(context.WithValue(context.Background(), utils.CtxKeys.Log, utils.LogCtx(ctx))))
-		<-ingestUnknownGuard
-	}()
-
-	writeResponse(ctx, w, presp("starting flashfreeze ingestion of unknown files", http.StatusOK), http.StatusOK)
+	writeResponse(ctx, w, op, http.StatusOK)
 }
 
 var indexUnindexedGuard = make(chan struct{}, 1)
@@ -858,6 +1159,12 @@ func (a *App) HandleIndexUnindexedFlashfreeze(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if err := a.Service.RecordAuditLogEntry(ctx, constants.AuditActionIndexUnindexedFlashfreeze, "", nil, nil, nil); err != nil {
+		<-indexUnindexedGuard
+		writeError(ctx, w, err)
+		return
+	}
+
 	go func() {
 		a.Service.IndexUnindexedFlashfreezeItems(utils.LogCtx(context.WithValue(context.Background(), utils.CtxKeys.Log, utils.LogCtx(ctx))))
 		<-indexUnindexedGuard
@@ -866,6 +1173,74 @@ func (a *App) HandleIndexUnindexedFlashfreeze(w http.ResponseWriter, r *http.Req
 	writeResponse(ctx, w, presp("starting flashfreeze indexing of unindexed files", http.StatusOK), http.StatusOK)
 }
 
+var indexUnindexedSubmissionFilesGuard = make(chan struct{}, 1)
+
+func (a *App) HandleIndexUnindexedSubmissionFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	select {
+	case indexUnindexedSubmissionFilesGuard <- struct{}{}:
+		utils.LogCtx(ctx).Debug("starting indexing of unindexed submission files")
+	default:
+		writeResponse(ctx, w, presp("indexing already running", http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if err := a.Service.RecordAuditLogEntry(ctx, constants.AuditActionIndexUnindexedSubmissionFiles, "", nil, nil, nil); err != nil {
+		<-indexUnindexedSubmissionFilesGuard
+		writeError(ctx, w, err)
+		return
+	}
+
+	go func() {
+		a.Service.IndexUnindexedSubmissionFiles(utils.LogCtx(context.WithValue(context.Background(), utils.CtxKeys.Log, utils.LogCtx(ctx))))
+		<-indexUnindexedSubmissionFilesGuard
+	}()
+
+	writeResponse(ctx, w, presp("starting indexing of unindexed submission files", http.StatusOK), http.StatusOK)
+}
+
+var revalidateSubmissionFilesGuard = make(chan struct{}, 1)
+
+func (a *App) HandleRevalidateSubmissionFiles(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	sfids := make([]int64, 0)
+	for _, fileID := range strings.Split(r.URL.Query().Get("file-ids"), ",") {
+		fileID = strings.TrimSpace(fileID)
+		if fileID == "" {
+			continue
+		}
+		sfid, err := strconv.ParseInt(fileID, 10, 64)
+		if err != nil {
+			writeError(ctx, w, perr("invalid submission file id", http.StatusBadRequest))
+			return
+		}
+		sfids = append(sfids, sfid)
+	}
+
+	select {
+	case revalidateSubmissionFilesGuard <- struct{}{}:
+		utils.LogCtx(ctx).Debug("starting revalidation of submission files")
+	default:
+		writeResponse(ctx, w, presp("revalidation already running", http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if err := a.Service.RecordAuditLogEntry(ctx, constants.AuditActionRevalidateSubmissionFiles, "", nil, nil, map[string][]int64{"file_ids": sfids}); err != nil {
+		<-revalidateSubmissionFilesGuard
+		writeError(ctx, w, err)
+		return
+	}
+
+	go func() {
+		a.Service.RevalidateSubmissionFiles(utils.LogCtx(context.WithValue(context.Background(), utils.CtxKeys.Log, utils.LogCtx(ctx))), sfids)
+		<-revalidateSubmissionFilesGuard
+	}()
+
+	writeResponse(ctx, w, presp("starting revalidation of submission files", http.StatusOK), http.StatusOK)
+}
+
 func (a *App) HandleDeleteUserSessions(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -892,6 +1267,106 @@ func (a *App) HandleDeleteUserSessions(w http.ResponseWriter, r *http.Request) {
 	writeResponse(ctx, w, presp(fmt.Sprintf("deleted %d sessions", count), http.StatusOK), http.StatusOK)
 }
 
+func (a *App) HandleSimilarityRulesPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req := &types.SimilarityTestRequest{}
+
+	if err := a.decoder.Decode(req, r.URL.Query()); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to decode query params", http.StatusInternalServerError))
+		return
+	}
+
+	pageData, err := a.Service.GetSimilarityRulesPageData(ctx, req)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	a.RenderTemplates(ctx, w, r, pageData, "templates/similarity-rules.gohtml")
+}
+
+func (a *App) HandleCreateSimilarityRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to parse form", http.StatusBadRequest))
+		return
+	}
+
+	rule := &types.SimilarityRule{}
+
+	if err := a.decoder.Decode(rule, r.PostForm); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to decode form", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.CreateSimilarityRule(ctx, rule); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/web/internal/similarity-rules", http.StatusFound)
+}
+
+func (a *App) HandleUpdateSimilarityRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	similarityRuleID := params[constants.ResourceKeySimilarityRuleID]
+
+	srid, err := strconv.ParseInt(similarityRuleID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid similarity rule id", http.StatusBadRequest))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to parse form", http.StatusBadRequest))
+		return
+	}
+
+	rule := &types.SimilarityRule{}
+
+	if err := a.decoder.Decode(rule, r.PostForm); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to decode form", http.StatusBadRequest))
+		return
+	}
+	rule.ID = srid
+
+	if err := a.Service.UpdateSimilarityRule(ctx, rule); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/web/internal/similarity-rules", http.StatusFound)
+}
+
+func (a *App) HandleDeleteSimilarityRule(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	similarityRuleID := params[constants.ResourceKeySimilarityRuleID]
+
+	srid, err := strconv.ParseInt(similarityRuleID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid similarity rule id", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.DeleteSimilarityRule(ctx, srid); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/web/internal/similarity-rules", http.StatusFound)
+}
+
 func (a *App) HandleStatisticsPage(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -1123,11 +1598,184 @@ func (a *App) HandleGetUploadProgress(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	tempName := params[constants.ResourceKeyTempName]
 
+	status, err := a.Service.GetUploadStatus(ctx, tempName)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	timeline, err := a.Service.GetUploadStatusTimeline(ctx, tempName)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
 	data := struct {
-		Status *types.SubmissionStatus `json:"status"`
+		Status   *types.SubmissionStatus               `json:"status"`
+		Timeline []*types.SubmissionStatusHistoryEntry `json:"timeline"`
 	}{
-		a.Service.SSK.Get(tempName),
+		status,
+		timeline,
 	}
 
 	writeResponse(ctx, w, data, http.StatusOK)
 }
+
+func (a *App) HandleGetLabels(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	labels, err := a.Service.GetLabels(ctx)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	data := struct {
+		Labels []*types.Label `json:"labels"`
+	}{
+		labels,
+	}
+
+	writeResponse(ctx, w, data, http.StatusOK)
+}
+
+func (a *App) HandleCreateLabel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to parse form", http.StatusBadRequest))
+		return
+	}
+
+	label, err := a.Service.CreateLabel(ctx, r.FormValue("name"))
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, label, http.StatusOK)
+}
+
+func (a *App) HandleUpdateLabel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	labelID := params[constants.ResourceKeyLabelID]
+
+	lid, err := strconv.ParseInt(labelID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid label id", http.StatusBadRequest))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to parse form", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.UpdateLabel(ctx, lid, r.FormValue("name")); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, presp("success", http.StatusOK), http.StatusOK)
+}
+
+func (a *App) HandleDeleteLabel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	labelID := params[constants.ResourceKeyLabelID]
+
+	lid, err := strconv.ParseInt(labelID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid label id", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.DeleteLabel(ctx, lid); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, nil, http.StatusNoContent)
+}
+
+func (a *App) HandleAddSubmissionLabel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	submissionID := params[constants.ResourceKeySubmissionID]
+
+	sid, err := strconv.ParseInt(submissionID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission id", http.StatusBadRequest))
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to parse form", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.AddSubmissionLabel(ctx, sid, r.FormValue("name")); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, presp("success", http.StatusOK), http.StatusOK)
+}
+
+func (a *App) HandleRemoveSubmissionLabel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	params := mux.Vars(r)
+	submissionID := params[constants.ResourceKeySubmissionID]
+
+	sid, err := strconv.ParseInt(submissionID, 10, 64)
+	if err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("invalid submission id", http.StatusBadRequest))
+		return
+	}
+
+	if err := a.Service.RemoveSubmissionLabel(ctx, sid, r.URL.Query().Get("name")); err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	writeResponse(ctx, w, nil, http.StatusNoContent)
+}
+
+func (a *App) HandleAuditLogPage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter := &types.AuditLogFilter{}
+
+	if err := a.decoder.Decode(filter, r.URL.Query()); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr("failed to decode query params", http.StatusInternalServerError))
+		return
+	}
+
+	if err := filter.Validate(); err != nil {
+		utils.LogCtx(ctx).Error(err)
+		writeError(ctx, w, perr(err.Error(), http.StatusBadRequest))
+		return
+	}
+
+	pageData, err := a.Service.GetAuditLogPageData(ctx, filter)
+	if err != nil {
+		writeError(ctx, w, err)
+		return
+	}
+
+	if utils.RequestType(ctx) != constants.RequestWeb {
+		writeResponse(ctx, w, pageData, http.StatusOK)
+		return
+	}
+
+	a.RenderTemplates(ctx, w, r, pageData, "templates/audit-log.gohtml")
+}
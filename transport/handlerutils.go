@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"html/template"
 	"net/http"
 	"strconv"
@@ -39,6 +40,7 @@ func (a *App) RenderTemplates(ctx context.Context, w http.ResponseWriter, r *htt
 		"isGod":                         constants.IsGod,
 		"sizeToString":                  utils.SizeToString,
 		"splitMultilineText":            utils.SplitMultilineText,
+		"markdown":                      utils.RenderMarkdown,
 		"capitalizeAscii":               utils.CapitalizeASCII,
 		"parseMetaTags":                 parseMetaTags,
 		"submissionsShowPreviousButton": submissionsShowPreviousButton,
@@ -245,3 +247,29 @@ func capString(maxLen int, s *string) string {
 func isReturnURLValid(s string) bool {
 	return len(s) > 0 && strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//")
 }
+
+// parseMetaDiffParams parses optional 'from' and 'to' submission file IDs from the query
+func parseMetaDiffParams(r *http.Request) (*int64, *int64, error) {
+	parse := func(key string) (*int64, error) {
+		v := r.URL.Query().Get(key)
+		if v == "" {
+			return nil, nil
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, perr(fmt.Sprintf("invalid %s file id", key), http.StatusBadRequest)
+		}
+		return &id, nil
+	}
+
+	fromFID, err := parse("from")
+	if err != nil {
+		return nil, nil, err
+	}
+	toFID, err := parse("to")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fromFID, toFID, nil
+}
@@ -19,6 +19,9 @@ func (a *App) handleRequests(l *logrus.Entry, srv *http.Server, router *mux.Rout
 	isDeleter := func(r *http.Request, uid int64) (bool, error) {
 		return a.UserHasAnyRole(r, uid, constants.DeleterRoles())
 	}
+	isDecider := func(r *http.Request, uid int64) (bool, error) {
+		return a.UserHasAnyRole(r, uid, constants.DeciderRoles())
+	}
 	isInAudit := func(r *http.Request, uid int64) (bool, error) {
 		s, err := a.UserHasAnyRole(r, uid, constants.StaffRoles())
 		if err != nil {
@@ -188,6 +191,39 @@ func (a *App) handleRequests(l *logrus.Entry, srv *http.Server, router *mux.Rout
 
 	////////////////////////
 
+	f = a.UserAuthMux(
+		a.HandleCurationMetaDiff,
+		muxAny(isStaff, isTrialCurator, isInAudit))
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/meta-diff", constants.ResourceKeySubmissionID),
+		http.HandlerFunc(a.RequestJSON(f))).
+		Methods("GET")
+
+	////////////////////////
+
+	f = a.UserAuthMux(
+		a.HandleArchiveContentDiff,
+		muxAny(isStaff, isTrialCurator, isInAudit))
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/content-diff", constants.ResourceKeySubmissionID),
+		http.HandlerFunc(a.RequestJSON(f))).
+		Methods("GET")
+
+	////////////////////////
+
+	f = a.UserAuthMux(
+		a.HandleGetSubmissionFileValidation,
+		muxAny(isStaff, isTrialCurator, isInAudit))
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/file/{%s}/validation", constants.ResourceKeySubmissionID, constants.ResourceKeyFileID),
+		http.HandlerFunc(a.RequestJSON(f))).
+		Methods("GET")
+
+	////////////////////////
+
 	f = a.UserAuthMux(
 		a.HandleSearchFlashfreezePage,
 		muxAny(isStaff, isTrialCurator, isInAudit))
@@ -347,6 +383,18 @@ func (a *App) handleRequests(l *logrus.Entry, srv *http.Server, router *mux.Rout
 				muxAll(isInAudit, userOwnsAllSubmissions)))))).
 		Methods("POST")
 
+	router.Handle(
+		"/api/submission-next",
+		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(
+			a.HandleAssignNextSubmission, isDecider)))).
+		Methods("POST")
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/comment/{%s}", constants.ResourceKeySubmissionID, constants.ResourceKeyCommentID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleEditComment, muxAny(isStaff, isTrialCurator, isInAudit))))).
+		Methods("PUT")
+
 	router.Handle("/api/notification-settings",
 		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
 			a.HandleUpdateNotificationSettings, muxAny(isStaff, isTrialCurator, isInAudit))))).
@@ -390,6 +438,13 @@ func (a *App) handleRequests(l *logrus.Entry, srv *http.Server, router *mux.Rout
 			muxAny(isStaff, isTrialCurator, isInAudit))))).
 		Methods("GET")
 
+	router.Handle(
+		fmt.Sprintf("/data/submission/{%s}/comment-image/{%s}.png", constants.ResourceKeySubmissionID, constants.ResourceKeyCommentImageID),
+		http.HandlerFunc(a.RequestData(a.UserAuthMux(
+			a.HandleDownloadCommentImage,
+			muxAny(isStaff, isTrialCurator, isInAudit))))).
+		Methods("GET")
+
 	router.Handle(
 		fmt.Sprintf("/data/flashfreeze/file/{%s}", constants.ResourceKeyFlashfreezeRootFileID),
 		http.HandlerFunc(a.RequestData(a.UserAuthMux(
@@ -423,6 +478,36 @@ func (a *App) handleRequests(l *logrus.Entry, srv *http.Server, router *mux.Rout
 			a.HandleSoftDeleteComment, muxAll(isDeleter))))).
 		Methods("DELETE")
 
+	// restore
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/restore", constants.ResourceKeySubmissionID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleRestoreSubmission, muxAll(isDeleter))))).
+		Methods("POST")
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/file/{%s}/restore", constants.ResourceKeySubmissionID, constants.ResourceKeyFileID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleRestoreSubmissionFile, muxAll(isDeleter))))).
+		Methods("POST")
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/comment/{%s}/restore", constants.ResourceKeySubmissionID, constants.ResourceKeyCommentID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleRestoreComment, muxAll(isDeleter))))).
+		Methods("POST")
+
+	f = a.UserAuthMux(a.HandleTrashPage, muxAll(isDeleter))
+
+	router.Handle("/web/trash",
+		http.HandlerFunc(a.RequestWeb(f))).
+		Methods("GET")
+
+	router.Handle("/api/trash",
+		http.HandlerFunc(a.RequestJSON(f))).
+		Methods("GET")
+
 	// bot override
 
 	router.Handle(
@@ -431,6 +516,44 @@ func (a *App) handleRequests(l *logrus.Entry, srv *http.Server, router *mux.Rout
 			a.HandleOverrideBot, muxAny(isDeleter, isStaff))))).
 		Methods("POST")
 
+	// labels
+
+	router.Handle(
+		"/api/labels",
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleGetLabels, isStaff)))).
+		Methods("GET")
+
+	router.Handle(
+		"/api/labels",
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleCreateLabel, isStaff)))).
+		Methods("POST")
+
+	router.Handle(
+		fmt.Sprintf("/api/label/{%s}", constants.ResourceKeyLabelID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleUpdateLabel, isStaff)))).
+		Methods("PUT")
+
+	router.Handle(
+		fmt.Sprintf("/api/label/{%s}", constants.ResourceKeyLabelID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleDeleteLabel, isStaff)))).
+		Methods("DELETE")
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/label", constants.ResourceKeySubmissionID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleAddSubmissionLabel, isStaff)))).
+		Methods("POST")
+
+	router.Handle(
+		fmt.Sprintf("/api/submission/{%s}/label", constants.ResourceKeySubmissionID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(
+			a.HandleRemoveSubmissionLabel, isStaff)))).
+		Methods("DELETE")
+
 	// user statistics
 
 	router.Handle(
@@ -473,10 +596,30 @@ func (a *App) handleRequests(l *logrus.Entry, srv *http.Server, router *mux.Rout
 		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleIngestUnknownFlashfreeze, isGod)))).
 		Methods("GET")
 
+	router.Handle("/api/internal/operations",
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(a.HandleGetOperations, isGod)))).
+		Methods("GET")
+
+	router.Handle(fmt.Sprintf("/api/internal/operations/{%s}", constants.ResourceKeyOperationID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(a.HandleGetOperation, isGod)))).
+		Methods("GET")
+
+	router.Handle(fmt.Sprintf("/api/internal/operations/{%s}/cancel", constants.ResourceKeyOperationID),
+		http.HandlerFunc(a.RequestJSON(a.UserAuthMux(a.HandleCancelOperation, isGod)))).
+		Methods("POST")
+
 	router.Handle("/api/internal/flashfreeze/index-unindexed-files",
 		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleIndexUnindexedFlashfreeze, isGod)))).
 		Methods("GET")
 
+	router.Handle("/api/internal/index-unindexed-submission-files",
+		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleIndexUnindexedSubmissionFiles, isGod)))).
+		Methods("GET")
+
+	router.Handle("/api/internal/revalidate-submission-files",
+		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleRevalidateSubmissionFiles, isGod)))).
+		Methods("GET")
+
 	router.Handle("/api/internal/delete-user-sessions",
 		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleDeleteUserSessions, isGod)))).
 		Methods("POST")
@@ -485,6 +628,32 @@ func (a *App) handleRequests(l *logrus.Entry, srv *http.Server, router *mux.Rout
 		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleSendRemindersAboutRequestedChanges, isGod)))).
 		Methods("GET")
 
+	router.Handle("/web/internal/similarity-rules",
+		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleSimilarityRulesPage, isGod)))).
+		Methods("GET")
+
+	router.Handle("/api/internal/similarity-rules",
+		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleCreateSimilarityRule, isGod)))).
+		Methods("POST")
+
+	router.Handle(fmt.Sprintf("/api/internal/similarity-rules/{%s}", constants.ResourceKeySimilarityRuleID),
+		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleUpdateSimilarityRule, isGod)))).
+		Methods("POST")
+
+	router.Handle(fmt.Sprintf("/api/internal/similarity-rules/{%s}/delete", constants.ResourceKeySimilarityRuleID),
+		http.HandlerFunc(a.RequestWeb(a.UserAuthMux(a.HandleDeleteSimilarityRule, isGod)))).
+		Methods("POST")
+
+	f = a.UserAuthMux(a.HandleAuditLogPage, isGod)
+
+	router.Handle("/web/audit-log",
+		http.HandlerFunc(a.RequestWeb(f))).
+		Methods("GET")
+
+	router.Handle("/api/audit-log",
+		http.HandlerFunc(a.RequestJSON(f))).
+		Methods("GET")
+
 	err := srv.ListenAndServe()
 	if err != nil {
 		l.Fatal(err)
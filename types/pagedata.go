@@ -30,11 +30,14 @@ type ViewSubmissionPageData struct {
 	NextSubmissionID     *int64
 	PreviousSubmissionID *int64
 	TagList              []Tag
+	ValidationResults    []*ValidationRuleResult
 }
 
 type SubmissionsFilesPageData struct {
 	BasePageData
 	SubmissionFiles []*ExtendedSubmissionFile
+	MetaDiff        *CurationMetaDiff
+	ContentDiff     *ArchiveContentDiff
 }
 
 type SearchFlashfreezePageData struct {
@@ -59,6 +62,8 @@ type StatisticsPageData struct {
 	FlashfreezeFileCount        int64
 	TotalSubmissionSize         int64
 	TotalFlashfreezeSize        int64
+	PurgedFileCount             int64
+	TotalReclaimedSize          int64
 }
 
 type SubmitFixesFilesPageData struct {
@@ -82,3 +87,29 @@ type UserStatisticsPageData struct {
 	BasePageData
 	Users []*UserStatistics
 }
+
+type SimilarityRulesPageData struct {
+	BasePageData
+	Rules []*SimilarityRule
+	Test  *SimilarityTestResult
+}
+
+type AuditLogPageData struct {
+	BasePageData
+	Entries    []*AuditLogEntry
+	TotalCount int64
+	Filter     AuditLogFilter
+	Actions    []string
+}
+
+type TrashPageData struct {
+	BasePageData
+	Items []*DeletedItem
+}
+
+type InternalPageData struct {
+	BasePageData
+	ScheduledTasks []*ScheduledTaskStatus
+	RecentRuns     []*ScheduledTaskRun
+	Operations     []*Operation
+}
@@ -1,9 +1,14 @@
 package types
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"regexp"
+	"strings"
 	"time"
+
+	"github.com/Dri0m/flashpoint-submission-system/constants"
 )
 
 type CurationMeta struct {
@@ -59,12 +64,14 @@ type MasterDatabaseGame struct {
 }
 
 type Comment struct {
-	ID           int64
-	AuthorID     int64
-	SubmissionID int64
-	Action       string
-	Message      *string
-	CreatedAt    time.Time
+	ID              int64
+	AuthorID        int64
+	SubmissionID    int64
+	Action          string
+	Message         *string
+	CreatedAt       time.Time
+	TargetUserID    *int64 // user on whose behalf the system posted the action
+	ParentCommentID *int64 // comment this one replies to
 }
 
 type SubmissionFile struct {
@@ -90,6 +97,7 @@ type ExtendedSubmissionFile struct {
 	UploadedAt         time.Time
 	MD5Sum             string
 	SHA256Sum          string
+	IndexedAt          *time.Time
 }
 
 type ExtendedSubmission struct {
@@ -122,6 +130,99 @@ type ExtendedSubmission struct {
 	ApprovedUserIDs             []int64
 	VerifiedUserIDs             []int64
 	DistinctActions             []string
+	State                       string
+	RequiredApprovals           int64
+	Labels                      []string
+}
+
+// MissingApprovals returns how many more approvals the submission needs to meet the approval quorum
+func (s *ExtendedSubmission) MissingApprovals() int64 {
+	missing := s.RequiredApprovals - int64(len(s.ApprovedUserIDs))
+	if missing < 0 {
+		return 0
+	}
+	return missing
+}
+
+// ActiveAssignment is a testing or verification assignment which has not been unassigned yet
+type ActiveAssignment struct {
+	CommentID    int64 // the assign comment
+	SubmissionID int64
+	UserID       int64
+	Action       string
+	AssignedAt   time.Time
+	IsNoticeSent bool
+}
+
+// AssignmentExpiry is how long assignments last. A role override is more specific than the default so it applies instead of it,
+// if several roles of the assignee have an override the longest one applies. Zero means the assignment does not expire.
+type AssignmentExpiry struct {
+	Default time.Duration
+	ByRole  map[string]time.Duration
+}
+
+// For returns the expiry for an assignee with the given roles, zero means the assignment does not expire
+func (ae AssignmentExpiry) For(roles []string) time.Duration {
+	var result time.Duration
+	isOverridden := false
+	for _, role := range roles {
+		expiry, ok := ae.ByRole[role]
+		if !ok {
+			continue
+		}
+		if expiry == 0 {
+			return 0
+		}
+		if !isOverridden || expiry > result {
+			result = expiry
+		}
+		isOverridden = true
+	}
+	if !isOverridden {
+		return ae.Default
+	}
+	return result
+}
+
+// WorkloadCaps is how many submissions a user can be assigned to test at once. A role override is more specific than the default so it applies instead of it,
+// if several roles of the user have an override the highest one applies. Zero means the user can take any amount of submissions.
+type WorkloadCaps struct {
+	Default int64
+	ByRole  map[string]int64
+}
+
+// For returns the cap for a user with the given roles, zero means the user can take any amount of submissions
+func (wc WorkloadCaps) For(roles []string) int64 {
+	var result int64
+	isOverridden := false
+	for _, role := range roles {
+		c, ok := wc.ByRole[role]
+		if !ok {
+			continue
+		}
+		if c == 0 {
+			return 0
+		}
+		if !isOverridden || c > result {
+			result = c
+		}
+		isOverridden = true
+	}
+	if !isOverridden {
+		return wc.Default
+	}
+	return result
+}
+
+// ApprovalQuorums maps submission levels to the number of approvals from distinct testers the submission needs
+type ApprovalQuorums map[string]int64
+
+// Required returns the approval quorum of a submission level, at least one approval is always required
+func (aq ApprovalQuorums) Required(submissionLevel string) int64 {
+	if required := aq[submissionLevel]; required > 1 {
+		return required
+	}
+	return 1
 }
 
 type SubmissionsFilter struct {
@@ -158,11 +259,15 @@ type SubmissionsFilter struct {
 	IsExtreme                      *string  `schema:"is-extreme"`
 	DistinctActions                []string `schema:"distinct-action"`
 	DistinctActionsNot             []string `schema:"distinct-action-not"`
+	States                         []string `schema:"state"`
 	LaunchCommandFuzzy             *string  `schema:"launch-command-fuzzy"`
 	LastUploaderNotMe              *string  `schema:"last-uploader-not-me"`
 	OrderBy                        *string  `schema:"order-by"`
 	AscDesc                        *string  `schema:"asc-desc"`
 	SubscribedMe                   *string  `schema:"subscribed-me"`
+	ValidationCodes                []string `schema:"validation-code"`
+	Labels                         []string `schema:"label"`
+	LabelsNot                      []string `schema:"label-not"`
 	ExcludeLegacy                  bool
 	UpdatedByID                    *int64
 }
@@ -187,6 +292,29 @@ func unzeroNilPointers(x interface{}) {
 func (sf *SubmissionsFilter) Validate() error {
 	unzeroNilPointers(sf)
 
+	// validation codes can be also typed as a comma-separated list
+	validationCodes := make([]string, 0, len(sf.ValidationCodes))
+	for _, vcs := range sf.ValidationCodes {
+		for _, vc := range strings.Split(vcs, ",") {
+			if vc = strings.TrimSpace(vc); vc != "" {
+				validationCodes = append(validationCodes, vc)
+			}
+		}
+	}
+	sf.ValidationCodes = validationCodes
+
+	// labels can be also typed as a comma-separated list
+	labels, err := normalizeLabelNames(sf.Labels)
+	if err != nil {
+		return err
+	}
+	sf.Labels = labels
+	labelsNot, err := normalizeLabelNames(sf.LabelsNot)
+	if err != nil {
+		return err
+	}
+	sf.LabelsNot = labelsNot
+
 	for _, sid := range sf.SubmissionIDs {
 		if sid < 1 {
 			{
@@ -194,6 +322,18 @@ func (sf *SubmissionsFilter) Validate() error {
 			}
 		}
 	}
+	for _, state := range sf.States {
+		isStateValid := false
+		for _, s := range constants.GetSubmissionStates() {
+			if state == s {
+				isStateValid = true
+				break
+			}
+		}
+		if !isStateValid {
+			return fmt.Errorf("invalid state '%s'", state)
+		}
+	}
 	if sf.SubmitterID != nil && *sf.SubmitterID < 1 {
 		if *sf.SubmitterID == 0 {
 			sf.SubmitterID = nil
@@ -297,14 +437,35 @@ func (sf *SubmissionsFilter) Validate() error {
 }
 
 type ExtendedComment struct {
-	CommentID    int64
-	AuthorID     int64
-	Username     string
-	AvatarURL    string
-	SubmissionID int64
-	Action       string
-	Message      *string
-	CreatedAt    time.Time
+	CommentID       int64
+	AuthorID        int64
+	Username        string
+	AvatarURL       string
+	SubmissionID    int64
+	Action          string
+	Message         *string
+	CreatedAt       time.Time
+	UpdatedAt       *time.Time         // last edit
+	Revisions       []*CommentRevision // previous versions, newest first
+	EditDiff        []*TextDiffChunk   // changes made by the last edit
+	ParentCommentID *int64             // comment this one replies to
+	ParentUsername  string             // author of the parent comment
+	Depth           int                // nesting level in the thread
+	ImageIDs        []int64            // attached images
+}
+
+// CommentRevision is a previous version of an edited comment
+type CommentRevision struct {
+	ID        int64
+	CommentID int64
+	Message   *string
+	CreatedAt time.Time // when this version was written
+}
+
+// TextDiffChunk is a piece of text which was kept, added or removed
+type TextDiffChunk struct {
+	Kind string // same, added or removed
+	Text string
 }
 
 type UpdateNotificationSettings struct {
@@ -355,6 +516,16 @@ type Notification struct {
 	SentAt    time.Time
 }
 
+// CommentImage is an image attached to a comment, stored next to the curation images
+type CommentImage struct {
+	ID           int64
+	CommentID    int64
+	SubmissionID int64
+	Filename     string
+	Size         int64
+	CreatedAt    time.Time
+}
+
 type CurationImage struct {
 	ID               int64
 	SubmissionFileID int64
@@ -376,6 +547,7 @@ type ValidatorResponse struct {
 	CurationType     int                      `json:"curation_type"`
 	Meta             CurationMeta             `json:"meta"`
 	Images           []ValidatorResponseImage `json:"images"`
+	IsDegraded       bool                     `json:"is_degraded"`
 }
 
 type ReceiveFileTempNameResp struct {
@@ -668,3 +840,326 @@ type SubmissionStatus struct {
 	Message      *string `json:"message"`
 	SubmissionID *int64  `json:"submission_id"`
 }
+
+// SubmissionJob is a received submission upload waiting to be processed by the submission job consumer
+type SubmissionJob struct {
+	ID                  int64
+	TempName            string
+	UserID              int64
+	SubmissionID        *int64 // set when uploading a new version of an existing submission
+	ResumableIdentifier string
+	Filename            string
+	Size                int64
+	ChunkCount          int
+	State               string
+	Attempts            int64
+	LastError           *string
+	ResultSubmissionID  *int64
+	Status              *string // last known types.SubmissionStatus status, reported by the instance processing the job
+	StatusMessage       *string
+	NextAttemptAt       time.Time
+	LockedAt            *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type SubmissionStatusHistoryEntry struct {
+	Status    string    `json:"status"`
+	Message   *string   `json:"message"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CurationMetaFieldDiff is a change of a single curation meta field between two submission file versions
+type CurationMetaFieldDiff struct {
+	Field string  `json:"field"`
+	From  *string `json:"from"`
+	To    *string `json:"to"`
+}
+
+// CurationMetaListDiff is a change of a curation meta field which contains a list of values, like tags
+type CurationMetaListDiff struct {
+	Field   string   `json:"field"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+type CurationMetaDiff struct {
+	FromFileID int64                    `json:"from_file_id"`
+	ToFileID   int64                    `json:"to_file_id"`
+	Fields     []*CurationMetaFieldDiff `json:"fields"`
+	Lists      []*CurationMetaListDiff  `json:"lists"`
+	// one of the files has no curation meta, so there is nothing to compare
+	IsMetaMissing bool `json:"is_meta_missing"`
+}
+
+// ArchiveEntryDiff is a change of a single file inside of a submission archive
+type ArchiveEntryDiff struct {
+	Path      string `json:"path"`
+	SizeFrom  int64  `json:"size_from"`
+	SizeTo    int64  `json:"size_to"`
+	SizeDelta int64  `json:"size_delta"`
+}
+
+type ArchiveContentDiff struct {
+	FromFileID int64               `json:"from_file_id"`
+	ToFileID   int64               `json:"to_file_id"`
+	Added      []*ArchiveEntryDiff `json:"added"`
+	Removed    []*ArchiveEntryDiff `json:"removed"`
+	Modified   []*ArchiveEntryDiff `json:"modified"`
+}
+
+type ValidationRuleResult struct {
+	ID               int64     `json:"id"`
+	SubmissionFileID int64     `json:"submission_file_id"`
+	RuleID           string    `json:"rule_id"`
+	Severity         string    `json:"severity"`
+	Message          string    `json:"message"`
+	ExplanationURL   *string   `json:"explanation_url"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type ValidationMessage struct {
+	Kind    string `json:"kind"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type SubmissionFileValidation struct {
+	SubmissionFileID int64                   `json:"submission_file_id"`
+	Response         ValidatorResponse       `json:"response"`
+	Messages         []*ValidationMessage    `json:"messages"`
+	RuleResults      []*ValidationRuleResult `json:"rule_results"`
+	CreatedAt        time.Time               `json:"created_at"`
+}
+
+type SubmissionFileValidationTarget struct {
+	FileID          int64
+	SubmissionID    int64
+	CurrentFilename string
+	IsNewest        bool
+}
+
+// ArchiveContentHash is a hash of a single file inside a submission archive
+type ArchiveContentHash struct {
+	Filename string
+	SHA256   string
+}
+
+// ContentHashMatch is a file in flashfreeze or in another submission with the same hash as a file of a submission archive
+type ContentHashMatch struct {
+	SHA256        string
+	Source        string
+	ID            int64
+	MatchFilename string
+}
+
+// SimilarityRule overrides the similarity threshold and normalization of launch commands matching the pattern
+type SimilarityRule struct {
+	ID               int64
+	Pattern          string  `schema:"pattern"`
+	Threshold        float64 `schema:"threshold"`
+	StripQueryString bool    `schema:"strip-query-string"`
+	IgnoreHost       bool    `schema:"ignore-host"`
+	CaseFold         bool    `schema:"case-fold"`
+	UserID           int64
+	Username         string
+	UpdatedAt        time.Time
+}
+
+type SimilarityTestRequest struct {
+	Title         string `schema:"title"`
+	LaunchCommand string `schema:"launch-command"`
+}
+
+// SimilarityTestResult shows how the similarity scorer treats a title and launch command
+type SimilarityTestResult struct {
+	SimilarityTestRequest
+	MatchedRule             *SimilarityRule
+	MinimumMatch            float64
+	NormalizedTitle         string
+	NormalizedLaunchCommand string
+	TitleMatches            []*SimilarityAttributes
+	LaunchCommandMatches    []*SimilarityAttributes
+}
+
+// Label is a free-form triage label staff can attach to submissions
+type Label struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	UserID          int64     `json:"user_id"`
+	Username        string    `json:"username"`
+	CreatedAt       time.Time `json:"created_at"`
+	SubmissionCount int64     `json:"submission_count"`
+}
+
+var labelNameRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9 _.-]*$`)
+
+// NormalizeLabelName trims and lowercases the label name and checks that it's valid
+func NormalizeLabelName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("label name cannot be empty")
+	}
+	if len([]rune(name)) > 64 {
+		return "", fmt.Errorf("label name cannot be longer than 64 characters")
+	}
+	if !labelNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("label name '%s' can contain only letters, numbers, spaces, dots, dashes and underscores, and must start with a letter or number", name)
+	}
+	return name, nil
+}
+
+// normalizeLabelNames normalizes labels typed as lists or as comma-separated lists
+func normalizeLabelNames(names []string) ([]string, error) {
+	result := make([]string, 0, len(names))
+	for _, ns := range names {
+		for _, n := range strings.Split(ns, ",") {
+			if strings.TrimSpace(n) == "" {
+				continue
+			}
+			name, err := NormalizeLabelName(n)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, name)
+		}
+	}
+	return result, nil
+}
+
+// AuditLogEntry records a privileged action, the states are JSON snapshots of the target before and after the action
+type AuditLogEntry struct {
+	ID         int64           `json:"id"`
+	UserID     int64           `json:"user_id"`
+	Username   string          `json:"username"`
+	Action     string          `json:"action"`
+	TargetType *string         `json:"target_type"`
+	TargetID   *int64          `json:"target_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestID  string          `json:"request_id"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type AuditLogFilter struct {
+	Actions    []string `schema:"action"`
+	UserID     *int64   `schema:"user-id"`
+	TargetType *string  `schema:"target-type"`
+	TargetID   *int64   `schema:"target-id"`
+	RequestID  *string  `schema:"request-id"`
+
+	ResultsPerPage *int64 `schema:"results-per-page"`
+	Page           *int64 `schema:"page"`
+}
+
+func (af *AuditLogFilter) Validate() error {
+
+	v := reflect.ValueOf(af).Elem() // schema zeroes out the nil pointers
+	t := reflect.TypeOf(af).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if t.Field(i).Type.Kind() == reflect.Ptr {
+			f := v.Field(i)
+			e := f.Elem()
+			if e.Kind() == reflect.Int64 && e.Int() == 0 {
+				f.Set(reflect.Zero(f.Type()))
+			}
+			if e.Kind() == reflect.String && e.String() == "" {
+				f.Set(reflect.Zero(f.Type()))
+			}
+		}
+	}
+
+	actions := make([]string, 0, len(af.Actions))
+	for _, action := range af.Actions {
+		if action != "" {
+			actions = append(actions, action)
+		}
+	}
+	af.Actions = actions
+
+	if af.UserID != nil && *af.UserID < 1 {
+		return fmt.Errorf("user id must be >= 1")
+	}
+	if af.TargetID != nil && *af.TargetID < 1 {
+		return fmt.Errorf("target id must be >= 1")
+	}
+
+	if af.ResultsPerPage != nil && *af.ResultsPerPage < 1 {
+		return fmt.Errorf("results per page must be >= 1")
+	}
+	if af.Page != nil && *af.Page < 1 {
+		return fmt.Errorf("page must be >= 1")
+	}
+
+	return nil
+}
+
+// DeletedItem is a soft-deleted submission, submission file or comment shown in the trash
+type DeletedItem struct {
+	Type              string    `json:"type"`
+	ID                int64     `json:"id"`
+	SubmissionID      int64     `json:"submission_id"`
+	Description       *string   `json:"description"` // filename or comment message
+	DeletedAt         time.Time `json:"deleted_at"`
+	DeletedReason     *string   `json:"deleted_reason"`
+	DeletedByUserID   *int64    `json:"deleted_by_user_id"`
+	DeletedByUsername *string   `json:"deleted_by_username"`
+}
+
+// PurgeableFile is a soft-deleted file whose retention period is over
+type PurgeableFile struct {
+	Type            string
+	ID              int64
+	CurrentFilename string
+}
+
+// FilePurge is a record of a deleted file removed from the disk
+type FilePurge struct {
+	ID             int64
+	FileType       string
+	FileID         int64
+	Filename       string
+	ImageCount     int64
+	ReclaimedBytes int64
+	PurgedAt       time.Time
+}
+
+// ScheduledTaskConfig is an entry of the scheduled tasks file, the schedule is a five-field cron expression in UTC
+type ScheduledTaskConfig struct {
+	Task     string `json:"task"`
+	Schedule string `json:"schedule"`
+}
+
+// ScheduledTaskRun is a single run of a scheduled task
+type ScheduledTaskRun struct {
+	ID         int64      `json:"id"`
+	TaskName   string     `json:"task_name"`
+	Instance   string     `json:"instance"`
+	Status     string     `json:"status"`
+	Message    *string    `json:"message"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// ScheduledTaskStatus is a configured scheduled task with its next and last run
+type ScheduledTaskStatus struct {
+	Task      string            `json:"task"`
+	Schedule  string            `json:"schedule"`
+	NextRunAt time.Time         `json:"next_run_at"`
+	LastRun   *ScheduledTaskRun `json:"last_run"`
+}
+
+// Operation is a snapshot of a long-running internal operation
+type Operation struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	UserID     int64      `json:"user_id"`
+	Status     string     `json:"status"`
+	Done       int64      `json:"done"`
+	Total      int64      `json:"total"`
+	Progress   float64    `json:"progress"` // percent, 0 while the total is unknown
+	LogTail    []string   `json:"log_tail"`
+	Error      *string    `json:"error"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	markdownCodeSpanRegexp    = regexp.MustCompile("`([^`]+)`")
+	markdownLinkRegexp        = regexp.MustCompile(`\[([^\[\]]+)\]\(([^\s()\x00]+)\)|https?://[^\s<>()\x00]*[^\s<>()\x00.,:;!?'"]`)
+	markdownBoldRegexp        = regexp.MustCompile(`\*\*(\S|\S.*?\S)\*\*`)
+	markdownItalicRegexp      = regexp.MustCompile(`\*(\S|\S.*?\S)\*`)
+	markdownStrikeRegexp      = regexp.MustCompile(`~~(\S|\S.*?\S)~~`)
+	markdownPlaceholderRegexp = regexp.MustCompile("\x00([0-9]+)\x00")
+	markdownHeadingRegexp     = regexp.MustCompile(`^(#{1,6})\s+(.*)$`)
+	markdownUnorderedRegexp   = regexp.MustCompile(`^\s*[-*+]\s+(.*)$`)
+	markdownOrderedRegexp     = regexp.MustCompile(`^\s*[0-9]+[.)]\s+(.*)$`)
+	markdownBlockquoteRegexp  = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	markdownCodeFenceRegexp   = regexp.MustCompile("^\\s*```")
+)
+
+const markdownMaxBlockquoteDepth = 5
+
+// RenderMarkdown renders a subset of markdown into HTML. Raw HTML is never passed through, everything the user wrote is escaped
+// and only links with http(s) or site-relative URLs are created.
+func RenderMarkdown(s *string) template.HTML {
+	if s == nil {
+		return ""
+	}
+	text := strings.ReplaceAll(strings.ReplaceAll(*s, "\r\n", "\n"), "\x00", "")
+	return template.HTML(renderMarkdownBlocks(strings.Split(text, "\n"), 0))
+}
+
+func renderMarkdownBlocks(lines []string, depth int) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case markdownCodeFenceRegexp.MatchString(line):
+			i++
+			code := make([]string, 0)
+			for ; i < len(lines) && !markdownCodeFenceRegexp.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			i++ // closing fence
+			sb.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>")
+
+		case markdownHeadingRegexp.MatchString(line):
+			m := markdownHeadingRegexp.FindStringSubmatch(line)
+			// comments live in a small box, so even the top level heading is fairly small
+			level := strconv.Itoa(len(m[1]) + 2)
+			if len(m[1]) > 4 {
+				level = "6"
+			}
+			sb.WriteString("<h" + level + ">" + renderMarkdownInline(m[2]) + "</h" + level + ">")
+			i++
+
+		case markdownBlockquoteRegexp.MatchString(line) && depth < markdownMaxBlockquoteDepth:
+			quoted := make([]string, 0)
+			for ; i < len(lines) && markdownBlockquoteRegexp.MatchString(lines[i]); i++ {
+				quoted = append(quoted, markdownBlockquoteRegexp.FindStringSubmatch(lines[i])[1])
+			}
+			sb.WriteString("<blockquote>" + renderMarkdownBlocks(quoted, depth+1) + "</blockquote>")
+
+		case markdownUnorderedRegexp.MatchString(line):
+			sb.WriteString("<ul>")
+			for ; i < len(lines) && markdownUnorderedRegexp.MatchString(lines[i]); i++ {
+				sb.WriteString("<li>" + renderMarkdownInline(markdownUnorderedRegexp.FindStringSubmatch(lines[i])[1]) + "</li>")
+			}
+			sb.WriteString("</ul>")
+
+		case markdownOrderedRegexp.MatchString(line):
+			sb.WriteString("<ol>")
+			for ; i < len(lines) && markdownOrderedRegexp.MatchString(lines[i]); i++ {
+				sb.WriteString("<li>" + renderMarkdownInline(markdownOrderedRegexp.FindStringSubmatch(lines[i])[1]) + "</li>")
+			}
+			sb.WriteString("</ol>")
+
+		default:
+			// line breaks inside a paragraph are kept, that's how the comments were always displayed
+			paragraph := make([]string, 0)
+			for ; i < len(lines) && isMarkdownParagraphLine(lines[i], depth); i++ {
+				paragraph = append(paragraph, renderMarkdownInline(lines[i]))
+			}
+			sb.WriteString("<p>" + strings.Join(paragraph, "<br>") + "</p>")
+		}
+	}
+
+	return sb.String()
+}
+
+func isMarkdownParagraphLine(line string, depth int) bool {
+	return strings.TrimSpace(line) != "" &&
+		!markdownCodeFenceRegexp.MatchString(line) &&
+		!markdownHeadingRegexp.MatchString(line) &&
+		!(markdownBlockquoteRegexp.MatchString(line) && depth < markdownMaxBlockquoteDepth) &&
+		!markdownUnorderedRegexp.MatchString(line) &&
+		!markdownOrderedRegexp.MatchString(line)
+}
+
+// renderMarkdownInline escapes the text first and then adds the markup, generated tags are swapped for placeholders
+// so that emphasis cannot reach into code spans and link URLs
+func renderMarkdownInline(s string) string {
+	fragments := make([]string, 0)
+	placeholder := func(fragment string) string {
+		fragments = append(fragments, fragment)
+		return fmt.Sprintf("\x00%d\x00", len(fragments)-1)
+	}
+
+	s = markdownCodeSpanRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		return placeholder("<code>" + html.EscapeString(m[1:len(m)-1]) + "</code>")
+	})
+
+	s = markdownLinkRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		text, url := m, m
+		if sm := markdownLinkRegexp.FindStringSubmatch(m); sm[2] != "" {
+			text, url = sm[1], sm[2]
+		}
+		if !isSafeMarkdownURL(url) {
+			return m
+		}
+		return placeholder(`<a href="` + html.EscapeString(url) + `" rel="nofollow noreferrer">` + html.EscapeString(text) + "</a>")
+	})
+
+	s = html.EscapeString(s)
+	s = markdownBoldRegexp.ReplaceAllString(s, "<strong>$1</strong>")
+	s = markdownItalicRegexp.ReplaceAllString(s, "<em>$1</em>")
+	s = markdownStrikeRegexp.ReplaceAllString(s, "<del>$1</del>")
+
+	// a link text can contain a code span, so the fragments are resolved recursively
+	var resolve func(s string) string
+	resolve = func(s string) string {
+		return markdownPlaceholderRegexp.ReplaceAllStringFunc(s, func(m string) string {
+			i, _ := strconv.Atoi(m[1 : len(m)-1])
+			return resolve(fragments[i])
+		})
+	}
+
+	return resolve(s)
+}
+
+func isSafeMarkdownURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") ||
+		(strings.HasPrefix(url, "/") && !strings.HasPrefix(url, "//") && !strings.HasPrefix(url, "/\\"))
+}
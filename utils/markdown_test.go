@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"html/template"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want template.HTML
+	}{
+		{name: "plain lines keep their breaks", text: "first\nsecond\n\nthird", want: "<p>first<br>second</p><p>third</p>"},
+		{name: "raw html is escaped", text: `<script>alert("x")</script>`, want: "<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>"},
+		{name: "emphasis", text: "**bold** *italic* ~~gone~~", want: "<p><strong>bold</strong> <em>italic</em> <del>gone</del></p>"},
+		{name: "code span is not formatted", text: "`**x** <b>`", want: "<p><code>**x** &lt;b&gt;</code></p>"},
+		{name: "link", text: "[docs](https://example.com/a_b_c?x=1&y=2)", want: `<p><a href="https://example.com/a_b_c?x=1&amp;y=2" rel="nofollow noreferrer">docs</a></p>`},
+		{name: "autolink without trailing dot", text: "see https://example.com/x.", want: `<p>see <a href="https://example.com/x" rel="nofollow noreferrer">https://example.com/x</a>.</p>`},
+		{name: "relative link", text: "[sub](/web/submission/1)", want: `<p><a href="/web/submission/1" rel="nofollow noreferrer">sub</a></p>`},
+		{name: "javascript link is not created", text: "[x](javascript:alert(1))", want: "<p>[x](javascript:alert(1))</p>"},
+		{name: "protocol relative link is not created", text: "[x](//evil.com)", want: "<p>[x](//evil.com)</p>"},
+		{name: "quote in link is escaped", text: `[x](https://a.com/"><b>)`, want: `<p><a href="https://a.com/&#34;&gt;&lt;b&gt;" rel="nofollow noreferrer">x</a></p>`},
+		{name: "code block", text: "```\n<b>\n**x**\n```", want: "<pre><code>&lt;b&gt;\n**x**</code></pre>"},
+		{name: "lists", text: "- a\n- b\n1. c", want: "<ul><li>a</li><li>b</li></ul><ol><li>c</li></ol>"},
+		{name: "heading and quote", text: "# Title\n> quoted\n> more", want: "<h3>Title</h3><blockquote><p>quoted<br>more</p></blockquote>"},
+		{name: "null bytes cannot fake placeholders", text: "\x000\x00", want: "<p>0</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RenderMarkdown(&tt.text))
+		})
+	}
+
+	assert.Equal(t, template.HTML(""), RenderMarkdown(nil))
+}
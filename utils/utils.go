@@ -253,18 +253,22 @@ func UploadMultipartFile(ctx context.Context, url string, f io.Reader, filename
 		defer resp.Body.Close()
 	}
 
-	if err != nil || merr != nil {
+	if err != nil {
 		return nil, fmt.Errorf("http error: %v, multipart error: %v", err, merr)
 	}
 
 	bodyBytes, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
 
-	// Check the response
+	// Check the response, a server rejecting the file can respond before the whole file is sent
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("upload to remote error: %s", string(bodyBytes))
+		return nil, &HTTPStatusError{StatusCode: resp.StatusCode, Msg: fmt.Sprintf("upload to remote error: %s", string(bodyBytes))}
+	}
+
+	if merr != nil {
+		return nil, fmt.Errorf("multipart error: %v", merr)
+	}
+	if err != nil {
+		return nil, err
 	}
 
 	LogCtx(ctx).WithField("url", url).Debug("response OK")
@@ -272,6 +276,16 @@ func UploadMultipartFile(ctx context.Context, url string, f io.Reader, filename
 	return bodyBytes, nil
 }
 
+// HTTPStatusError is returned when a remote server responds with an unexpected status code
+type HTTPStatusError struct {
+	StatusCode int
+	Msg        string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return e.Msg
+}
+
 type ValueOnlyContext struct {
 	context.Context
 }